@@ -0,0 +1,109 @@
+// Command blobsync synchronizes a local directory with a blob container.
+//
+// Usage:
+//
+//	blobsync [flags] up   <directory> <container>[/<prefix>]
+//	blobsync [flags] down <container>[/<prefix>] <directory>
+//
+// Files are compared by size and Content-MD5, or by last-modified time if the
+// blob has no Content-MD5, and only changed files are transferred. Uploads
+// are done in parallel blocks and set the content type of the blobs from the
+// file extensions.
+//
+// The storage account is specified with the -account and -key flags, or the
+// ACCOUNT_NAME and ACCOUNT_KEY environment variables.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+)
+
+// patterns is a flag which can be repeated or given comma-separated values.
+type patterns []string
+
+func (p *patterns) String() string { return strings.Join(*p, ",") }
+
+func (p *patterns) Set(v string) error {
+	for _, s := range strings.Split(v, ",") {
+		if s != "" {
+			*p = append(*p, s)
+		}
+	}
+	return nil
+}
+
+func main() {
+	var (
+		opts     options
+		account  = flag.String("account", os.Getenv("ACCOUNT_NAME"), "storage account `name`")
+		key      = flag.String("key", os.Getenv("ACCOUNT_KEY"), "storage account `key`")
+		include  patterns
+		exclude  patterns
+		parallel = flag.Int("parallel", 8, "number of files and blocks transferred in parallel")
+	)
+	flag.BoolVar(&opts.deleteExtraneous, "delete", false, "delete destination items which do not exist in the source")
+	flag.BoolVar(&opts.dryRun, "dry-run", false, "report the changes without making them")
+	flag.Var(&include, "include", "only sync paths matching the glob `pattern` (repeatable)")
+	flag.Var(&exclude, "exclude", "do not sync paths matching the glob `pattern` (repeatable)")
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "usage: %s [flags] up <directory> <container>[/<prefix>]\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "       %s [flags] down <container>[/<prefix>] <directory>\n\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(*account, *key, *parallel, filter{include, exclude}, opts, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "blobsync:", err)
+		os.Exit(1)
+	}
+}
+
+func run(account, key string, parallel int, f filter, opts options, args []string) error {
+	if len(args) != 3 || (args[0] != "up" && args[0] != "down") {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if account == "" || key == "" {
+		return errors.New("storage account name and key required")
+	}
+	if err := f.validate(); err != nil {
+		return err
+	}
+
+	cli, err := storage.NewBasicClient(account, key)
+	if err != nil {
+		return err
+	}
+
+	s := syncer{
+		blob:     cli.GetBlobService(),
+		filter:   f,
+		parallel: parallel,
+		out:      os.Stdout,
+	}
+	if args[0] == "up" {
+		s.dir = args[1]
+		s.container, s.prefix = splitContainerPath(args[2])
+		return s.upload(opts)
+	}
+	s.container, s.prefix = splitContainerPath(args[1])
+	s.dir = args[2]
+	return s.download(opts)
+}
+
+// splitContainerPath splits "container/prefix" into the container name and
+// the blob name prefix, which ends with a slash unless empty.
+func splitContainerPath(p string) (container, prefix string) {
+	p = strings.Trim(p, "/")
+	i := strings.Index(p, "/")
+	if i < 0 {
+		return p, ""
+	}
+	return p[:i], p[i+1:] + "/"
+}
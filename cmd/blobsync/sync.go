@@ -0,0 +1,336 @@
+package main
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+)
+
+type options struct {
+	deleteExtraneous bool
+	dryRun           bool
+}
+
+// filter selects paths by glob patterns. Patterns without a slash are
+// matched against the base name, others against the whole relative path.
+type filter struct {
+	include []string
+	exclude []string
+}
+
+func (f filter) validate() error {
+	for _, p := range append(append([]string{}, f.include...), f.exclude...) {
+		if _, err := path.Match(p, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %v", p, err)
+		}
+	}
+	return nil
+}
+
+func (f filter) matches(name string) bool {
+	if len(f.include) > 0 && !matchAny(f.include, name) {
+		return false
+	}
+	return !matchAny(f.exclude, name)
+}
+
+func matchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		target := name
+		if !strings.Contains(p, "/") {
+			target = path.Base(name)
+		}
+		if ok, _ := path.Match(p, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// item is a file or a blob to be synced.
+type item struct {
+	name    string // slash-separated path relative to the synced root
+	size    int64
+	modTime time.Time
+	md5     string // base64-encoded Content-MD5, empty if not known
+	path    string // local file path, empty for blobs
+}
+
+// contentMD5 returns the base64-encoded MD5 hash of the content of the item,
+// computing it for local files.
+func (it *item) contentMD5() (string, error) {
+	if it.md5 != "" || it.path == "" {
+		return it.md5, nil
+	}
+	f, err := os.Open(it.path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := md5.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	it.md5 = base64.StdEncoding.EncodeToString(h.Sum(nil))
+	return it.md5, nil
+}
+
+// needsTransfer reports whether the source item differs from the destination
+// item, which is nil if it does not exist.
+func needsTransfer(src, dst *item) (bool, error) {
+	if dst == nil || src.size != dst.size {
+		return true, nil
+	}
+
+	local, remote := src, dst
+	if src.path == "" {
+		local, remote = dst, src
+	}
+	if remote.md5 != "" {
+		sum, err := local.contentMD5()
+		return sum != remote.md5, err
+	}
+	return src.modTime.After(dst.modTime), nil
+}
+
+// action is a change planned to make the destination match the source.
+type action struct {
+	op   string // "upload", "download" or "delete"
+	item *item
+}
+
+// plan returns the actions to make dst match src, sorted by name.
+func plan(src, dst map[string]*item, transfer string, deleteExtraneous bool) ([]action, error) {
+	var out []action
+	for name, s := range src {
+		ok, err := needsTransfer(s, dst[name])
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			out = append(out, action{transfer, s})
+		}
+	}
+	if deleteExtraneous {
+		for name, d := range dst {
+			if _, ok := src[name]; !ok {
+				out = append(out, action{"delete", d})
+			}
+		}
+	}
+	sort.Sort(byName(out))
+	return out, nil
+}
+
+type byName []action
+
+func (a byName) Len() int           { return len(a) }
+func (a byName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byName) Less(i, j int) bool { return a[i].item.name < a[j].item.name }
+
+// listLocal returns the regular files under dir matching the filter, keyed
+// by their slash-separated path relative to dir. A missing dir is empty.
+func listLocal(dir string, f filter) (map[string]*item, error) {
+	out := make(map[string]*item)
+	err := filepath.Walk(dir, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			if p == dir && os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		name := filepath.ToSlash(rel)
+		if f.matches(name) {
+			out[name] = &item{name: name, size: info.Size(), modTime: info.ModTime(), path: p}
+		}
+		return nil
+	})
+	return out, err
+}
+
+// syncer syncs a local directory with the blobs under a prefix in a
+// container.
+type syncer struct {
+	blob      storage.BlobStorageClient
+	container string
+	prefix    string
+	dir       string
+	filter    filter
+	parallel  int
+	out       io.Writer
+
+	// slots bounds the requests in flight across all files and their
+	// blocks to parallel. It is created by upload and download.
+	slots chan struct{}
+}
+
+// concurrency returns the number of requests made at once, which is at
+// least one.
+func (s syncer) concurrency() int {
+	if s.parallel < 1 {
+		return 1
+	}
+	return s.parallel
+}
+
+// acquire blocks until one of the request slots is free and takes it.
+// Every request is made holding a slot, which is then returned with release.
+func (s syncer) acquire() { s.slots <- struct{}{} }
+
+func (s syncer) release() { <-s.slots }
+
+// withSlot makes a request while holding a slot.
+func (s syncer) withSlot(fn func() error) error {
+	s.acquire()
+	defer s.release()
+	return fn()
+}
+
+// listRemote returns the blobs under the prefix matching the filter, keyed
+// by their names relative to the prefix.
+func (s syncer) listRemote() (map[string]*item, error) {
+	out := make(map[string]*item)
+	params := storage.ListBlobsParameters{Prefix: s.prefix}
+	for {
+		resp, err := s.blob.ListBlobs(s.container, params)
+		if err != nil {
+			return nil, err
+		}
+		for _, b := range resp.Blobs {
+			name := strings.TrimPrefix(b.Name, s.prefix)
+			if !s.filter.matches(name) {
+				continue
+			}
+			modTime, err := time.Parse(http.TimeFormat, b.Properties.LastModified)
+			if err != nil {
+				return nil, fmt.Errorf("cannot parse last-modified time of %s: %v", b.Name, err)
+			}
+			out[name] = &item{
+				name:    name,
+				size:    b.Properties.ContentLength,
+				modTime: modTime,
+				md5:     b.Properties.ContentMD5,
+			}
+		}
+		if resp.NextMarker == "" {
+			return out, nil
+		}
+		params.Marker = resp.NextMarker
+	}
+}
+
+// upload makes the blobs under the prefix match the local directory.
+func (s syncer) upload(opts options) error {
+	local, err := listLocal(s.dir, s.filter)
+	if err != nil {
+		return err
+	}
+	remote, err := s.listRemote()
+	if err != nil {
+		return err
+	}
+	actions, err := plan(local, remote, "upload", opts.deleteExtraneous)
+	if err != nil {
+		return err
+	}
+	s.slots = make(chan struct{}, s.concurrency())
+	return s.apply(actions, opts.dryRun, func(a action) error {
+		if a.op == "delete" {
+			return s.withSlot(func() error {
+				return s.blob.DeleteBlob(s.container, s.prefix+a.item.name)
+			})
+		}
+		return s.uploadFile(a.item)
+	})
+}
+
+// download makes the local directory match the blobs under the prefix.
+func (s syncer) download(opts options) error {
+	local, err := listLocal(s.dir, s.filter)
+	if err != nil {
+		return err
+	}
+	remote, err := s.listRemote()
+	if err != nil {
+		return err
+	}
+	actions, err := plan(remote, local, "download", opts.deleteExtraneous)
+	if err != nil {
+		return err
+	}
+	s.slots = make(chan struct{}, s.concurrency())
+	return s.apply(actions, opts.dryRun, func(a action) error {
+		if a.op == "delete" {
+			return os.Remove(a.item.path)
+		}
+		return s.downloadFile(a.item)
+	})
+}
+
+// apply reports the actions and, unless in dry-run mode, executes them in
+// parallel. The workers only coordinate the transfers; the requests fn
+// makes are bounded by the slots, so that large files split into blocks do
+// not multiply the number of requests in flight. Failures are reported as
+// they happen and summarized in the returned error.
+func (s syncer) apply(actions []action, dryRun bool, fn func(action) error) error {
+	if dryRun {
+		for _, a := range actions {
+			fmt.Fprintf(s.out, "%s %s (dry run)\n", a.op, a.item.name)
+		}
+		fmt.Fprintf(s.out, "%d changes\n", len(actions))
+		return nil
+	}
+
+	var (
+		mu     sync.Mutex
+		failed int
+		wg     sync.WaitGroup
+		work   = make(chan action)
+	)
+	for i := 0; i < s.concurrency(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range work {
+				err := fn(a)
+				mu.Lock()
+				if err != nil {
+					failed++
+					fmt.Fprintf(s.out, "%s %s failed: %v\n", a.op, a.item.name, err)
+				} else {
+					fmt.Fprintf(s.out, "%s %s\n", a.op, a.item.name)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, a := range actions {
+		work <- a
+	}
+	close(work)
+	wg.Wait()
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d changes failed", failed, len(actions))
+	}
+	return nil
+}
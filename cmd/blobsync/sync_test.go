@@ -0,0 +1,249 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSplitContainerPath(t *testing.T) {
+	for _, tt := range []struct {
+		in, container, prefix string
+	}{
+		{"cnt", "cnt", ""},
+		{"cnt/", "cnt", ""},
+		{"cnt/a", "cnt", "a/"},
+		{"/cnt/a/b/", "cnt", "a/b/"},
+	} {
+		container, prefix := splitContainerPath(tt.in)
+		if container != tt.container || prefix != tt.prefix {
+			t.Errorf("splitContainerPath(%q) = %q, %q; want %q, %q", tt.in, container, prefix, tt.container, tt.prefix)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	f := filter{include: []string{"*.go", "docs/*"}, exclude: []string{"*_test.go"}}
+	for name, want := range map[string]bool{
+		"main.go":           true,
+		"pkg/sync.go":       true,
+		"pkg/sync_test.go":  false,
+		"docs/readme.txt":   true,
+		"docs/sub/page.txt": false,
+		"readme.txt":        false,
+	} {
+		if got := f.matches(name); got != want {
+			t.Errorf("matches(%q) = %v, want %v", name, got, want)
+		}
+	}
+
+	if !(filter{}).matches("anything") {
+		t.Error("empty filter does not match")
+	}
+	if err := (filter{exclude: []string{"["}}).validate(); err == nil {
+		t.Error("invalid pattern accepted")
+	}
+}
+
+func writeFile(t *testing.T, p, content string, modTime time.Time) {
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(p, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestListLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	writeFile(t, filepath.Join(dir, "a.txt"), "a", now)
+	writeFile(t, filepath.Join(dir, "sub", "b.txt"), "bb", now)
+	writeFile(t, filepath.Join(dir, "sub", "c.log"), "c", now)
+
+	items, err := listLocal(dir, filter{exclude: []string{"*.log"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 2 || items["a.txt"] == nil || items["sub/b.txt"] == nil {
+		t.Fatalf("unexpected items: %v", items)
+	}
+	if items["sub/b.txt"].size != 2 || items["sub/b.txt"].path != filepath.Join(dir, "sub", "b.txt") {
+		t.Errorf("unexpected item: %+v", items["sub/b.txt"])
+	}
+
+	items, err = listLocal(filepath.Join(dir, "missing"), filter{})
+	if err != nil || len(items) != 0 {
+		t.Errorf("listLocal of missing directory = %v, %v", items, err)
+	}
+}
+
+func TestNeedsTransfer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	old := time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)
+	p := filepath.Join(dir, "f.txt")
+	writeFile(t, p, "hello", old)
+	local := func() *item { return &item{name: "f.txt", size: 5, modTime: old, path: p} }
+	const helloMD5 = "XUFAKrxLKna5cZ2REBfFkg=="
+
+	for _, tt := range []struct {
+		desc     string
+		src, dst *item
+		want     bool
+	}{
+		{"missing", local(), nil, true},
+		{"size differs", local(), &item{size: 4, md5: helloMD5}, true},
+		{"md5 equal", local(), &item{size: 5, md5: helloMD5, modTime: old.Add(-time.Hour)}, false},
+		{"md5 differs", local(), &item{size: 5, md5: "AAAAAAAAAAAAAAAAAAAAAA=="}, true},
+		{"download md5 equal", &item{size: 5, md5: helloMD5, modTime: old.Add(time.Hour)}, local(), false},
+		{"source newer", local(), &item{size: 5, modTime: old.Add(-time.Hour)}, true},
+		{"source older", local(), &item{size: 5, modTime: old.Add(time.Hour)}, false},
+		{"download same time", &item{size: 5, modTime: old}, local(), false},
+	} {
+		got, err := needsTransfer(tt.src, tt.dst)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.desc, err)
+		}
+		if got != tt.want {
+			t.Errorf("%s: needsTransfer = %v, want %v", tt.desc, got, tt.want)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	now := time.Now()
+	src := map[string]*item{
+		"changed":   {name: "changed", size: 2, modTime: now},
+		"new":       {name: "new", size: 1, modTime: now},
+		"unchanged": {name: "unchanged", size: 1, modTime: now},
+	}
+	dst := map[string]*item{
+		"changed":    {name: "changed", size: 1, modTime: now},
+		"extraneous": {name: "extraneous", size: 1, modTime: now},
+		"unchanged":  {name: "unchanged", size: 1, modTime: now},
+	}
+
+	ops := func(actions []action) []string {
+		var out []string
+		for _, a := range actions {
+			out = append(out, a.op+" "+a.item.name)
+		}
+		return out
+	}
+
+	actions, err := plan(src, dst, "upload", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ops(actions), []string{"upload changed", "upload new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("plan = %v, want %v", got, want)
+	}
+
+	actions, err = plan(src, dst, "upload", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := ops(actions), []string{"upload changed", "delete extraneous", "upload new"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("plan = %v, want %v", got, want)
+	}
+}
+
+func TestApplySharesSlots(t *testing.T) {
+	s := syncer{parallel: 3, out: ioutil.Discard}
+	s.slots = make(chan struct{}, s.concurrency())
+
+	var (
+		mu             sync.Mutex
+		inFlight, peak int
+	)
+	request := func() error {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return nil
+	}
+
+	var actions []action
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		actions = append(actions, action{"upload", &item{name: name}})
+	}
+	err := s.apply(actions, false, func(a action) error {
+		// every file is transferred in several blocks, as uploadFile does
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			s.acquire()
+			go func() {
+				defer wg.Done()
+				defer s.release()
+				request()
+			}()
+		}
+		wg.Wait()
+		return s.withSlot(request)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if peak > s.parallel {
+		t.Errorf("%d requests in flight, want at most %d", peak, s.parallel)
+	}
+}
+
+func TestLocalPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "blobsync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	parent, dir := dir, filepath.Join(dir, "root")
+	s := syncer{dir: dir}
+
+	for name, want := range map[string]string{
+		"a.txt":        filepath.Join(dir, "a.txt"),
+		"sub/b.txt":    filepath.Join(dir, "sub", "b.txt"),
+		"sub/../c.txt": filepath.Join(dir, "c.txt"),
+		"/abs/d.txt":   filepath.Join(dir, "abs", "d.txt"),
+		"../x":         "",
+		"sub/../../x":  "",
+		"..":           "",
+		"a/../../../x": "",
+	} {
+		got, err := s.localPath(name)
+		if want == "" && err == nil || want != "" && (err != nil || got != want) {
+			t.Errorf("localPath(%q) = %q, %v; want %q", name, got, err, want)
+		}
+	}
+
+	// the download is skipped before any request is made
+	if err := s.downloadFile(&item{name: "../x"}); err == nil {
+		t.Error("download outside of the directory accepted")
+	}
+	if _, err := os.Stat(filepath.Join(parent, "x")); !os.IsNotExist(err) {
+		t.Errorf("file written outside of the directory: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/Azure/azure-sdk-for-go/storage"
+)
+
+// blockSize is the size of the blocks files are uploaded in.
+const blockSize = 4 * 1024 * 1024
+
+// uploadFile uploads a local file to its blob, in parallel blocks if it is
+// larger than a block. Each request holds one of the slots shared with the
+// other transfers. The Content-MD5 and the content type by file
+// extension are set on the blob, so that later syncs can compare by hash.
+func (s syncer) uploadFile(it *item) error {
+	sum, err := it.contentMD5()
+	if err != nil {
+		return err
+	}
+	props := storage.BlobProperties{
+		ContentType: mime.TypeByExtension(path.Ext(it.name)),
+		ContentMD5:  sum,
+	}
+	name := s.prefix + it.name
+
+	f, err := os.Open(it.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if it.size <= blockSize {
+		return s.withSlot(func() error {
+			return s.blob.CreateBlockBlobFromReader(s.container, name, uint64(it.size), f, &props)
+		})
+	}
+
+	n := int((it.size + blockSize - 1) / blockSize)
+	blocks := make([]storage.Block, n)
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for i := range blocks {
+		blocks[i] = storage.Block{
+			ID:     base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", i))),
+			Status: storage.BlockStatusUncommitted,
+		}
+
+		off := int64(i) * blockSize
+		size := it.size - off
+		if size > blockSize {
+			size = blockSize
+		}
+
+		wg.Add(1)
+		s.acquire()
+		go func(id string, off, size int64) {
+			defer wg.Done()
+			defer s.release()
+			errs <- s.blob.PutBlockWithLength(s.container, name, id, uint64(size), io.NewSectionReader(f, off, size))
+		}(blocks[i].ID, off, size)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.withSlot(func() error {
+		if err := s.blob.PutBlockList(s.container, name, blocks); err != nil {
+			return err
+		}
+		return s.blob.SetBlobProperties(s.container, name, props)
+	})
+}
+
+// downloadFile downloads a blob to its local file through a temporary file,
+// and sets the modification time of the file to that of the blob.
+func (s syncer) downloadFile(it *item) error {
+	p, err := s.localPath(it.name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+		return err
+	}
+
+	// the slot is held until the body has been read
+	s.acquire()
+	defer s.release()
+	body, err := s.blob.GetBlob(s.container, s.prefix+it.name)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	tmp, err := ioutil.TempFile(filepath.Dir(p), ".blobsync")
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(tmp, body)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), p)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Chtimes(p, it.modTime, it.modTime)
+}
+
+// localPath returns the path of the local file of the blob with the given
+// name. Names which would place the file outside of the directory, such as
+// "../x", are rejected.
+func (s syncer) localPath(name string) (string, error) {
+	p := filepath.Join(s.dir, filepath.FromSlash(name))
+	rel, err := filepath.Rel(s.dir, p)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("blob name %q is outside of the directory", name)
+	}
+	return p, nil
+}
@@ -0,0 +1,162 @@
+package management
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/Godeps/_workspace/src/golang.org/x/crypto/pkcs12"
+	"github.com/Azure/azure-sdk-for-go/core/tls"
+)
+
+// NewClientFromPFX creates a new Client using the given subscription ID and
+// the management certificate and private key in a PKCS#12 (PFX) archive
+// protected with the given password. Use PFXToPEM with NewClientFromConfig
+// to customize the client configuration.
+func NewClientFromPFX(subscriptionID string, pfxData []byte, password string) (Client, error) {
+	cert, err := PFXToPEM(pfxData, password)
+	if err != nil {
+		return client{}, err
+	}
+	return NewClient(subscriptionID, cert)
+}
+
+// NewClientFromPFXFile creates a new Client using the given subscription ID
+// and the management certificate in the PKCS#12 (PFX) file at the given
+// path, protected with the given password.
+func NewClientFromPFXFile(subscriptionID, filePath, password string) (Client, error) {
+	pfxData, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return client{}, err
+	}
+	return NewClientFromPFX(subscriptionID, pfxData, password)
+}
+
+// NewClientFromKeyPair creates a new Client using the given subscription ID
+// and a management certificate and private key which are PEM-encoded
+// separately.
+func NewClientFromKeyPair(subscriptionID string, certPEM, keyPEM []byte) (Client, error) {
+	if _, err := tls.X509KeyPair(certPEM, keyPEM); err != nil {
+		return client{}, fmt.Errorf("azure: management certificate and key do not form a valid key pair: %v", err)
+	}
+	cert := append(append([]byte{}, certPEM...), '\n')
+	return NewClient(subscriptionID, append(cert, keyPEM...))
+}
+
+// NewClientFromThumbprint creates a new Client using the given subscription
+// ID and the management certificate with the given thumbprint, which is
+// looked up in a directory of PEM files with FindCertificateByThumbprint.
+func NewClientFromThumbprint(subscriptionID, dir, thumbprint string) (Client, error) {
+	cert, err := FindCertificateByThumbprint(dir, thumbprint)
+	if err != nil {
+		return client{}, err
+	}
+	return NewClient(subscriptionID, cert)
+}
+
+// PFXToPEM decodes a PKCS#12 (PFX) archive protected with the given
+// password into the PEM-encoded certificate and private key accepted by
+// NewClient.
+func PFXToPEM(pfxData []byte, password string) ([]byte, error) {
+	blocks, err := pkcs12.ToPEM(pfxData, password)
+	if err == nil && len(blocks) == 0 {
+		// ToPEM does not report errors reading the archive, Decode does
+		_, _, err = pkcs12.Decode(pfxData, password)
+	}
+	if err == pkcs12.ErrIncorrectPassword {
+		return nil, errors.New("azure: incorrect password for PFX management certificate")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("azure: cannot decode PFX management certificate: %v", err)
+	}
+
+	var cert []byte
+	hasCert, hasKey := false, false
+	for _, b := range blocks {
+		hasCert = hasCert || b.Type == "CERTIFICATE"
+		hasKey = hasKey || strings.HasSuffix(b.Type, "PRIVATE KEY")
+		cert = append(cert, pem.EncodeToMemory(b)...)
+	}
+	if !hasCert || !hasKey {
+		return nil, errors.New("azure: PFX management certificate must contain a certificate and its private key")
+	}
+	return cert, nil
+}
+
+// FindCertificateByThumbprint looks for the certificate with the given SHA-1
+// thumbprint in the PEM files in a directory, and returns the certificate
+// and the private key in its file in the PEM format accepted by NewClient.
+// Thumbprints are matched in hexadecimal regardless of case, spaces and
+// colons.
+func FindCertificateByThumbprint(dir, thumbprint string) ([]byte, error) {
+	want := normalizeThumbprint(thumbprint)
+	if want == "" {
+		return nil, fmt.Errorf(errParamNotSpecified, "thumbprint")
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	for _, fi := range files {
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		path := filepath.Join(dir, fi.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+
+		var certs, keys []*pem.Block
+		for rest := data; ; {
+			var b *pem.Block
+			if b, rest = pem.Decode(rest); b == nil {
+				break
+			}
+			switch {
+			case b.Type == "CERTIFICATE":
+				certs = append(certs, b)
+			case strings.HasSuffix(b.Type, "PRIVATE KEY"):
+				keys = append(keys, b)
+			}
+		}
+
+		for _, c := range certs {
+			if thumbprintOf(c.Bytes) != want {
+				continue
+			}
+			cert := pem.EncodeToMemory(c)
+			for _, k := range keys {
+				key := pem.EncodeToMemory(k)
+				if _, err := tls.X509KeyPair(cert, key); err == nil {
+					return append(cert, key...), nil
+				}
+			}
+			return nil, fmt.Errorf("azure: management certificate %s in %s has no matching private key", thumbprint, path)
+		}
+	}
+
+	return nil, fmt.Errorf("azure: management certificate %s not found in %s", thumbprint, dir)
+}
+
+// thumbprintOf returns the SHA-1 thumbprint of a DER-encoded certificate
+// in upper case hexadecimal, as shown by Azure.
+func thumbprintOf(der []byte) string {
+	sum := sha1.Sum(der)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+func normalizeThumbprint(thumbprint string) string {
+	return strings.ToUpper(strings.Map(func(r rune) rune {
+		if r == ' ' || r == ':' {
+			return -1
+		}
+		return r
+	}, thumbprint))
+}
@@ -0,0 +1,147 @@
+package management_test
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+// testPFX is a PKCS#12 archive with a self-signed certificate, with
+// thumbprint B7BB82511EB5293C66FCE763B75D5F0196E6DFB1, and its private key
+// protected with the password "password".
+var testPFX = `
+MIIGGQIBAzCCBd8GCSqGSIb3DQEHAaCCBdAEggXMMIIFyDCCAscGCSqGSIb3DQEHBqCCArgwggK0
+AgEAMIICrQYJKoZIhvcNAQcBMBwGCiqGSIb3DQEMAQMwDgQIFrvwtGGLIgkCAggAgIICgFtG35lo
+tFCtn+w3B5moyQ/YM71u5MdX3K9e38mp1t2GQF04Oqk9514cVIRC/XYePrKW4E6MxPHp7UgoTtOz
+m2+yMkys0W+2tmoC/yDFJYyDk8WXpFxTzxR1+h3dtC4P75gXyElFJ5SDCpOXAWXkX4LGmotvN/my
+tgXqZMKqCcWLsa0Z9Q4R5+zSYhHPCBo1OJK9WfwlWfEfJ5RZ7fA9T8KBXI8D3Kb5sY+v/3lo4VvD
+GAONvh29T8xfDK5Imo62SAcTa7jEGx5tQ9xMf2gVZVKo5lUsCyv+fdTEzxWurcI6bGigN81G4W/M
+hVSQG1su31EzGjXVYnO4VRvHQ0A+PdGhiZZZQmGPqJ1+IFJAVaSE5WO2z5kkWR5Fy6o6NQ/6yU1S
+7neth/Vcgi4b9+5BGba9vxitCbdb7Qc3ryzfFLMoz7stvkRX1P7oAEp69XIHzZDWGkcL7dnOIARE
+Iq2cYldVF/a7pvxauVRfZb0UeanR0X7xOiaomTd9+i0+8MHim/AOZsQDAafWJ/8qfmJllw3T+9lB
+Lz4awN2mQaXcNx14/E5MKmZrzKeV2k+RwRDy1OeZEgtfDoHfImUdb+YSZ8fJFwH0/Bnw4VC39D0c
+xNN9AgmR9yVOjW4Mld0jQR9qlCmAy5ymKh1S/Q5JJm0q4izU35cnfrld0zv5IZcWe34hyLJSXrnw
+i0cxYLY9GGQ1cgTFV8dGoIW2Pp5+S2SENP3Q67HlZqciUW7kwJ0keMyDj5EpWpDzuWR6OZTrG7uS
+7qr1fAejspf3aJwZThE+fglKkbK6aYqQTfztzEeknC53bZ0VMjbEFBTxCLzTvbFzKLYD7zKwGM+s
+8z0BWCHi08UwggL5BgkqhkiG9w0BBwGgggLqBIIC5jCCAuIwggLeBgsqhkiG9w0BDAoBAqCCAqYw
+ggKiMBwGCiqGSIb3DQEMAQMwDgQIuwNXA4ecaa0CAggABIICgJ7eJ5vRqdengn8T5Yi1cTHrLTWO
+FXYQx2ehIgD2OHos40cUaLvMoqDewtAOfBA7cqtVeeGd8SBD1irtPi0IzEogyiW54QSstPdkDNpz
+Eh1xgt8WRySftlSmrb54cnx/kxVyfi9SUzjLBY3O0HzcENimy5lmLKPF/Yxiv3utFkILcPb+T0uD
+oCQhbUBqjWhJcRUT3waPnH5uRRvD0n4CYBn4Lsdx6QWAQCJP7WymqNSL9t/NYfFKXuJF+Redg5UQ
+g2FuAiI5fSCAf8OQITn4QNZmoO0GIbnuDk4d4cIXcVYh3nF2uFTLnZR/4ByiFVG8sn7PDta3RrVf
+q7KXKxWMLbIiZJJ1tF4LAI+YcoVIey2OGCJWP8IWy+fG9DDspkE5y2Cqh9tH4M21Gqa4JHJKJ95K
+NDsMzMlxA4064gUa8O2+sHsoMFCUjj98vsqDtUXTb13H1+hKI9SjHf+7FSnC8XlBLHtJ6Zw4DZdE
+ESp8vSA1I59jsQLpSKlcwndVw/OJ4KD8EjkcOrEb15eTqoqaqmR2uI/OY6xtTTTTi4G/zi9Ah2TY
+VgIGJ3Ww6nhZIDZtH7Avwr8E83id6/XDo52ItgkGL+EBAUZnP6qn/XeKOsAb/nlaRVCcx3NJig3P
+Lalb04SmDRj8cgfQO3NimiFB3zECkSekQQLF6X4vMBIN1S3EyUttuIfzEgQr/a9M6/D/QqJI7QdX
+5olT7qLT19VuyeQXbN1k5AZ6Tij5jUd2cQmhHI9RGJGzYy3E/efbrrtzAwgM/mpR1hOnbbDKWo7P
+8mDc5YjumxDk9oWBYtpx8yfrigI0n3+qIywG0iVzf1hXP8Y/ulDPfdS8MRBKyDHmca0xJTAjBgkq
+hkiG9w0BCRUxFgQUt7uCUR61KTxm/Odjt11fAZbm37EwMTAhMAkGBSsOAwIaBQAEFHWKlIl9M/W7
+d1CMfcW7Tfi22lzsBAii0d2ve5omzwICCAA=
+`
+
+func TestNewClientFromPFX(t *testing.T) {
+	pfx, err := base64.StdEncoding.DecodeString(strings.TrimSpace(testPFX))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := management.NewClientFromPFX("subscription", pfx, "password"); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = management.NewClientFromPFX("subscription", pfx, "wrong")
+	if err == nil || !strings.Contains(err.Error(), "incorrect password") {
+		t.Fatalf("Expected an incorrect password error but got %v", err)
+	}
+
+	_, err = management.NewClientFromPFX("subscription", []byte("not a pfx"), "")
+	if err == nil || !strings.Contains(err.Error(), "cannot decode PFX") {
+		t.Fatalf("Expected a decoding error but got %v", err)
+	}
+}
+
+// splitPEM returns the certificate and the private key blocks of PEM data.
+func splitPEM(data []byte) (cert, key []byte) {
+	for rest := data; ; {
+		var b *pem.Block
+		if b, rest = pem.Decode(rest); b == nil {
+			return
+		}
+		if b.Type == "CERTIFICATE" {
+			cert = append(cert, pem.EncodeToMemory(b)...)
+		} else {
+			key = append(key, pem.EncodeToMemory(b)...)
+		}
+	}
+}
+
+func TestNewClientFromKeyPair(t *testing.T) {
+	cert, key := splitPEM(testManagementCert(t))
+	if _, err := management.NewClientFromKeyPair("subscription", cert, key); err != nil {
+		t.Fatal(err)
+	}
+
+	_, otherKey := splitPEM(testManagementCert(t))
+	if _, err := management.NewClientFromKeyPair("subscription", cert, otherKey); err == nil {
+		t.Fatal("Expected an error for a key of another certificate")
+	}
+}
+
+func TestFindCertificateByThumbprint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certificates")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pfx, err := base64.StdEncoding.DecodeString(strings.TrimSpace(testPFX))
+	if err != nil {
+		t.Fatal(err)
+	}
+	found, err := management.PFXToPEM(pfx, "password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key := splitPEM(found)
+	other, otherKey := splitPEM(testManagementCert(t))
+
+	files := map[string][]byte{
+		"other.pem":   testManagementCert(t),
+		"found.pem":   append(append(append([]byte{}, otherKey...), key...), cert...),
+		"nokey.pem":   other,
+		"unrelated":   []byte("not a certificate"),
+		"sub/key.pem": key,
+	}
+	for name, data := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, err := management.FindCertificateByThumbprint(dir, "b7:bb:82:51:1e:b5:29:3c:66:fc:e7:63:b7:5d:5f:01:96:e6:df:b1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, append(cert, key...)) {
+		t.Fatalf("Unexpected certificate:\n%s", got)
+	}
+	if _, err := management.NewClientFromThumbprint("subscription", dir, "B7BB82511EB5293C66FCE763B75D5F0196E6DFB1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := management.FindCertificateByThumbprint(dir, "0000"); err == nil {
+		t.Fatal("Expected an error for an unknown thumbprint")
+	}
+}
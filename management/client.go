@@ -4,7 +4,13 @@ package management
 
 import (
 	"errors"
+	"fmt"
+	"net"
+	"net/url"
 	"time"
+
+	"github.com/Azure/azure-sdk-for-go/core/http"
+	"github.com/Azure/azure-sdk-for-go/core/tls"
 )
 
 const (
@@ -21,6 +27,7 @@ const (
 type client struct {
 	publishSettings publishSettings
 	config          ClientConfig
+	httpClient      *http.Client
 }
 
 // Client is the base Azure Service Management API client instance that
@@ -72,6 +79,31 @@ type ClientConfig struct {
 	OperationPollInterval time.Duration
 	UserAgent             string
 	APIVersion            string
+
+	// RetryPolicy configures how failed requests are retried.
+	// DefaultRetryPolicy is used if it is nil.
+	RetryPolicy *RetryPolicy
+
+	// Transport, if set, is used to send requests instead of a transport
+	// built from the settings below. It must present the management
+	// certificate to the server itself.
+	Transport http.RoundTripper
+
+	// Dial specifies the dial function for creating TCP connections. If it
+	// is nil, net.Dial is used.
+	Dial func(network, addr string) (net.Conn, error)
+
+	// Proxy returns the proxy for a request, as http.ProxyURL does for a
+	// fixed one. If it is nil, http.ProxyFromEnvironment is used.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// RequestTimeout limits the time of a request including reading the
+	// response body. Zero means no timeout.
+	RequestTimeout time.Duration
+
+	// TLSHandshakeTimeout limits the time of the TLS handshake. Zero means
+	// no timeout.
+	TLSHandshakeTimeout time.Duration
 }
 
 // NewAnonymousClient creates a new azure.Client with no credentials set.
@@ -113,6 +145,11 @@ func makeClient(subscriptionID string, managementCert []byte, config ClientConfi
 		return c, errors.New("azure: management certificate required")
 	}
 
+	cert, err := tls.X509KeyPair(managementCert, managementCert)
+	if err != nil {
+		return c, fmt.Errorf("azure: invalid management certificate: %v", err)
+	}
+
 	publishSettings := publishSettings{
 		SubscriptionID:   subscriptionID,
 		SubscriptionCert: managementCert,
@@ -130,9 +167,16 @@ func makeClient(subscriptionID string, managementCert []byte, config ClientConfi
 	case config.UserAgent == "":
 		config.UserAgent = DefaultUserAgent
 	}
+	if p := config.RetryPolicy; p != nil && (p.MaxRetries < 0 || p.MinBackoff < 0 || p.MaxBackoff < p.MinBackoff) {
+		return c, errors.New("azure: retry policy must specify a non-negative number of retries and backoff range")
+	}
+	if config.RequestTimeout < 0 || config.TLSHandshakeTimeout < 0 {
+		return c, errors.New("azure: timeouts must not be negative")
+	}
 
 	return client{
 		publishSettings: publishSettings,
 		config:          config,
+		httpClient:      newHTTPClient(config, []tls.Certificate{cert}),
 	}, nil
 }
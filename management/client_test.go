@@ -0,0 +1,163 @@
+package management_test
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	corehttp "github.com/Azure/azure-sdk-for-go/core/http"
+	"github.com/Azure/azure-sdk-for-go/management"
+	"github.com/Azure/azure-sdk-for-go/management/testutils"
+)
+
+// testManagementCert returns a self-signed certificate and its private key
+// in PEM format, as accepted by NewClient.
+func testManagementCert(t *testing.T) []byte {
+	der, key := testutils.NewSelfSignedCertificate(t, time.Now().Add(time.Hour))
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	return append(cert, pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})...)
+}
+
+func TestNewClientFromConfig_Validation(t *testing.T) {
+	cert := testManagementCert(t)
+	if _, err := management.NewClient("subscription", cert); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := management.NewClient("subscription", []byte("not a certificate")); err == nil {
+		t.Fatal("Expected an error for an invalid management certificate")
+	}
+
+	config := management.DefaultConfig()
+	config.RequestTimeout = -time.Second
+	if _, err := management.NewClientFromConfig("subscription", cert, config); err == nil {
+		t.Fatal("Expected an error for a negative timeout")
+	}
+
+	config = management.DefaultConfig()
+	config.RetryPolicy = &management.RetryPolicy{MaxRetries: 1, MinBackoff: time.Second}
+	if _, err := management.NewClientFromConfig("subscription", cert, config); err == nil {
+		t.Fatal("Expected an error for an invalid retry policy")
+	}
+}
+
+// countingTransport counts the requests sent through it.
+type countingTransport struct {
+	corehttp.RoundTripper
+	mu       sync.Mutex
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(r *corehttp.Request) (*corehttp.Response, error) {
+	t.mu.Lock()
+	t.requests++
+	t.mu.Unlock()
+	return t.RoundTripper.RoundTrip(r)
+}
+
+func TestClientConfig_Transport(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		conns int
+	)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	ts.Config.ConnState = func(c net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			mu.Lock()
+			conns++
+			mu.Unlock()
+		}
+	}
+	ts.Start()
+	defer ts.Close()
+
+	// connections are reused across requests
+	dials := 0
+	config := management.DefaultConfig()
+	config.ManagementURL = ts.URL
+	config.Dial = func(network, addr string) (net.Conn, error) {
+		dials++
+		return net.Dial(network, addr)
+	}
+	c, err := management.NewClientFromConfig("subscription", testManagementCert(t), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err := c.SendAzureGetRequest("url"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if dials != 1 || conns != 1 {
+		t.Fatalf("Expected 1 connection but got %d dials and %d connections", dials, conns)
+	}
+
+	transport := &countingTransport{RoundTripper: &corehttp.Transport{}}
+	config = management.DefaultConfig()
+	config.ManagementURL = ts.URL
+	config.Transport = transport
+	c, err = management.NewClientFromConfig("subscription", testManagementCert(t), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SendAzureGetRequest("url"); err != nil {
+		t.Fatal(err)
+	}
+	if transport.requests != 1 {
+		t.Fatalf("Expected 1 request through the transport but got %d", transport.requests)
+	}
+}
+
+func TestClientConfig_RequestTimeout(t *testing.T) {
+	release := make(chan struct{})
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	config := management.DefaultConfig()
+	config.ManagementURL = ts.URL
+	config.RequestTimeout = 10 * time.Millisecond
+	config.RetryPolicy = &management.RetryPolicy{}
+	c, err := management.NewClientFromConfig("subscription", testManagementCert(t), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.SendAzureGetRequest("url"); err == nil {
+		t.Fatal("Expected the request to time out")
+	}
+}
+
+func TestSendAzureGetRequest_URL(t *testing.T) {
+	var paths []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+	}))
+	defer ts.Close()
+
+	config := management.DefaultConfig()
+	config.ManagementURL = ts.URL
+	c, err := management.NewClientFromConfig("subscription", testManagementCert(t), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, url := range []string{management.SubscriptionURL, "services/hostedservices"} {
+		if _, err := c.SendAzureGetRequest(url); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if expected := []string{"/subscription", "/subscription/services/hostedservices"}; !reflect.DeepEqual(paths, expected) {
+		t.Fatalf("Expected %q but got %q", expected, paths)
+	}
+
+	if _, err := c.SendAzureGetRequest(""); err == nil {
+		t.Fatal("Expected an error for an empty url")
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"encoding/base64"
 	"encoding/xml"
 	"fmt"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/management"
 )
@@ -18,6 +19,12 @@ const (
 	deleteAzureDeploymentURL          = "services/hostedservices/%s/deployments/%s"
 	getHostedServicePropertiesURL     = "services/hostedservices/%s"
 	azureServiceCertificateURL        = "services/hostedservices/%s/certificates"
+	azureCertificateURL               = "services/hostedservices/%s/certificates/%s-%s"
+	azureDeploymentSlotURL            = "services/hostedservices/%s/deploymentslots/%s"
+	changeConfigurationURL            = "services/hostedservices/%s/deploymentslots/%s/?comp=config"
+	upgradeDeploymentURL              = "services/hostedservices/%s/deploymentslots/%s/?comp=upgrade"
+	walkUpgradeDomainURL              = "services/hostedservices/%s/deploymentslots/%s/?comp=walkupgradedomain"
+	updateDeploymentStatusURL         = "services/hostedservices/%s/deploymentslots/%s/?comp=status"
 
 	errParamNotSpecified = "Parameter %s is not specified."
 )
@@ -123,3 +130,360 @@ func (h HostedServiceClient) AddCertificate(dnsName string, certData []byte, cer
 	requestURL := fmt.Sprintf(azureServiceCertificateURL, dnsName)
 	return h.client.SendAzurePostRequest(requestURL, buffer)
 }
+
+// ListCertificates returns the service certificates of the hosted service.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460788.aspx
+func (h HostedServiceClient) ListCertificates(dnsName string) (ListCertificatesResponse, error) {
+	var response ListCertificatesResponse
+	if dnsName == "" {
+		return response, fmt.Errorf(errParamNotSpecified, "dnsName")
+	}
+
+	requestURL := fmt.Sprintf(azureServiceCertificateURL, dnsName)
+	data, err := h.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return response, err
+	}
+
+	if err := xml.Unmarshal(data, &response); err != nil {
+		return response, err
+	}
+	for i := range response.Certificates {
+		if err := decodeCertificateData(&response.Certificates[i]); err != nil {
+			return response, err
+		}
+	}
+	return response, nil
+}
+
+// GetCertificate returns the service certificate of the hosted service with
+// the given thumbprint, which is computed with thumbprintAlgorithm (such as
+// "sha1").
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460792.aspx
+func (h HostedServiceClient) GetCertificate(dnsName, thumbprintAlgorithm, thumbprint string) (Certificate, error) {
+	var certificate Certificate
+	if dnsName == "" {
+		return certificate, fmt.Errorf(errParamNotSpecified, "dnsName")
+	}
+	if thumbprintAlgorithm == "" {
+		return certificate, fmt.Errorf(errParamNotSpecified, "thumbprintAlgorithm")
+	}
+	if thumbprint == "" {
+		return certificate, fmt.Errorf(errParamNotSpecified, "thumbprint")
+	}
+
+	requestURL := fmt.Sprintf(azureCertificateURL, dnsName, thumbprintAlgorithm, thumbprint)
+	data, err := h.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return certificate, err
+	}
+
+	if err := xml.Unmarshal(data, &certificate); err != nil {
+		return certificate, err
+	}
+	// the service omits these from the response to a single certificate
+	if certificate.ThumbprintAlgorithm == "" {
+		certificate.ThumbprintAlgorithm = thumbprintAlgorithm
+	}
+	if certificate.Thumbprint == "" {
+		certificate.Thumbprint = thumbprint
+	}
+	return certificate, decodeCertificateData(&certificate)
+}
+
+// DeleteCertificate deletes the service certificate of the hosted service
+// with the given thumbprint, which is computed with thumbprintAlgorithm.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460803.aspx
+func (h HostedServiceClient) DeleteCertificate(dnsName, thumbprintAlgorithm, thumbprint string) (management.OperationID, error) {
+	if dnsName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "dnsName")
+	}
+	if thumbprintAlgorithm == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "thumbprintAlgorithm")
+	}
+	if thumbprint == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "thumbprint")
+	}
+
+	requestURL := fmt.Sprintf(azureCertificateURL, dnsName, thumbprintAlgorithm, thumbprint)
+	return h.client.SendAzureDeleteRequest(requestURL)
+}
+
+// ListExpiringCertificates returns the service certificates of all hosted
+// services returned by ListHostedServices which expire before the given
+// time, including those that have already expired.
+func (h HostedServiceClient) ListExpiringCertificates(before time.Time) ([]ExpiringCertificate, error) {
+	services, err := h.ListHostedServices()
+	if err != nil {
+		return nil, err
+	}
+
+	var expiring []ExpiringCertificate
+	for _, service := range services.HostedServices {
+		certificates, err := h.ListCertificates(service.ServiceName)
+		if err != nil {
+			return nil, err
+		}
+		found, err := certificatesExpiringBefore(service.ServiceName, certificates.Certificates, before)
+		if err != nil {
+			return nil, err
+		}
+		expiring = append(expiring, found...)
+	}
+	return expiring, nil
+}
+
+func certificatesExpiringBefore(serviceName string, certificates []Certificate, before time.Time) ([]ExpiringCertificate, error) {
+	var expiring []ExpiringCertificate
+	for _, c := range certificates {
+		cert, err := c.X509Certificate()
+		if err != nil {
+			return nil, fmt.Errorf("Cannot parse certificate %s of hosted service %s: %v", c.Thumbprint, serviceName, err)
+		}
+		if cert.NotAfter.Before(before) {
+			expiring = append(expiring, ExpiringCertificate{
+				ServiceName: serviceName,
+				Certificate: c,
+				NotAfter:    cert.NotAfter,
+			})
+		}
+	}
+	return expiring, nil
+}
+
+func decodeCertificateData(c *Certificate) error {
+	data, err := base64.StdEncoding.DecodeString(c.DataBase64)
+	if err != nil {
+		return err
+	}
+	c.Data = data
+	return nil
+}
+
+// CreateDeployment uploads a service package and creates a deployment of it
+// in the given slot of the hosted service. The package must be stored in a
+// blob of a storage account in the same subscription.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460813.aspx
+func (h HostedServiceClient) CreateDeployment(serviceName string, slot DeploymentSlot, params CreateDeploymentParameters) (management.OperationID, error) {
+	if serviceName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "serviceName")
+	}
+	if slot == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "slot")
+	}
+	if params.Name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Name")
+	}
+	if params.PackageURL == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "PackageURL")
+	}
+	if params.Configuration == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Configuration")
+	}
+	if params.Label == "" {
+		params.Label = params.Name
+	}
+
+	params.Label = base64.StdEncoding.EncodeToString([]byte(params.Label))
+	params.Configuration = base64.StdEncoding.EncodeToString([]byte(params.Configuration))
+	req, err := xml.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(azureDeploymentSlotURL, serviceName, slot)
+	return h.client.SendAzurePostRequest(requestURL, req)
+}
+
+// GetDeploymentBySlot returns the deployment in the given slot of the hosted
+// service.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460804.aspx
+func (h HostedServiceClient) GetDeploymentBySlot(serviceName string, slot DeploymentSlot) (Deployment, error) {
+	var deployment Deployment
+	if serviceName == "" {
+		return deployment, fmt.Errorf(errParamNotSpecified, "serviceName")
+	}
+	if slot == "" {
+		return deployment, fmt.Errorf(errParamNotSpecified, "slot")
+	}
+
+	requestURL := fmt.Sprintf(azureDeploymentSlotURL, serviceName, slot)
+	response, err := h.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return deployment, err
+	}
+
+	if err := xml.Unmarshal(response, &deployment); err != nil {
+		return deployment, err
+	}
+
+	label, err := base64.StdEncoding.DecodeString(deployment.LabelBase64)
+	if err != nil {
+		return deployment, err
+	}
+	deployment.Label = string(label)
+
+	configuration, err := base64.StdEncoding.DecodeString(deployment.ConfigurationBase64)
+	if err != nil {
+		return deployment, err
+	}
+	deployment.Configuration = string(configuration)
+	return deployment, nil
+}
+
+// SwapDeployment swaps the virtual IP addresses of the deployments in the
+// production and staging slots of the hosted service. productionName may
+// be empty if there is no deployment in the production slot.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460814.aspx
+func (h HostedServiceClient) SwapDeployment(serviceName, productionName, sourceName string) (management.OperationID, error) {
+	if serviceName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "serviceName")
+	}
+	if sourceName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "sourceName")
+	}
+
+	req, err := xml.Marshal(SwapDeploymentParameters{
+		Production:       productionName,
+		SourceDeployment: sourceName,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(getHostedServicePropertiesURL, serviceName)
+	return h.client.SendAzurePostRequest(requestURL, req)
+}
+
+// ChangeDeploymentConfiguration replaces the service configuration of the
+// deployment in the given slot of the hosted service.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460809.aspx
+func (h HostedServiceClient) ChangeDeploymentConfiguration(serviceName string, slot DeploymentSlot, params ChangeConfigurationParameters) (management.OperationID, error) {
+	if serviceName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "serviceName")
+	}
+	if slot == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "slot")
+	}
+	if params.Configuration == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Configuration")
+	}
+
+	params.Configuration = base64.StdEncoding.EncodeToString([]byte(params.Configuration))
+	req, err := xml.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(changeConfigurationURL, serviceName, slot)
+	return h.client.SendAzurePostRequest(requestURL, req)
+}
+
+// UpgradeDeployment starts an upgrade of the deployment in the given slot of
+// the hosted service to a new service package and configuration. In manual
+// mode, each upgrade domain must then be upgraded with WalkUpgradeDomain.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460793.aspx
+func (h HostedServiceClient) UpgradeDeployment(serviceName string, slot DeploymentSlot, params UpgradeDeploymentParameters) (management.OperationID, error) {
+	if serviceName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "serviceName")
+	}
+	if slot == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "slot")
+	}
+	if params.Mode == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Mode")
+	}
+	if params.PackageURL == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "PackageURL")
+	}
+	if params.Configuration == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Configuration")
+	}
+	if params.Label == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Label")
+	}
+
+	params.Label = base64.StdEncoding.EncodeToString([]byte(params.Label))
+	params.Configuration = base64.StdEncoding.EncodeToString([]byte(params.Configuration))
+	req, err := xml.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(upgradeDeploymentURL, serviceName, slot)
+	return h.client.SendAzurePostRequest(requestURL, req)
+}
+
+// WalkUpgradeDomain upgrades the given upgrade domain of a deployment which
+// is being upgraded in manual mode. Upgrade domains are numbered from zero
+// and must be walked in order.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460800.aspx
+func (h HostedServiceClient) WalkUpgradeDomain(serviceName string, slot DeploymentSlot, upgradeDomain int) (management.OperationID, error) {
+	if serviceName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "serviceName")
+	}
+	if slot == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "slot")
+	}
+	if upgradeDomain < 0 {
+		return "", fmt.Errorf("Invalid upgrade domain: %d", upgradeDomain)
+	}
+
+	req, err := xml.Marshal(walkUpgradeDomainParameters{UpgradeDomain: upgradeDomain})
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(walkUpgradeDomainURL, serviceName, slot)
+	return h.client.SendAzurePostRequest(requestURL, req)
+}
+
+// UpdateDeploymentStatus starts or suspends the deployment in the given slot
+// of the hosted service. status must be DeploymentStatusRunning or
+// DeploymentStatusSuspended.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460808.aspx
+func (h HostedServiceClient) UpdateDeploymentStatus(serviceName string, slot DeploymentSlot, status DeploymentStatus) (management.OperationID, error) {
+	if serviceName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "serviceName")
+	}
+	if slot == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "slot")
+	}
+	if status != DeploymentStatusRunning && status != DeploymentStatusSuspended {
+		return "", fmt.Errorf("Invalid deployment status: %q", status)
+	}
+
+	req, err := xml.Marshal(updateDeploymentStatusParameters{Status: status})
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(updateDeploymentStatusURL, serviceName, slot)
+	return h.client.SendAzurePostRequest(requestURL, req)
+}
+
+// DeleteDeploymentBySlot deletes the deployment in the given slot of the
+// hosted service.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460815.aspx
+func (h HostedServiceClient) DeleteDeploymentBySlot(serviceName string, slot DeploymentSlot) (management.OperationID, error) {
+	if serviceName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "serviceName")
+	}
+	if slot == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "slot")
+	}
+
+	requestURL := fmt.Sprintf(azureDeploymentSlotURL, serviceName, slot)
+	return h.client.SendAzureDeleteRequest(requestURL)
+}
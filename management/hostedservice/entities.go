@@ -1,7 +1,9 @@
 package hostedservice
 
 import (
+	"crypto/x509"
 	"encoding/xml"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/management"
 )
@@ -46,6 +48,34 @@ type CertificateFile struct {
 	Password          string `xml:",omitempty"`
 }
 
+// Certificate is a service certificate of a hosted service. Data holds the
+// decoded value of DataBase64, the public part of the certificate in DER
+// format.
+type Certificate struct {
+	CertificateURL      string `xml:"CertificateUrl"`
+	Thumbprint          string
+	ThumbprintAlgorithm string
+	DataBase64          string `xml:"Data"`
+	Data                []byte `xml:"-"`
+}
+
+// X509Certificate parses the certificate data.
+func (c Certificate) X509Certificate() (*x509.Certificate, error) {
+	return x509.ParseCertificate(c.Data)
+}
+
+type ListCertificatesResponse struct {
+	Certificates []Certificate `xml:"Certificate"`
+}
+
+// ExpiringCertificate is a service certificate reported by
+// ListExpiringCertificates.
+type ExpiringCertificate struct {
+	ServiceName string
+	Certificate Certificate
+	NotAfter    time.Time
+}
+
 type CertificateFormat string
 
 const (
@@ -56,3 +86,147 @@ const (
 type ListHostedServicesResponse struct {
 	HostedServices []HostedService `xml:"HostedService"`
 }
+
+// DeploymentSlot is the environment of a cloud service a deployment runs in.
+type DeploymentSlot string
+
+const (
+	DeploymentSlotProduction = DeploymentSlot("Production")
+	DeploymentSlotStaging    = DeploymentSlot("Staging")
+)
+
+// CreateDeploymentParameters describes a deployment of a service package.
+// Label and Configuration are base64-encoded by CreateDeployment.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460813.aspx
+type CreateDeploymentParameters struct {
+	XMLName              xml.Name `xml:"http://schemas.microsoft.com/windowsazure CreateDeployment"`
+	Name                 string
+	PackageURL           string `xml:"PackageUrl"`
+	Label                string
+	Configuration        string
+	StartDeployment      bool                `xml:",omitempty"`
+	TreatWarningsAsError bool                `xml:",omitempty"`
+	ExtendedProperties   *[]ExtendedProperty `xml:"ExtendedProperties>ExtendedProperty,omitempty"`
+}
+
+type ExtendedProperty struct {
+	Name  string
+	Value string
+}
+
+// Deployment is a deployment of a cloud service as returned by
+// GetDeploymentBySlot. Label and Configuration hold the decoded values of
+// LabelBase64 and ConfigurationBase64.
+type Deployment struct {
+	XMLName             xml.Name `xml:"http://schemas.microsoft.com/windowsazure Deployment"`
+	Name                string
+	DeploymentSlot      DeploymentSlot
+	PrivateID           string
+	Status              DeploymentStatus
+	LabelBase64         string         `xml:"Label"`
+	Label               string         `xml:"-"`
+	URL                 string         `xml:"Url"`
+	ConfigurationBase64 string         `xml:"Configuration"`
+	Configuration       string         `xml:"-"`
+	RoleInstanceList    []RoleInstance `xml:">RoleInstance"`
+	UpgradeStatus       *UpgradeStatus
+	UpgradeDomainCount  int
+	SdkVersion          string
+	Locked              bool
+	RollbackAllowed     bool
+	CreatedTime         string
+	LastModifiedTime    string
+	ExtendedProperties  []ExtendedProperty `xml:">ExtendedProperty"`
+}
+
+type DeploymentStatus string
+
+const (
+	DeploymentStatusRunning                = DeploymentStatus("Running")
+	DeploymentStatusSuspended              = DeploymentStatus("Suspended")
+	DeploymentStatusRunningTransitioning   = DeploymentStatus("RunningTransitioning")
+	DeploymentStatusSuspendedTransitioning = DeploymentStatus("SuspendedTransitioning")
+	DeploymentStatusStarting               = DeploymentStatus("Starting")
+	DeploymentStatusSuspending             = DeploymentStatus("Suspending")
+	DeploymentStatusDeploying              = DeploymentStatus("Deploying")
+	DeploymentStatusDeleting               = DeploymentStatus("Deleting")
+)
+
+type RoleInstance struct {
+	RoleName              string
+	InstanceName          string
+	InstanceStatus        string
+	InstanceUpgradeDomain int
+	InstanceFaultDomain   int
+	InstanceSize          string
+	InstanceStateDetails  string
+	InstanceErrorCode     string
+	IPAddress             string `xml:"IpAddress"`
+	PowerState            string
+	HostName              string
+}
+
+type UpgradeStatus struct {
+	UpgradeType               UpgradeMode
+	CurrentUpgradeDomainState string
+	CurrentUpgradeDomain      int
+}
+
+// UpgradeMode specifies how update domains are upgraded when the
+// configuration or the package of a deployment changes.
+type UpgradeMode string
+
+const (
+	UpgradeModeAuto         = UpgradeMode("Auto")
+	UpgradeModeManual       = UpgradeMode("Manual")
+	UpgradeModeSimultaneous = UpgradeMode("Simultaneous")
+)
+
+// SwapDeploymentParameters swaps the deployment in the production slot with
+// the source deployment in the staging slot.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460814.aspx
+type SwapDeploymentParameters struct {
+	XMLName          xml.Name `xml:"http://schemas.microsoft.com/windowsazure Swap"`
+	Production       string
+	SourceDeployment string
+}
+
+// ChangeConfigurationParameters replaces the service configuration of a
+// deployment. Configuration is base64-encoded by
+// ChangeDeploymentConfiguration.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460809.aspx
+type ChangeConfigurationParameters struct {
+	XMLName              xml.Name `xml:"http://schemas.microsoft.com/windowsazure ChangeConfiguration"`
+	Configuration        string
+	TreatWarningsAsError bool                `xml:",omitempty"`
+	Mode                 UpgradeMode         `xml:",omitempty"`
+	ExtendedProperties   *[]ExtendedProperty `xml:"ExtendedProperties>ExtendedProperty,omitempty"`
+}
+
+// UpgradeDeploymentParameters upgrades a deployment to a new service
+// package. Label and Configuration are base64-encoded by UpgradeDeployment.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460793.aspx
+type UpgradeDeploymentParameters struct {
+	XMLName            xml.Name `xml:"http://schemas.microsoft.com/windowsazure UpgradeDeployment"`
+	Mode               UpgradeMode
+	PackageURL         string `xml:"PackageUrl"`
+	Configuration      string
+	Label              string
+	RoleToUpgrade      string `xml:",omitempty"`
+	Force              bool
+	ExtendedProperties *[]ExtendedProperty `xml:"ExtendedProperties>ExtendedProperty,omitempty"`
+}
+
+type walkUpgradeDomainParameters struct {
+	XMLName       xml.Name `xml:"http://schemas.microsoft.com/windowsazure WalkUpgradeDomain"`
+	UpgradeDomain int
+}
+
+type updateDeploymentStatusParameters struct {
+	XMLName xml.Name `xml:"http://schemas.microsoft.com/windowsazure UpdateDeploymentStatus"`
+	Status  DeploymentStatus
+}
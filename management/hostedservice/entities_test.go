@@ -0,0 +1,187 @@
+package hostedservice
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/management/testutils"
+)
+
+func Test_CreateDeploymentParameters_Marshal(t *testing.T) {
+	data, err := xml.Marshal(CreateDeploymentParameters{
+		Name:            "deployment",
+		PackageURL:      "https://account.blob.core.windows.net/packages/service.cspkg",
+		Label:           "bGFiZWw=",
+		Configuration:   "Y29uZmln",
+		StartDeployment: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<CreateDeployment xmlns="http://schemas.microsoft.com/windowsazure"><Name>deployment</Name>` +
+		`<PackageUrl>https://account.blob.core.windows.net/packages/service.cspkg</PackageUrl>` +
+		`<Label>bGFiZWw=</Label><Configuration>Y29uZmln</Configuration><StartDeployment>true</StartDeployment></CreateDeployment>`
+	if string(data) != expected {
+		t.Fatalf("Expected %q but got %q", expected, string(data))
+	}
+}
+
+func Test_UpgradeDeploymentParameters_Marshal(t *testing.T) {
+	data, err := xml.Marshal(UpgradeDeploymentParameters{
+		Mode:               UpgradeModeManual,
+		PackageURL:         "package-url",
+		Configuration:      "Y29uZmln",
+		Label:              "bGFiZWw=",
+		RoleToUpgrade:      "WebRole",
+		ExtendedProperties: &[]ExtendedProperty{{Name: "name", Value: "value"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<UpgradeDeployment xmlns="http://schemas.microsoft.com/windowsazure"><Mode>Manual</Mode>` +
+		`<PackageUrl>package-url</PackageUrl><Configuration>Y29uZmln</Configuration><Label>bGFiZWw=</Label>` +
+		`<RoleToUpgrade>WebRole</RoleToUpgrade><Force>false</Force>` +
+		`<ExtendedProperties><ExtendedProperty><Name>name</Name><Value>value</Value></ExtendedProperty></ExtendedProperties>` +
+		`</UpgradeDeployment>`
+	if string(data) != expected {
+		t.Fatalf("Expected %q but got %q", expected, string(data))
+	}
+}
+
+func Test_SwapDeploymentParameters_Marshal(t *testing.T) {
+	data, err := xml.Marshal(SwapDeploymentParameters{Production: "prod", SourceDeployment: "staging"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<Swap xmlns="http://schemas.microsoft.com/windowsazure"><Production>prod</Production><SourceDeployment>staging</SourceDeployment></Swap>`
+	if string(data) != expected {
+		t.Fatalf("Expected %q but got %q", expected, string(data))
+	}
+}
+
+func Test_Deployment_Unmarshal(t *testing.T) {
+	// trimmed from https://msdn.microsoft.com/en-us/library/azure/ee460804.aspx
+	response := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<Deployment xmlns="http://schemas.microsoft.com/windowsazure">
+  <Name>deployment</Name>
+  <DeploymentSlot>Staging</DeploymentSlot>
+  <PrivateID>0123456789abcdef</PrivateID>
+  <Status>Running</Status>
+  <Label>bGFiZWw=</Label>
+  <Url>http://0123456789abcdef.cloudapp.net/</Url>
+  <Configuration>Y29uZmln</Configuration>
+  <RoleInstanceList>
+    <RoleInstance>
+      <RoleName>WebRole</RoleName>
+      <InstanceName>WebRole_IN_0</InstanceName>
+      <InstanceStatus>ReadyRole</InstanceStatus>
+      <InstanceUpgradeDomain>0</InstanceUpgradeDomain>
+      <InstanceFaultDomain>1</InstanceFaultDomain>
+    </RoleInstance>
+  </RoleInstanceList>
+  <UpgradeStatus>
+    <UpgradeType>Manual</UpgradeType>
+    <CurrentUpgradeDomainState>Before</CurrentUpgradeDomainState>
+    <CurrentUpgradeDomain>1</CurrentUpgradeDomain>
+  </UpgradeStatus>
+  <UpgradeDomainCount>2</UpgradeDomainCount>
+</Deployment>`)
+
+	var deployment Deployment
+	if err := xml.Unmarshal(response, &deployment); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := DeploymentSlotStaging; deployment.DeploymentSlot != expected {
+		t.Fatalf("Expected %q but got %q", expected, deployment.DeploymentSlot)
+	}
+	if expected := DeploymentStatusRunning; deployment.Status != expected {
+		t.Fatalf("Expected %q but got %q", expected, deployment.Status)
+	}
+	if expected := "Y29uZmln"; deployment.ConfigurationBase64 != expected {
+		t.Fatalf("Expected %q but got %q", expected, deployment.ConfigurationBase64)
+	}
+	if len(deployment.RoleInstanceList) != 1 || deployment.RoleInstanceList[0].InstanceFaultDomain != 1 {
+		t.Fatalf("Unexpected role instances: %+v", deployment.RoleInstanceList)
+	}
+	if deployment.UpgradeStatus == nil || deployment.UpgradeStatus.UpgradeType != UpgradeModeManual || deployment.UpgradeStatus.CurrentUpgradeDomain != 1 {
+		t.Fatalf("Unexpected upgrade status: %+v", deployment.UpgradeStatus)
+	}
+}
+
+func testCertificate(t *testing.T, notAfter time.Time) Certificate {
+	der, _ := testutils.NewSelfSignedCertificate(t, notAfter)
+	return Certificate{Thumbprint: notAfter.Format("20060102"), DataBase64: base64.StdEncoding.EncodeToString(der)}
+}
+
+func Test_ListCertificatesResponse_Unmarshal(t *testing.T) {
+	c := testCertificate(t, time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC))
+	response := []byte(fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<Certificates xmlns="http://schemas.microsoft.com/windowsazure">
+  <Certificate>
+    <CertificateUrl>https://management.core.windows.net/subscription/services/hostedservices/service/certificates/sha1-thumbprint</CertificateUrl>
+    <Thumbprint>thumbprint</Thumbprint>
+    <ThumbprintAlgorithm>sha1</ThumbprintAlgorithm>
+    <Data>%s</Data>
+  </Certificate>
+</Certificates>`, c.DataBase64))
+
+	var list ListCertificatesResponse
+	if err := xml.Unmarshal(response, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Certificates) != 1 {
+		t.Fatalf("Expected 1 certificate but got %d", len(list.Certificates))
+	}
+
+	certificate := list.Certificates[0]
+	if expected := "sha1"; certificate.ThumbprintAlgorithm != expected {
+		t.Fatalf("Expected %q but got %q", expected, certificate.ThumbprintAlgorithm)
+	}
+	if err := decodeCertificateData(&certificate); err != nil {
+		t.Fatal(err)
+	}
+	cert, err := certificate.X509Certificate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "test"; cert.Subject.CommonName != expected {
+		t.Fatalf("Expected %q but got %q", expected, cert.Subject.CommonName)
+	}
+}
+
+func Test_certificatesExpiringBefore(t *testing.T) {
+	before := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	var certificates []Certificate
+	for _, notAfter := range []time.Time{before.AddDate(0, 0, -1), before.AddDate(0, 0, 1), before.AddDate(-1, 0, 0)} {
+		c := testCertificate(t, notAfter)
+		if err := decodeCertificateData(&c); err != nil {
+			t.Fatal(err)
+		}
+		certificates = append(certificates, c)
+	}
+
+	expiring, err := certificatesExpiringBefore("service", certificates, before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(expiring) != 2 {
+		t.Fatalf("Expected 2 expiring certificates but got %d", len(expiring))
+	}
+	for i, expected := range []string{"20151231", "20150101"} {
+		if expiring[i].ServiceName != "service" || expiring[i].Certificate.Thumbprint != expected {
+			t.Fatalf("Unexpected expiring certificate %d: %+v", i, expiring[i])
+		}
+	}
+
+	_, err = certificatesExpiringBefore("service", []Certificate{{Thumbprint: "invalid"}}, before)
+	if err == nil {
+		t.Fatal("Expected an error for invalid certificate data")
+	}
+}
@@ -3,6 +3,7 @@ package management
 import (
 	"bytes"
 	"fmt"
+	"time"
 
 	"github.com/Azure/azure-sdk-for-go/core/http"
 	"github.com/Azure/azure-sdk-for-go/core/tls"
@@ -16,6 +17,10 @@ const (
 	defaultContentHeaderValue = "application/xml"
 )
 
+// SubscriptionURL is the url of the subscription itself, to which the urls
+// of requests are relative.
+const SubscriptionURL = "."
+
 func (client client) SendAzureGetRequest(url string) ([]byte, error) {
 	resp, err := client.sendAzureRequest("GET", url, "", nil)
 	if err != nil {
@@ -71,9 +76,13 @@ func (client client) sendAzureRequest(method, url, contentType string, data []by
 		return nil, fmt.Errorf(errParamNotSpecified, "url")
 	}
 
-	httpClient := client.createHTTPClient()
+	httpClient := client.httpClient
+	if httpClient == nil {
+		// anonymous clients have no certificate
+		httpClient = newHTTPClient(client.config, nil)
+	}
 
-	response, err := client.sendRequest(httpClient, url, method, contentType, data, 5)
+	response, err := client.sendRequest(httpClient, url, method, contentType, data)
 	if err != nil {
 		return nil, err
 	}
@@ -81,32 +90,40 @@ func (client client) sendAzureRequest(method, url, contentType string, data []by
 	return response, nil
 }
 
-// createHTTPClient creates an HTTP Client configured with the key pair for
-// the subscription for this client.
-func (client client) createHTTPClient() *http.Client {
-	cert, _ := tls.X509KeyPair(client.publishSettings.SubscriptionCert, client.publishSettings.SubscriptionKey)
-
-	ssl := &tls.Config{}
-	ssl.Certificates = []tls.Certificate{cert}
-
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			Proxy:           http.ProxyFromEnvironment,
-			TLSClientConfig: ssl,
-		},
+// newHTTPClient creates an HTTP Client which presents the given
+// certificates and is configured with the transport settings of the
+// configuration. The client is shared by all requests of a Client so that
+// connections are reused.
+func newHTTPClient(config ClientConfig, certs []tls.Certificate) *http.Client {
+	transport := config.Transport
+	if transport == nil {
+		proxy := config.Proxy
+		if proxy == nil {
+			proxy = http.ProxyFromEnvironment
+		}
+		transport = &http.Transport{
+			Proxy:               proxy,
+			Dial:                config.Dial,
+			TLSClientConfig:     &tls.Config{Certificates: certs},
+			TLSHandshakeTimeout: config.TLSHandshakeTimeout,
+		}
 	}
 
-	return httpClient
+	return &http.Client{
+		Transport: transport,
+		Timeout:   config.RequestTimeout,
+	}
 }
 
 // sendRequest sends a request to the Azure management API using the given
-// HTTP client and parameters. It returns the response from the call or an
+// HTTP client and parameters, retrying it according to the retry policy of
+// the client configuration. It returns the response from the call or an
 // error.
-func (client client) sendRequest(httpClient *http.Client, url, requestType, contentType string, data []byte, numberOfRetries int) (*http.Response, error) {
-
+func (client client) sendRequest(httpClient *http.Client, url, requestType, contentType string, data []byte) (*http.Response, error) {
+	policy := client.config.retryPolicy()
 	absURI := client.createAzureRequestURI(url)
 
-	for {
+	for retries := 0; ; {
 		request, reqErr := client.createAzureRequest(absURI, requestType, contentType, data)
 		if reqErr != nil {
 			return nil, reqErr
@@ -114,11 +131,12 @@ func (client client) sendRequest(httpClient *http.Client, url, requestType, cont
 
 		response, err := httpClient.Do(request)
 		if err != nil {
-			if numberOfRetries == 0 {
-				return nil, err
+			if retries < policy.MaxRetries && (isIdempotent(requestType) || isNotSentError(err)) {
+				time.Sleep(policy.backoff(retries))
+				retries++
+				continue
 			}
-
-			return client.sendRequest(httpClient, url, requestType, contentType, data, numberOfRetries-1)
+			return nil, err
 		}
 		if response.StatusCode == http.StatusTemporaryRedirect {
 			// ASM's way of moving traffic around, see https://msdn.microsoft.com/en-us/library/azure/ee460801.aspx
@@ -138,13 +156,17 @@ func (client client) sendRequest(httpClient *http.Client, url, requestType, cont
 				return nil, err
 			}
 			azureErr := getAzureError(body)
-			if azureErr != nil {
-				if numberOfRetries == 0 {
-					return nil, azureErr
+			typedErr, _ := azureErr.(AzureError)
+			if retries < policy.MaxRetries && isIdempotent(requestType) && policy.shouldRetryResponse(response.StatusCode, typedErr) {
+				delay, ok := retryAfter(response)
+				if !ok {
+					delay = policy.backoff(retries)
 				}
-
-				return client.sendRequest(httpClient, url, requestType, contentType, data, numberOfRetries-1)
+				time.Sleep(delay)
+				retries++
+				continue
 			}
+			return nil, azureErr
 		}
 
 		return response, nil
@@ -154,6 +176,9 @@ func (client client) sendRequest(httpClient *http.Client, url, requestType, cont
 // createAzureRequestURI constructs the request uri using the management API endpoint and
 // subscription ID associated with the client.
 func (client client) createAzureRequestURI(url string) string {
+	if url == SubscriptionURL {
+		return fmt.Sprintf("%s/%s", client.config.ManagementURL, client.publishSettings.SubscriptionID)
+	}
 	return fmt.Sprintf("%s/%s/%s", client.config.ManagementURL, client.publishSettings.SubscriptionID, url)
 }
 
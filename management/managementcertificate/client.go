@@ -0,0 +1,150 @@
+// Package managementcertificate provides a client for subscription
+// Management Certificates.
+package managementcertificate
+
+import (
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+const (
+	azureCertificateListURL = "certificates"
+	azureCertificateURL     = "certificates/%s"
+
+	errParamNotSpecified = "Parameter %s is not specified."
+)
+
+//NewClient is used to instantiate a new ManagementCertificateClient from an Azure client
+func NewClient(client management.Client) ManagementCertificateClient {
+	return ManagementCertificateClient{client: client}
+}
+
+// ListCertificates returns the management certificates of the subscription.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154105.aspx
+func (c ManagementCertificateClient) ListCertificates() (ListCertificatesResponse, error) {
+	var response ListCertificatesResponse
+
+	data, err := c.client.SendAzureGetRequest(azureCertificateListURL)
+	if err != nil {
+		return response, err
+	}
+
+	if err := xml.Unmarshal(data, &response); err != nil {
+		return response, err
+	}
+	for i := range response.Certificates {
+		if err := decodeCertificate(&response.Certificates[i]); err != nil {
+			return response, err
+		}
+	}
+	return response, nil
+}
+
+// GetCertificate returns the management certificate of the subscription
+// with the given thumbprint.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154131.aspx
+func (c ManagementCertificateClient) GetCertificate(thumbprint string) (Certificate, error) {
+	var certificate Certificate
+	if thumbprint == "" {
+		return certificate, fmt.Errorf(errParamNotSpecified, "thumbprint")
+	}
+
+	requestURL := fmt.Sprintf(azureCertificateURL, thumbprint)
+	data, err := c.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return certificate, err
+	}
+
+	if err := xml.Unmarshal(data, &certificate); err != nil {
+		return certificate, err
+	}
+	return certificate, decodeCertificate(&certificate)
+}
+
+// AddCertificate adds a management certificate to the subscription and
+// returns it. certData is the certificate in DER format, or PEM data such
+// as accepted by management.NewClient, in which case the first certificate
+// is added and private keys are ignored.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154123.aspx
+func (c ManagementCertificateClient) AddCertificate(certData []byte) (Certificate, error) {
+	var certificate Certificate
+	if len(certData) == 0 {
+		return certificate, fmt.Errorf(errParamNotSpecified, "certData")
+	}
+
+	der := certData
+	for rest := certData; ; {
+		var b *pem.Block
+		if b, rest = pem.Decode(rest); b == nil {
+			break
+		}
+		if b.Type == "CERTIFICATE" {
+			der = b.Bytes
+			break
+		}
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return certificate, fmt.Errorf("Cannot parse management certificate: %v", err)
+	}
+
+	sum := sha1.Sum(cert.Raw)
+	certificate = Certificate{
+		PublicKeyBase64: base64.StdEncoding.EncodeToString(cert.RawSubjectPublicKeyInfo),
+		Thumbprint:      strings.ToUpper(hex.EncodeToString(sum[:])),
+		DataBase64:      base64.StdEncoding.EncodeToString(cert.Raw),
+		PublicKey:       cert.RawSubjectPublicKeyInfo,
+		Data:            cert.Raw,
+	}
+	req, err := xml.Marshal(addCertificateParameters{
+		PublicKeyBase64: certificate.PublicKeyBase64,
+		Thumbprint:      certificate.Thumbprint,
+		DataBase64:      certificate.DataBase64,
+	})
+	if err != nil {
+		return certificate, err
+	}
+
+	_, err = c.client.SendAzurePostRequest(azureCertificateListURL, req) // not a long running operation
+	return certificate, err
+}
+
+// DeleteCertificate deletes the management certificate of the subscription
+// with the given thumbprint. Requests authenticated with the certificate
+// fail afterwards, so the client should be switched over to another one
+// first.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154127.aspx
+func (c ManagementCertificateClient) DeleteCertificate(thumbprint string) error {
+	if thumbprint == "" {
+		return fmt.Errorf(errParamNotSpecified, "thumbprint")
+	}
+
+	requestURL := fmt.Sprintf(azureCertificateURL, thumbprint)
+	_, err := c.client.SendAzureDeleteRequest(requestURL) // not a long running operation
+	return err
+}
+
+func decodeCertificate(c *Certificate) error {
+	publicKey, err := base64.StdEncoding.DecodeString(c.PublicKeyBase64)
+	if err != nil {
+		return err
+	}
+	data, err := base64.StdEncoding.DecodeString(c.DataBase64)
+	if err != nil {
+		return err
+	}
+	c.PublicKey, c.Data = publicKey, data
+	return nil
+}
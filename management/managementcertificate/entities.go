@@ -0,0 +1,49 @@
+package managementcertificate
+
+import (
+	"crypto/x509"
+	"encoding/xml"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+//ManagementCertificateClient is used to perform operations on Azure subscription management certificates
+type ManagementCertificateClient struct {
+	client management.Client
+}
+
+// Certificate is a management certificate of a subscription. PublicKey and
+// Data hold the decoded values of PublicKeyBase64 and DataBase64, the public
+// key and the certificate in DER format.
+type Certificate struct {
+	XMLName         xml.Name `xml:"http://schemas.microsoft.com/windowsazure SubscriptionCertificate"`
+	PublicKeyBase64 string   `xml:"SubscriptionCertificatePublicKey"`
+	Thumbprint      string   `xml:"SubscriptionCertificateThumbprint"`
+	DataBase64      string   `xml:"SubscriptionCertificateData"`
+	Created         string
+	PublicKey       []byte `xml:"-"`
+	Data            []byte `xml:"-"`
+}
+
+// CreatedTime parses the time the certificate was added to the subscription.
+func (c Certificate) CreatedTime() (time.Time, error) {
+	return time.Parse(time.RFC3339Nano, c.Created)
+}
+
+// X509Certificate parses the certificate data.
+func (c Certificate) X509Certificate() (*x509.Certificate, error) {
+	return x509.ParseCertificate(c.Data)
+}
+
+type ListCertificatesResponse struct {
+	XMLName      xml.Name      `xml:"http://schemas.microsoft.com/windowsazure SubscriptionCertificates"`
+	Certificates []Certificate `xml:"SubscriptionCertificate"`
+}
+
+type addCertificateParameters struct {
+	XMLName         xml.Name `xml:"http://schemas.microsoft.com/windowsazure SubscriptionCertificate"`
+	PublicKeyBase64 string   `xml:"SubscriptionCertificatePublicKey"`
+	Thumbprint      string   `xml:"SubscriptionCertificateThumbprint"`
+	DataBase64      string   `xml:"SubscriptionCertificateData"`
+}
@@ -0,0 +1,108 @@
+package managementcertificate
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+	"github.com/Azure/azure-sdk-for-go/management/testutils"
+)
+
+func testCertificate(t *testing.T) []byte {
+	der, _ := testutils.NewSelfSignedCertificate(t, time.Now().AddDate(1, 0, 0))
+	return der
+}
+
+func Test_ListCertificatesResponse_Unmarshal(t *testing.T) {
+	der := testCertificate(t)
+	response := []byte(fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<SubscriptionCertificates xmlns="http://schemas.microsoft.com/windowsazure">
+  <SubscriptionCertificate>
+    <SubscriptionCertificatePublicKey>cHVibGljLWtleQ==</SubscriptionCertificatePublicKey>
+    <SubscriptionCertificateThumbprint>thumbprint</SubscriptionCertificateThumbprint>
+    <SubscriptionCertificateData>%s</SubscriptionCertificateData>
+    <Created>2015-10-21T07:28:00.1234567Z</Created>
+  </SubscriptionCertificate>
+</SubscriptionCertificates>`, base64.StdEncoding.EncodeToString(der)))
+
+	var list ListCertificatesResponse
+	if err := xml.Unmarshal(response, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Certificates) != 1 {
+		t.Fatalf("Expected 1 certificate but got %d", len(list.Certificates))
+	}
+
+	c := list.Certificates[0]
+	if err := decodeCertificate(&c); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "public-key"; string(c.PublicKey) != expected {
+		t.Fatalf("Expected %q but got %q", expected, c.PublicKey)
+	}
+	if expected := "2015-10-21T07:28:00.1234567Z"; c.Created != expected {
+		t.Fatalf("Expected %q but got %q", expected, c.Created)
+	}
+	if created, err := c.CreatedTime(); err != nil {
+		t.Fatal(err)
+	} else if expected := time.Date(2015, 10, 21, 7, 28, 0, 123456700, time.UTC); !created.Equal(expected) {
+		t.Fatalf("Expected %v but got %v", expected, created)
+	}
+	if _, err := c.X509Certificate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// postRecorder is a management.Client which records the data of POST
+// requests.
+type postRecorder struct {
+	management.Client
+	data [][]byte
+}
+
+func (r *postRecorder) SendAzurePostRequest(url string, data []byte) (management.OperationID, error) {
+	r.data = append(r.data, data)
+	return "", nil
+}
+
+func Test_AddCertificate(t *testing.T) {
+	der := testCertificate(t)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &postRecorder{}
+	pemData := append(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("key")}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})...)
+	for _, data := range [][]byte{der, pemData} {
+		c, err := NewClient(r).AddCertificate(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(c.Thumbprint) != 40 || strings.ToUpper(c.Thumbprint) != c.Thumbprint {
+			t.Fatalf("Unexpected thumbprint %q", c.Thumbprint)
+		}
+		if string(c.PublicKey) != string(cert.RawSubjectPublicKeyInfo) {
+			t.Fatal("Unexpected public key")
+		}
+	}
+
+	expected := fmt.Sprintf(`<SubscriptionCertificate xmlns="http://schemas.microsoft.com/windowsazure">`+
+		`<SubscriptionCertificatePublicKey>%s</SubscriptionCertificatePublicKey>`+
+		`<SubscriptionCertificateThumbprint>`,
+		base64.StdEncoding.EncodeToString(cert.RawSubjectPublicKeyInfo))
+	if len(r.data) != 2 || !strings.HasPrefix(string(r.data[1]), expected) || string(r.data[0]) != string(r.data[1]) {
+		t.Fatalf("Unexpected requests %q", r.data)
+	}
+
+	if _, err := NewClient(r).AddCertificate([]byte("not a certificate")); err == nil {
+		t.Fatal("Expected an error for invalid certificate data")
+	}
+}
@@ -1,12 +1,18 @@
 package management
 
 import (
+	"context"
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"sync"
 	"time"
 )
 
+// DefaultOperationMinPollInterval is the interval after the first poll of
+// WaitForOperationContext if WaitOptions do not specify one.
+const DefaultOperationMinPollInterval = time.Second * 2
+
 var (
 	// ErrOperationCancelled from WaitForOperation when the polling loop is
 	// cancelled through signaling the channel.
@@ -56,6 +62,29 @@ func (c client) GetOperationStatus(operationID OperationID) (GetOperationStatusR
 	return operation, err
 }
 
+// WaitOptions configure how WaitForOperationContext polls for the status of
+// an operation.
+type WaitOptions struct {
+	// InitialDelay is the time to wait before the first poll.
+	InitialDelay time.Duration
+
+	// MinPollInterval is the interval after the first poll. It is doubled
+	// after every poll, up to MaxPollInterval.
+	// DefaultOperationMinPollInterval is used if it is not positive.
+	MinPollInterval time.Duration
+
+	// MaxPollInterval is the longest interval between polls. If it is not
+	// positive, the OperationPollInterval of the client configuration is
+	// used for clients created by this package, and
+	// DefaultOperationPollInterval for other implementations of Client.
+	MaxPollInterval time.Duration
+
+	// Progress, if set, is called with the status of the operation after
+	// every poll. When waiting for multiple operations with
+	// WaitForOperations, it is called concurrently.
+	Progress func(OperationID, GetOperationStatusResponse)
+}
+
 func (c client) WaitForOperation(operationID OperationID, cancel chan struct{}) error {
 	for {
 		done, err := c.checkOperationStatus(operationID)
@@ -70,12 +99,105 @@ func (c client) WaitForOperation(operationID OperationID, cancel chan struct{})
 	}
 }
 
+// WaitForOperationContext polls the Azure API for given operation ID
+// until the operation is completed with either success or failure, or
+// the context is done. Unlike WaitForOperation, the interval between
+// polls starts small and grows exponentially, as specified by the options.
+//
+// If the operation was not successful, an error is returned. If the
+// context is done first, its error is returned.
+func WaitForOperationContext(ctx context.Context, c Client, operationID OperationID, opts WaitOptions) error {
+	maxInterval := opts.MaxPollInterval
+	if maxInterval <= 0 {
+		maxInterval = DefaultOperationPollInterval
+		if cl, ok := c.(client); ok {
+			maxInterval = cl.config.OperationPollInterval
+		}
+	}
+	interval := opts.MinPollInterval
+	if interval <= 0 {
+		interval = DefaultOperationMinPollInterval
+	}
+	if interval > maxInterval {
+		interval = maxInterval
+	}
+
+	delay := opts.InitialDelay
+	for {
+		if err := sleepContext(ctx, delay); err != nil {
+			return err
+		}
+
+		op, err := c.GetOperationStatus(operationID)
+		if err != nil {
+			return fmt.Errorf("Failed to get operation status '%s': %v", operationID, err)
+		}
+		if opts.Progress != nil {
+			opts.Progress(operationID, op)
+		}
+		if done, err := operationResult(operationID, op); err != nil || done {
+			return err
+		}
+
+		delay = interval
+		if interval *= 2; interval > maxInterval {
+			interval = maxInterval
+		}
+	}
+}
+
+// WaitForOperations waits for the given operations concurrently with
+// WaitForOperationContext and returns the result of each, which is nil if
+// the operation has succeeded. Operations which have not completed when the
+// context is done report the error of the context.
+func WaitForOperations(ctx context.Context, c Client, operationIDs []OperationID, opts WaitOptions) map[OperationID]error {
+	var (
+		mu      sync.Mutex
+		wg      sync.WaitGroup
+		results = make(map[OperationID]error, len(operationIDs))
+	)
+	for _, id := range operationIDs {
+		wg.Add(1)
+		go func(id OperationID) {
+			defer wg.Done()
+			err := WaitForOperationContext(ctx, c, id, opts)
+			mu.Lock()
+			results[id] = err
+			mu.Unlock()
+		}(id)
+	}
+	wg.Wait()
+	return results
+}
+
+// sleepContext waits for the given duration or until the context is done,
+// whichever happens first, and returns the error of the context in the
+// latter case.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 func (c client) checkOperationStatus(id OperationID) (done bool, err error) {
 	op, err := c.GetOperationStatus(id)
 	if err != nil {
 		return false, fmt.Errorf("Failed to get operation status '%s': %v", id, err)
 	}
+	return operationResult(id, op)
+}
 
+// operationResult reports whether the operation with the given status has
+// completed, and its error if it has failed.
+func operationResult(id OperationID, op GetOperationStatusResponse) (done bool, err error) {
 	switch op.Status {
 	case OperationStatusSucceeded:
 		return true, nil
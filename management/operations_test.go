@@ -0,0 +1,154 @@
+package management_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+// operationServer responds to Get Operation Status requests. Each operation
+// is in progress for its number of polls and then has its final status.
+type operationServer struct {
+	mu       sync.Mutex
+	polls    map[string]int
+	final    map[string]management.OperationStatus
+	inFlight map[string]int
+}
+
+func (s *operationServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:]
+	status := management.OperationStatusInProgress
+	if s.polls[id]++; s.polls[id] > s.inFlight[id] {
+		status = s.final[id]
+	}
+	errorXML := ""
+	if status == management.OperationStatusFailed {
+		errorXML = "<Error><Code>Conflict</Code><Message>failed</Message></Error>"
+	}
+	fmt.Fprintf(w, `<Operation xmlns="http://schemas.microsoft.com/windowsazure"><ID>%s</ID><Status>%s</Status>%s</Operation>`, id, status, errorXML)
+}
+
+func newOperationTestClient(t *testing.T, s *operationServer) (management.Client, func()) {
+	ts := httptest.NewServer(s)
+	config := management.DefaultConfig()
+	config.ManagementURL = ts.URL
+	config.OperationPollInterval = 8 * time.Millisecond
+	c, err := management.NewClientFromConfig("subscription", testManagementCert(t), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, ts.Close
+}
+
+func TestWaitForOperationContext(t *testing.T) {
+	s := &operationServer{
+		polls:    map[string]int{},
+		final:    map[string]management.OperationStatus{"op": management.OperationStatusSucceeded},
+		inFlight: map[string]int{"op": 3},
+	}
+	c, done := newOperationTestClient(t, s)
+	defer done()
+
+	var statuses []management.OperationStatus
+	err := management.WaitForOperationContext(context.Background(), c, "op", management.WaitOptions{
+		MinPollInterval: time.Millisecond,
+		Progress: func(id management.OperationID, op management.GetOperationStatusResponse) {
+			statuses = append(statuses, op.Status)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []management.OperationStatus{"InProgress", "InProgress", "InProgress", "Succeeded"}
+	if fmt.Sprint(statuses) != fmt.Sprint(expected) {
+		t.Fatalf("Expected progress %v but got %v", expected, statuses)
+	}
+}
+
+func TestWaitForOperationContext_Cancelled(t *testing.T) {
+	s := &operationServer{polls: map[string]int{}, inFlight: map[string]int{"op": 1 << 20}}
+	c, done := newOperationTestClient(t, s)
+	defer done()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	err := management.WaitForOperationContext(ctx, c, "op", management.WaitOptions{MinPollInterval: time.Millisecond})
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Expected %v but got %v", context.DeadlineExceeded, err)
+	}
+
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	polls := s.polls["op"]
+	err = management.WaitForOperationContext(ctx, c, "op", management.WaitOptions{InitialDelay: time.Hour})
+	if err != context.Canceled {
+		t.Fatalf("Expected %v but got %v", context.Canceled, err)
+	}
+	if s.polls["op"] != polls {
+		t.Fatal("Expected no poll after cancellation")
+	}
+}
+
+func TestWaitForOperations(t *testing.T) {
+	s := &operationServer{
+		polls: map[string]int{},
+		final: map[string]management.OperationStatus{
+			"a": management.OperationStatusSucceeded,
+			"b": management.OperationStatusFailed,
+			"c": management.OperationStatusSucceeded,
+		},
+		inFlight: map[string]int{"a": 2, "b": 1, "c": 0},
+	}
+	c, done := newOperationTestClient(t, s)
+	defer done()
+
+	results := management.WaitForOperations(context.Background(), c, []management.OperationID{"a", "b", "c"},
+		management.WaitOptions{MinPollInterval: time.Millisecond})
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results but got %d", len(results))
+	}
+	if results["a"] != nil || results["c"] != nil {
+		t.Fatalf("Unexpected results: %v", results)
+	}
+	if err, ok := results["b"].(*management.AzureError); !ok || err.Code != "Conflict" {
+		t.Fatalf("Expected operation b to fail with Conflict but got %v", results["b"])
+	}
+}
+
+// wrappedClient is an implementation of management.Client outside of the
+// package.
+type wrappedClient struct {
+	management.Client
+}
+
+func TestWaitForOperationContext_OtherClient(t *testing.T) {
+	s := &operationServer{
+		polls:    map[string]int{},
+		final:    map[string]management.OperationStatus{"op": management.OperationStatusSucceeded},
+		inFlight: map[string]int{"op": 2},
+	}
+	c, done := newOperationTestClient(t, s)
+	defer done()
+
+	err := management.WaitForOperationContext(context.Background(), wrappedClient{c}, "op", management.WaitOptions{
+		MinPollInterval: time.Millisecond,
+		MaxPollInterval: 2 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.polls["op"] != 3 {
+		t.Fatalf("Expected 3 polls but got %d", s.polls["op"])
+	}
+}
@@ -2,12 +2,9 @@ package management
 
 import (
 	"encoding/base64"
-	"encoding/pem"
 	"encoding/xml"
 	"fmt"
 	"io/ioutil"
-
-	"github.com/Azure/azure-sdk-for-go/Godeps/_workspace/src/golang.org/x/crypto/pkcs12"
 )
 
 // ClientFromPublishSettingsData unmarshalls the contents of a publish settings file
@@ -60,11 +57,9 @@ func ClientFromPublishSettingsDataWithConfig(data []byte, subscriptionID string,
 					return client, err
 				}
 
-				pems, err := pkcs12.ToPEM(pfxData, "")
-
-				cert := []byte{}
-				for _, b := range pems {
-					cert = append(cert, pem.EncodeToMemory(b)...)
+				cert, err := PFXToPEM(pfxData, "")
+				if err != nil {
+					return client, err
 				}
 
 				config.ManagementURL = sub.ServiceManagementURL
@@ -0,0 +1,131 @@
+// Package reservedip provides a client for Reserved IP addresses.
+package reservedip
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+const (
+	azureReservedIPListURL    = "services/networking/reservedips"
+	azureReservedIPURL        = "services/networking/reservedips/%s"
+	associateReservedIPURL    = "services/networking/reservedips/%s/operations/associate"
+	disassociateReservedIPURL = "services/networking/reservedips/%s/operations/disassociate"
+
+	errParamNotSpecified = "Parameter %s is not specified."
+)
+
+//NewClient is used to instantiate a new ReservedIPClient from an Azure client
+func NewClient(client management.Client) ReservedIPClient {
+	return ReservedIPClient{client: client}
+}
+
+// CreateReservedIP reserves a public IP address in a location, which can be
+// assigned to deployments by name, for instance with the ReservedIPName of
+// virtualmachine.CreateDeploymentOptions.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn722413.aspx
+func (c ReservedIPClient) CreateReservedIP(params CreateReservedIPParameters) (management.OperationID, error) {
+	if params.Name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Name")
+	}
+	if params.Location == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Location")
+	}
+
+	req, err := xml.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	return c.client.SendAzurePostRequest(azureReservedIPListURL, req)
+}
+
+// GetReservedIP returns the reserved IP address with the given name.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn722415.aspx
+func (c ReservedIPClient) GetReservedIP(name string) (ReservedIP, error) {
+	var reservedIP ReservedIP
+	if name == "" {
+		return reservedIP, fmt.Errorf(errParamNotSpecified, "name")
+	}
+
+	requestURL := fmt.Sprintf(azureReservedIPURL, name)
+	response, err := c.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return reservedIP, err
+	}
+
+	err = xml.Unmarshal(response, &reservedIP)
+	return reservedIP, err
+}
+
+// ListReservedIPs returns the reserved IP addresses of the subscription.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn722418.aspx
+func (c ReservedIPClient) ListReservedIPs() (ListReservedIPsResponse, error) {
+	var response ListReservedIPsResponse
+
+	data, err := c.client.SendAzureGetRequest(azureReservedIPListURL)
+	if err != nil {
+		return response, err
+	}
+
+	err = xml.Unmarshal(data, &response)
+	return response, err
+}
+
+// DeleteReservedIP releases the reserved IP address with the given name,
+// which must not be in use.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn722416.aspx
+func (c ReservedIPClient) DeleteReservedIP(name string) (management.OperationID, error) {
+	if name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "name")
+	}
+
+	requestURL := fmt.Sprintf(azureReservedIPURL, name)
+	return c.client.SendAzureDeleteRequest(requestURL)
+}
+
+// AssociateReservedIP assigns the reserved IP address with the given name
+// to a running deployment of a cloud service.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn800281.aspx
+func (c ReservedIPClient) AssociateReservedIP(name, serviceName, deploymentName string) (management.OperationID, error) {
+	return c.sendAssociation(associateReservedIPURL, name, serviceName, deploymentName)
+}
+
+// DisassociateReservedIP removes the reserved IP address with the given
+// name from a deployment of a cloud service, which is assigned a new
+// public IP address.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn800282.aspx
+func (c ReservedIPClient) DisassociateReservedIP(name, serviceName, deploymentName string) (management.OperationID, error) {
+	return c.sendAssociation(disassociateReservedIPURL, name, serviceName, deploymentName)
+}
+
+func (c ReservedIPClient) sendAssociation(urlFormat, name, serviceName, deploymentName string) (management.OperationID, error) {
+	if name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "name")
+	}
+	if serviceName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "serviceName")
+	}
+	if deploymentName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "deploymentName")
+	}
+
+	req, err := xml.Marshal(reservedIPAssociation{
+		ServiceName:    serviceName,
+		DeploymentName: deploymentName,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(urlFormat, name)
+	return c.client.SendAzurePostRequest(requestURL, req)
+}
@@ -0,0 +1,57 @@
+package reservedip
+
+import (
+	"encoding/xml"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+//ReservedIPClient is used to perform operations on Azure Reserved IP addresses
+type ReservedIPClient struct {
+	client management.Client
+}
+
+// CreateReservedIPParameters describes a reserved IP address to create.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn722413.aspx
+type CreateReservedIPParameters struct {
+	XMLName  xml.Name `xml:"http://schemas.microsoft.com/windowsazure ReservedIP"`
+	Name     string
+	Label    string `xml:",omitempty"`
+	Location string
+}
+
+// ReservedIP is a reserved IP address and the deployment it is associated
+// with, if it is in use.
+type ReservedIP struct {
+	Name           string
+	Address        string
+	ID             string `xml:"Id"`
+	Label          string
+	State          ReservedIPState
+	InUse          bool
+	ServiceName    string
+	DeploymentName string
+	Location       string
+}
+
+type ReservedIPState string
+
+const (
+	ReservedIPStateCreated     = ReservedIPState("Created")
+	ReservedIPStateCreating    = ReservedIPState("Creating")
+	ReservedIPStateUpdating    = ReservedIPState("Updating")
+	ReservedIPStateDeleting    = ReservedIPState("Deleting")
+	ReservedIPStateUnavailable = ReservedIPState("Unavailable")
+)
+
+type ListReservedIPsResponse struct {
+	XMLName     xml.Name     `xml:"http://schemas.microsoft.com/windowsazure ReservedIPs"`
+	ReservedIPs []ReservedIP `xml:"ReservedIP"`
+}
+
+type reservedIPAssociation struct {
+	XMLName        xml.Name `xml:"http://schemas.microsoft.com/windowsazure ReservedIPAssociation"`
+	ServiceName    string
+	DeploymentName string
+}
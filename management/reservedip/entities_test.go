@@ -0,0 +1,55 @@
+package reservedip
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func Test_ListReservedIPsResponse_Unmarshal(t *testing.T) {
+	// from https://msdn.microsoft.com/en-us/library/azure/dn722418.aspx
+	response := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<ReservedIPs xmlns="http://schemas.microsoft.com/windowsazure">
+  <ReservedIP>
+    <Name>name-of-reserved-ip</Name>
+    <Address>191.239.1.2</Address>
+    <Id>reserved-ip-id</Id>
+    <Label>label</Label>
+    <State>Created</State>
+    <InUse>true</InUse>
+    <ServiceName>service</ServiceName>
+    <DeploymentName>deployment</DeploymentName>
+    <Location>West US</Location>
+  </ReservedIP>
+</ReservedIPs>`)
+
+	var list ListReservedIPsResponse
+	if err := xml.Unmarshal(response, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.ReservedIPs) != 1 {
+		t.Fatalf("Expected 1 reserved IP but got %d", len(list.ReservedIPs))
+	}
+
+	ip := list.ReservedIPs[0]
+	if expected := "191.239.1.2"; ip.Address != expected {
+		t.Fatalf("Expected %q but got %q", expected, ip.Address)
+	}
+	if expected := ReservedIPStateCreated; ip.State != expected {
+		t.Fatalf("Expected %q but got %q", expected, ip.State)
+	}
+	if !ip.InUse || ip.ID != "reserved-ip-id" || ip.DeploymentName != "deployment" {
+		t.Fatalf("Unexpected reserved IP: %+v", ip)
+	}
+}
+
+func Test_reservedIPAssociation_Marshal(t *testing.T) {
+	data, err := xml.Marshal(reservedIPAssociation{ServiceName: "service", DeploymentName: "deployment"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<ReservedIPAssociation xmlns="http://schemas.microsoft.com/windowsazure"><ServiceName>service</ServiceName><DeploymentName>deployment</DeploymentName></ReservedIPAssociation>`
+	if string(data) != expected {
+		t.Fatalf("Expected %q but got %q", expected, string(data))
+	}
+}
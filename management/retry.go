@@ -0,0 +1,138 @@
+package management
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/core/http"
+)
+
+// Default retry policy settings
+const (
+	DefaultMaxRetries = 4
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = time.Second * 30
+)
+
+// RetryPolicy configures how requests to the management API are retried
+// when they fail.
+//
+// Requests are retried after a delay which starts at MinBackoff and doubles
+// with every retry up to MaxBackoff, unless the response specifies one in
+// its Retry-After header. Requests with idempotent methods are retried on
+// transport errors and on responses which ShouldRetry accepts. POST
+// requests are only retried if they could not be sent, so that they are
+// never executed twice.
+type RetryPolicy struct {
+	// MaxRetries is the number of times a request is retried. Zero
+	// disables retries.
+	MaxRetries int
+
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// ShouldRetry classifies error responses by HTTP status code and Azure
+	// error code. IsRetryableResponse is used if it is nil.
+	ShouldRetry func(statusCode int, err AzureError) bool
+}
+
+// DefaultRetryPolicy returns the retry policy used if the client
+// configuration does not specify one.
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: DefaultMaxRetries,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+	}
+}
+
+// retryableErrorCodes are the Azure error codes of transient failures which
+// may be reported with a status code that is not retryable by itself.
+var retryableErrorCodes = map[string]bool{
+	"InternalError":     true,
+	"ServerBusy":        true,
+	"OperationTimedOut": true,
+	"TooManyRequests":   true,
+}
+
+// IsRetryableResponse reports whether an error response with the given
+// HTTP status code and Azure error is transient: a request timeout,
+// throttling, a server error or one of the Azure error codes of transient
+// failures. Other client errors, such as 400, 404 and 409, are not.
+func IsRetryableResponse(statusCode int, err AzureError) bool {
+	switch statusCode {
+	case http.StatusRequestTimeout,
+		429, // Too Many Requests
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return retryableErrorCodes[err.Code]
+}
+
+// retryPolicy returns the retry policy of the client configuration, or the
+// default one.
+func (c ClientConfig) retryPolicy() RetryPolicy {
+	if c.RetryPolicy == nil {
+		return DefaultRetryPolicy()
+	}
+	return *c.RetryPolicy
+}
+
+// backoff returns the delay before the given retry, counted from zero.
+func (p RetryPolicy) backoff(retry int) time.Duration {
+	d := p.MinBackoff
+	for i := 0; i < retry && d < p.MaxBackoff; i++ {
+		d *= 2
+	}
+	if d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	return d
+}
+
+func (p RetryPolicy) shouldRetryResponse(statusCode int, err AzureError) bool {
+	if p.ShouldRetry != nil {
+		return p.ShouldRetry(statusCode, err)
+	}
+	return IsRetryableResponse(statusCode, err)
+}
+
+// isIdempotent reports whether requests with the given method can be
+// repeated without changing their effect.
+func isIdempotent(method string) bool {
+	return method != "POST"
+}
+
+// isNotSentError reports whether a transport error happened before the
+// request was sent, that is, while connecting.
+func isNotSentError(err error) bool {
+	if ue, ok := err.(*url.Error); ok {
+		err = ue.Err
+	}
+	oe, ok := err.(*net.OpError)
+	return ok && oe.Op == "dial"
+}
+
+// retryAfter returns the delay requested by the Retry-After header of the
+// response, in seconds or as an HTTP date.
+func retryAfter(response *http.Response) (time.Duration, bool) {
+	v := response.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(v); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := t.Sub(time.Now()); d > 0 {
+			return d, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
@@ -0,0 +1,151 @@
+package management_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+// failingServer responds to the first failures requests with the given
+// status code and Azure error code, and then with 200.
+type failingServer struct {
+	mu         sync.Mutex
+	requests   int
+	failures   int
+	statusCode int
+	code       string
+	retryAfter string
+}
+
+func (s *failingServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+	if s.requests > s.failures {
+		w.Header().Set("x-ms-request-id", "id")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if s.retryAfter != "" {
+		w.Header().Set("Retry-After", s.retryAfter)
+	}
+	w.WriteHeader(s.statusCode)
+	w.Write([]byte(`<Error xmlns="http://schemas.microsoft.com/windowsazure"><Code>` + s.code + `</Code><Message>m</Message></Error>`))
+}
+
+func newRetryTestClient(t *testing.T, s *failingServer, policy *management.RetryPolicy) (management.Client, func()) {
+	ts := httptest.NewServer(s)
+	config := management.DefaultConfig()
+	config.ManagementURL = ts.URL
+	config.RetryPolicy = policy
+	c, err := management.NewClientFromConfig("subscription", testManagementCert(t), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c, ts.Close
+}
+
+var testRetryPolicy = &management.RetryPolicy{MaxRetries: 3, MinBackoff: time.Millisecond, MaxBackoff: 4 * time.Millisecond}
+
+func TestRetryPolicy(t *testing.T) {
+	tests := []struct {
+		method     string
+		statusCode int
+		code       string
+		failures   int
+		requests   int
+		ok         bool
+	}{
+		{"GET", http.StatusServiceUnavailable, "ServerBusy", 2, 3, true},
+		{"GET", http.StatusInternalServerError, "InternalError", 5, 4, false},
+		{"GET", http.StatusNotFound, "ResourceNotFound", 1, 1, false},
+		{"DELETE", http.StatusConflict, "ConflictError", 1, 1, false},
+		{"DELETE", http.StatusConflict, "TooManyRequests", 1, 2, true},
+		{"PUT", http.StatusBadRequest, "BadRequest", 1, 1, false},
+		{"POST", http.StatusServiceUnavailable, "ServerBusy", 1, 1, false},
+	}
+
+	for i, test := range tests {
+		s := &failingServer{failures: test.failures, statusCode: test.statusCode, code: test.code}
+		c, done := newRetryTestClient(t, s, testRetryPolicy)
+		var err error
+		switch test.method {
+		case "GET":
+			_, err = c.SendAzureGetRequest("url")
+		case "PUT":
+			_, err = c.SendAzurePutRequest("url", "", nil)
+		case "POST":
+			_, err = c.SendAzurePostRequest("url", nil)
+		case "DELETE":
+			_, err = c.SendAzureDeleteRequest("url")
+		}
+		done()
+
+		if (err == nil) != test.ok {
+			t.Fatalf("Test %d: expected success %t but got error %v", i+1, test.ok, err)
+		}
+		if err != nil {
+			if azureErr, ok := err.(management.AzureError); !ok || azureErr.Code != test.code {
+				t.Fatalf("Test %d: expected AzureError %s but got %v", i+1, test.code, err)
+			}
+		}
+		if s.requests != test.requests {
+			t.Fatalf("Test %d: expected %d requests but got %d", i+1, test.requests, s.requests)
+		}
+	}
+}
+
+func TestRetryPolicy_RetryAfter(t *testing.T) {
+	// the delay of the response replaces the backoff, which would time out
+	// the test
+	s := &failingServer{failures: 1, statusCode: 429, code: "TooManyRequests", retryAfter: "0"}
+	c, done := newRetryTestClient(t, s, &management.RetryPolicy{MaxRetries: 1, MinBackoff: time.Hour, MaxBackoff: time.Hour})
+	defer done()
+
+	if _, err := c.SendAzureGetRequest("url"); err != nil {
+		t.Fatal(err)
+	}
+	if s.requests != 2 {
+		t.Fatalf("Expected 2 requests but got %d", s.requests)
+	}
+}
+
+func TestRetryPolicy_Disabled(t *testing.T) {
+	s := &failingServer{failures: 1, statusCode: http.StatusServiceUnavailable, code: "ServerBusy"}
+	c, done := newRetryTestClient(t, s, &management.RetryPolicy{})
+	defer done()
+
+	if _, err := c.SendAzureGetRequest("url"); err == nil {
+		t.Fatal("Expected an error")
+	}
+	if s.requests != 1 {
+		t.Fatalf("Expected 1 request but got %d", s.requests)
+	}
+}
+
+func TestIsRetryableResponse(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		code       string
+		expected   bool
+	}{
+		{http.StatusBadRequest, "BadRequest", false},
+		{http.StatusNotFound, "ResourceNotFound", false},
+		{http.StatusConflict, "ConflictError", false},
+		{http.StatusConflict, "TooManyRequests", true},
+		{http.StatusRequestTimeout, "", true},
+		{429, "", true},
+		{http.StatusInternalServerError, "", true},
+		{http.StatusServiceUnavailable, "ServerBusy", true},
+	}
+
+	for i, test := range tests {
+		if res := management.IsRetryableResponse(test.statusCode, management.AzureError{Code: test.code}); res != test.expected {
+			t.Fatalf("Test %d: expected %t but got %t", i+1, test.expected, res)
+		}
+	}
+}
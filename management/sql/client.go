@@ -3,6 +3,7 @@ package sql
 import (
 	"encoding/xml"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Azure/azure-sdk-for-go/management"
@@ -26,9 +27,29 @@ const (
 	azureUpdateDatabaseURL = "services/sqlservers/servers/%s/databases/%s"
 	azureDeleteDatabaseURL = "services/sqlservers/servers/%s/databases/%s"
 
+	azureImportDatabaseURL     = "services/sqlservers/servers/%s/DacOperations/Import"
+	azureExportDatabaseURL     = "services/sqlservers/servers/%s/DacOperations/Export"
+	azureImportExportStatusURL = "services/sqlservers/servers/%s/DacOperations/Status"
+	azureCreateDatabaseCopyURL = "services/sqlservers/servers/%s/databases/%s/databasecopies"
+	azureListDatabaseCopiesURL = "services/sqlservers/servers/%s/databases/%s/databasecopies"
+	azureGetDatabaseCopyURL    = "services/sqlservers/servers/%s/databases/%s/databasecopies/%s"
+	azureDeleteDatabaseCopyURL = "services/sqlservers/servers/%s/databases/%s/databasecopies/%s"
+
+	azureResetServerPasswordURL      = "services/sqlservers/servers/%s?op=ResetPassword"
+	azureListServiceObjectivesURL    = "services/sqlservers/servers/%s/serviceobjectives"
+	azureGetServiceObjectiveURL      = "services/sqlservers/servers/%s/serviceobjectives/%s"
+	azureRestoreDatabaseURL          = "services/sqlservers/servers/%s/restoredatabaseoperations"
+	azureListRestorableDroppedDBsURL = "services/sqlservers/servers/%s/restorabledroppeddatabases?contentview=generic"
+
 	errParamNotSpecified = "Parameter %s was not specified."
 
 	DatabaseStateCreating = "Creating"
+
+	// States of the assignment of a service objective to a database, as
+	// reported by ServiceObjectiveAssignmentState.
+	ServiceObjectiveAssignmentStatePending  = 0
+	ServiceObjectiveAssignmentStateComplete = 1
+	ServiceObjectiveAssignmentStateFailed   = 2
 )
 
 // SQLDatabaseClient defines various database CRUD operations.
@@ -91,6 +112,28 @@ func (c SQLDatabaseClient) DeleteServer(name string) error {
 	return err
 }
 
+// ResetServerPassword sets the password of the administrator login of an
+// Azure SQL Database server.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn505696.aspx
+func (c SQLDatabaseClient) ResetServerPassword(server, password string) error {
+	if server == "" {
+		return fmt.Errorf(errParamNotSpecified, "server")
+	}
+	if password == "" {
+		return fmt.Errorf(errParamNotSpecified, "password")
+	}
+
+	req, err := xml.Marshal(administratorLoginPassword{Password: password})
+	if err != nil {
+		return err
+	}
+
+	url := fmt.Sprintf(azureResetServerPasswordURL, server)
+	_, err = c.mgmtClient.SendAzurePostRequest(url, req)
+	return err
+}
+
 // CreateFirewallRule creates an Azure SQL Database server
 // firewall rule.
 //
@@ -233,6 +276,40 @@ func (c SQLDatabaseClient) WaitForDatabaseCreation(
 	}
 }
 
+// WaitForDatabaseUpdate is a helper method which waits for a change of the
+// edition or service objective of the database on the given server, made
+// with UpdateDatabase, to finish, that is until the database has been
+// assigned the service objective with the given ID. An error is returned if
+// the service objective could not be assigned.
+func (c SQLDatabaseClient) WaitForDatabaseUpdate(
+	server, database, serviceObjectiveID string,
+	cancel chan struct{}) error {
+	if serviceObjectiveID == "" {
+		return fmt.Errorf(errParamNotSpecified, "serviceObjectiveID")
+	}
+	for {
+		stat, err := c.GetDatabase(server, database)
+		if err != nil {
+			return err
+		}
+		switch stat.ServiceObjectiveAssignmentState {
+		case ServiceObjectiveAssignmentStateFailed:
+			return fmt.Errorf("Updating database %s failed: %s", database, stat.ServiceObjectiveAssignmentErrorDescription)
+		case ServiceObjectiveAssignmentStateComplete:
+			// until the update starts, this is the state of the previous one
+			if strings.EqualFold(stat.AssignedServiceObjectiveID, serviceObjectiveID) {
+				return nil
+			}
+		}
+
+		select {
+		case <-time.After(management.DefaultOperationPollInterval):
+		case <-cancel:
+			return management.ErrOperationCancelled
+		}
+	}
+}
+
 // GetDatabase gets the details for an Azure SQL Database.
 //
 // https://msdn.microsoft.com/en-us/library/azure/dn505708.aspx
@@ -314,3 +391,346 @@ func (c SQLDatabaseClient) DeleteDatabase(server, database string) error {
 
 	return err
 }
+
+// ImportDatabase starts importing a .bacpac file from a storage blob into a
+// new database and returns the ID of the request, which can be passed to
+// GetImportExportStatus.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn781282.aspx
+func (c SQLDatabaseClient) ImportDatabase(server string, params ImportParams) (string, error) {
+	if server == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "server")
+	}
+	if err := validateDacParams(params.BlobCredentials, params.ConnectionInfo); err != nil {
+		return "", err
+	}
+	params.BlobCredentials.Type = blobStorageAccessKeyCredentialsType
+
+	req, err := xml.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf(azureImportDatabaseURL, server)
+	return c.sendDacRequest(url, req)
+}
+
+// ExportDatabase starts exporting a database to a .bacpac file in a storage
+// blob and returns the ID of the request, which can be passed to
+// GetImportExportStatus.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn781282.aspx
+func (c SQLDatabaseClient) ExportDatabase(server string, params ExportParams) (string, error) {
+	if server == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "server")
+	}
+	if err := validateDacParams(params.BlobCredentials, params.ConnectionInfo); err != nil {
+		return "", err
+	}
+	params.BlobCredentials.Type = blobStorageAccessKeyCredentialsType
+
+	req, err := xml.Marshal(params)
+	if err != nil {
+		return "", err
+	}
+
+	url := fmt.Sprintf(azureExportDatabaseURL, server)
+	return c.sendDacRequest(url, req)
+}
+
+func (c SQLDatabaseClient) sendDacRequest(url string, req []byte) (string, error) {
+	resp, err := c.mgmtClient.SendAzurePostRequestWithReturnedResponse(url, req)
+	if err != nil {
+		return "", err
+	}
+
+	var requestID string
+	err = xml.Unmarshal(resp, &requestID)
+	return requestID, err
+}
+
+func validateDacParams(blob BlobCredentials, conn ConnectionInfo) error {
+	if blob.URI == "" {
+		return fmt.Errorf(errParamNotSpecified, "BlobCredentials.URI")
+	}
+	if blob.StorageAccessKey == "" {
+		return fmt.Errorf(errParamNotSpecified, "BlobCredentials.StorageAccessKey")
+	}
+	if conn.ServerName == "" {
+		return fmt.Errorf(errParamNotSpecified, "ConnectionInfo.ServerName")
+	}
+	if conn.DatabaseName == "" {
+		return fmt.Errorf(errParamNotSpecified, "ConnectionInfo.DatabaseName")
+	}
+	return nil
+}
+
+// GetImportExportStatus gets the status of an import or export request. The
+// server login is required because the status is read from the server.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn781282.aspx
+func (c SQLDatabaseClient) GetImportExportStatus(server string, params ImportExportStatusParams) (ImportExportStatus, error) {
+	var status ImportExportStatus
+	if server == "" {
+		return status, fmt.Errorf(errParamNotSpecified, "server")
+	}
+	if params.RequestID == "" {
+		return status, fmt.Errorf(errParamNotSpecified, "RequestID")
+	}
+
+	req, err := xml.Marshal(params)
+	if err != nil {
+		return status, err
+	}
+
+	url := fmt.Sprintf(azureImportExportStatusURL, server)
+	resp, err := c.mgmtClient.SendAzurePostRequestWithReturnedResponse(url, req)
+	if err != nil {
+		return status, err
+	}
+
+	var statuses importExportStatusResponse
+	if err := xml.Unmarshal(resp, &statuses); err != nil {
+		return status, err
+	}
+	for _, s := range statuses.Statuses {
+		if s.RequestID == params.RequestID {
+			return s, nil
+		}
+	}
+	return status, fmt.Errorf("Import/export request %s not found", params.RequestID)
+}
+
+// WaitForImportExport is a helper method which waits for an import or
+// export request to complete. An error is returned if it failed.
+func (c SQLDatabaseClient) WaitForImportExport(
+	server string,
+	params ImportExportStatusParams,
+	cancel chan struct{}) (ImportExportStatus, error) {
+	for {
+		stat, err := c.GetImportExportStatus(server, params)
+		if err != nil {
+			return stat, err
+		}
+		switch stat.Status {
+		case ImportExportStatusCompleted:
+			return stat, nil
+		case ImportExportStatusFailed:
+			return stat, fmt.Errorf("Import/export request %s failed: %s", stat.RequestID, stat.ErrorMessage)
+		}
+
+		select {
+		case <-time.After(management.DefaultOperationPollInterval):
+		case <-cancel:
+			return stat, management.ErrOperationCancelled
+		}
+	}
+}
+
+// CopyDatabase starts copying a database to another server. A continuous
+// copy keeps replicating changes until it is deleted with
+// DeleteDatabaseCopy.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn509573.aspx
+func (c SQLDatabaseClient) CopyDatabase(server, database string, params DatabaseCopyCreateParams) (DatabaseCopy, error) {
+	var dbCopy DatabaseCopy
+	if server == "" {
+		return dbCopy, fmt.Errorf(errParamNotSpecified, "server")
+	}
+	if database == "" {
+		return dbCopy, fmt.Errorf(errParamNotSpecified, "database")
+	}
+	if params.PartnerServer == "" {
+		return dbCopy, fmt.Errorf(errParamNotSpecified, "PartnerServer")
+	}
+
+	req, err := xml.Marshal(params)
+	if err != nil {
+		return dbCopy, err
+	}
+
+	url := fmt.Sprintf(azureCreateDatabaseCopyURL, server, database)
+	resp, err := c.mgmtClient.SendAzurePostRequestWithReturnedResponse(url, req)
+	if err != nil {
+		return dbCopy, err
+	}
+
+	err = xml.Unmarshal(resp, &dbCopy)
+	return dbCopy, err
+}
+
+// ListDatabaseCopies returns the copies of a database, including their
+// replication state.
+func (c SQLDatabaseClient) ListDatabaseCopies(server, database string) (ListDatabaseCopiesResponse, error) {
+	var copies ListDatabaseCopiesResponse
+	if server == "" {
+		return copies, fmt.Errorf(errParamNotSpecified, "server")
+	}
+	if database == "" {
+		return copies, fmt.Errorf(errParamNotSpecified, "database")
+	}
+
+	url := fmt.Sprintf(azureListDatabaseCopiesURL, server, database)
+	resp, err := c.mgmtClient.SendAzureGetRequest(url)
+	if err != nil {
+		return copies, err
+	}
+
+	err = xml.Unmarshal(resp, &copies)
+	return copies, err
+}
+
+// GetDatabaseCopy gets a copy of a database by the name returned from
+// CopyDatabase.
+func (c SQLDatabaseClient) GetDatabaseCopy(server, database, copyName string) (DatabaseCopy, error) {
+	var dbCopy DatabaseCopy
+	if server == "" {
+		return dbCopy, fmt.Errorf(errParamNotSpecified, "server")
+	}
+	if database == "" {
+		return dbCopy, fmt.Errorf(errParamNotSpecified, "database")
+	}
+	if copyName == "" {
+		return dbCopy, fmt.Errorf(errParamNotSpecified, "copyName")
+	}
+
+	url := fmt.Sprintf(azureGetDatabaseCopyURL, server, database, copyName)
+	resp, err := c.mgmtClient.SendAzureGetRequest(url)
+	if err != nil {
+		return dbCopy, err
+	}
+
+	err = xml.Unmarshal(resp, &dbCopy)
+	return dbCopy, err
+}
+
+// DeleteDatabaseCopy stops a copy of a database. The copied database is
+// kept as an independent database.
+func (c SQLDatabaseClient) DeleteDatabaseCopy(server, database, copyName string) error {
+	if server == "" {
+		return fmt.Errorf(errParamNotSpecified, "server")
+	}
+	if database == "" {
+		return fmt.Errorf(errParamNotSpecified, "database")
+	}
+	if copyName == "" {
+		return fmt.Errorf(errParamNotSpecified, "copyName")
+	}
+
+	url := fmt.Sprintf(azureDeleteDatabaseCopyURL, server, database, copyName)
+	_, err := c.mgmtClient.SendAzureDeleteRequest(url)
+	return err
+}
+
+// ListServiceObjectives returns the service objectives, or performance
+// levels, which databases on the given server can be assigned.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn505714.aspx
+func (c SQLDatabaseClient) ListServiceObjectives(server string) (ListServiceObjectivesResponse, error) {
+	var objectives ListServiceObjectivesResponse
+	if server == "" {
+		return objectives, fmt.Errorf(errParamNotSpecified, "server")
+	}
+
+	url := fmt.Sprintf(azureListServiceObjectivesURL, server)
+	resp, err := c.mgmtClient.SendAzureGetRequest(url)
+	if err != nil {
+		return objectives, err
+	}
+
+	err = xml.Unmarshal(resp, &objectives)
+	return objectives, err
+}
+
+// GetServiceObjective gets a service objective by its ID.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn505714.aspx
+func (c SQLDatabaseClient) GetServiceObjective(server, id string) (ServiceObjective, error) {
+	var objective ServiceObjective
+	if server == "" {
+		return objective, fmt.Errorf(errParamNotSpecified, "server")
+	}
+	if id == "" {
+		return objective, fmt.Errorf(errParamNotSpecified, "id")
+	}
+
+	url := fmt.Sprintf(azureGetServiceObjectiveURL, server, id)
+	resp, err := c.mgmtClient.SendAzureGetRequest(url)
+	if err != nil {
+		return objective, err
+	}
+
+	err = xml.Unmarshal(resp, &objective)
+	return objective, err
+}
+
+// GetServiceObjectiveByName gets a service objective by its name, such as
+// S1 or P2, so that its ID can be used to create or update a database.
+func (c SQLDatabaseClient) GetServiceObjectiveByName(server, name string) (ServiceObjective, error) {
+	if name == "" {
+		return ServiceObjective{}, fmt.Errorf(errParamNotSpecified, "name")
+	}
+
+	objectives, err := c.ListServiceObjectives(server)
+	if err != nil {
+		return ServiceObjective{}, err
+	}
+	for _, o := range objectives.ServiceObjectives {
+		if strings.EqualFold(o.Name, name) {
+			return o, nil
+		}
+	}
+	return ServiceObjective{}, fmt.Errorf("Service objective %s not found on server %s", name, server)
+}
+
+// RestoreDatabase starts restoring a database, or a dropped database, to a
+// point in time as a new database. The new database can be waited for with
+// WaitForDatabaseCreation.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn509571.aspx
+func (c SQLDatabaseClient) RestoreDatabase(server string, params RestoreDatabaseParams) (RestoreDatabaseOperation, error) {
+	var op RestoreDatabaseOperation
+	if server == "" {
+		return op, fmt.Errorf(errParamNotSpecified, "server")
+	}
+	if params.SourceDatabaseName == "" {
+		return op, fmt.Errorf(errParamNotSpecified, "SourceDatabaseName")
+	}
+	if params.TargetDatabaseName == "" {
+		return op, fmt.Errorf(errParamNotSpecified, "TargetDatabaseName")
+	}
+
+	req, err := xml.Marshal(params)
+	if err != nil {
+		return op, err
+	}
+
+	url := fmt.Sprintf(azureRestoreDatabaseURL, server)
+	resp, err := c.mgmtClient.SendAzurePostRequestWithReturnedResponse(url, req)
+	if err != nil {
+		return op, err
+	}
+
+	err = xml.Unmarshal(resp, &op)
+	return op, err
+}
+
+// ListRestorableDroppedDatabases returns the dropped databases on the given
+// server which can still be restored with RestoreDatabase.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn509562.aspx
+func (c SQLDatabaseClient) ListRestorableDroppedDatabases(server string) (ListRestorableDroppedDatabasesResponse, error) {
+	var databases ListRestorableDroppedDatabasesResponse
+	if server == "" {
+		return databases, fmt.Errorf(errParamNotSpecified, "server")
+	}
+
+	url := fmt.Sprintf(azureListRestorableDroppedDBsURL, server)
+	resp, err := c.mgmtClient.SendAzureGetRequest(url)
+	if err != nil {
+		return databases, err
+	}
+
+	err = xml.Unmarshal(resp, &databases)
+	return databases, err
+}
@@ -0,0 +1,232 @@
+package sql
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+// databaseClient serves a fixed database from GetDatabase.
+type databaseClient struct {
+	management.Client
+	database string
+}
+
+func (c databaseClient) SendAzureGetRequest(url string) ([]byte, error) {
+	return []byte(c.database), nil
+}
+
+func TestWaitForDatabaseUpdate(t *testing.T) {
+	const objective = "f1173c43-91bd-4aaa-973c-54e79e15235b"
+
+	complete := databaseClient{database: `<ServiceResource xmlns="http://schemas.microsoft.com/windowsazure">
+  <Name>db</Name>
+  <AssignedServiceObjectiveId>F1173C43-91BD-4AAA-973C-54E79E15235B</AssignedServiceObjectiveId>
+  <ServiceObjectiveAssignmentState>1</ServiceObjectiveAssignmentState>
+  <ServiceObjectiveAssignmentStateDescription>Complete</ServiceObjectiveAssignmentStateDescription>
+</ServiceResource>`}
+	if err := NewClient(complete).WaitForDatabaseUpdate("server", "db", objective, nil); err != nil {
+		t.Fatalf("Expected no error but got %v", err)
+	}
+
+	failed := databaseClient{database: `<ServiceResource xmlns="http://schemas.microsoft.com/windowsazure">
+  <Name>db</Name>
+  <AssignedServiceObjectiveId>910b4fcb-8a29-4c3e-958f-f7ba794388b2</AssignedServiceObjectiveId>
+  <ServiceObjectiveAssignmentState>2</ServiceObjectiveAssignmentState>
+  <ServiceObjectiveAssignmentStateDescription>Failed</ServiceObjectiveAssignmentStateDescription>
+  <ServiceObjectiveAssignmentErrorCode>40630</ServiceObjectiveAssignmentErrorCode>
+  <ServiceObjectiveAssignmentErrorDescription>quota exceeded</ServiceObjectiveAssignmentErrorDescription>
+</ServiceResource>`}
+	if err := NewClient(failed).WaitForDatabaseUpdate("server", "db", objective, nil); err == nil {
+		t.Fatal("Expected an error for a failed update")
+	}
+
+	// the update is still pending, has not started yet and the previous
+	// update is reported, or there is no assignment state at all
+	cancel := make(chan struct{})
+	close(cancel)
+	for _, database := range []string{`<ServiceResource xmlns="http://schemas.microsoft.com/windowsazure">
+  <Name>db</Name>
+  <AssignedServiceObjectiveId>910b4fcb-8a29-4c3e-958f-f7ba794388b2</AssignedServiceObjectiveId>
+  <ServiceObjectiveAssignmentState>0</ServiceObjectiveAssignmentState>
+  <ServiceObjectiveAssignmentStateDescription>Pending</ServiceObjectiveAssignmentStateDescription>
+</ServiceResource>`, `<ServiceResource xmlns="http://schemas.microsoft.com/windowsazure">
+  <Name>db</Name>
+  <AssignedServiceObjectiveId>910b4fcb-8a29-4c3e-958f-f7ba794388b2</AssignedServiceObjectiveId>
+  <ServiceObjectiveAssignmentState>1</ServiceObjectiveAssignmentState>
+  <ServiceObjectiveAssignmentStateDescription>Complete</ServiceObjectiveAssignmentStateDescription>
+</ServiceResource>`, `<ServiceResource xmlns="http://schemas.microsoft.com/windowsazure">
+  <Name>db</Name>
+  <ServiceObjectiveAssignmentStateDescription></ServiceObjectiveAssignmentStateDescription>
+</ServiceResource>`} {
+		err := NewClient(databaseClient{database: database}).WaitForDatabaseUpdate("server", "db", objective, cancel)
+		if err != management.ErrOperationCancelled {
+			t.Fatalf("Expected %v but got %v", management.ErrOperationCancelled, err)
+		}
+	}
+
+	if err := NewClient(complete).WaitForDatabaseUpdate("server", "db", "", nil); err == nil {
+		t.Fatal("Expected an error for a missing service objective")
+	}
+}
+
+// postClient records the POST requests with returned responses and answers
+// them with its responses in order.
+type postClient struct {
+	management.Client
+	urls      []string
+	requests  [][]byte
+	responses []string
+}
+
+func (c *postClient) SendAzurePostRequestWithReturnedResponse(url string, data []byte) ([]byte, error) {
+	c.urls = append(c.urls, url)
+	c.requests = append(c.requests, data)
+	if len(c.responses) == 0 {
+		return nil, management.AzureError{Code: "ResourceNotFound"}
+	}
+	response := c.responses[0]
+	c.responses = c.responses[1:]
+	return []byte(response), nil
+}
+
+const dacRequestID = `<guid xmlns="http://schemas.microsoft.com/2003/10/Serialization/">9f7cc4e0-6d3e-4e16-a1b3-7c9e1ac1a9c5</guid>`
+
+func testDacParams() (BlobCredentials, ConnectionInfo) {
+	blob := BlobCredentials{
+		URI:              "https://account.blob.core.windows.net/bacpacs/db.bacpac",
+		StorageAccessKey: "key",
+	}
+	conn := ConnectionInfo{
+		DatabaseName: "db",
+		Password:     "password",
+		ServerName:   "server.database.windows.net",
+		UserName:     "admin",
+	}
+	return blob, conn
+}
+
+func TestImportAndExportDatabase(t *testing.T) {
+	blob, conn := testDacParams()
+	c := &postClient{responses: []string{dacRequestID, dacRequestID}}
+
+	id, err := NewClient(c).ImportDatabase("server", ImportParams{BlobCredentials: blob, ConnectionInfo: conn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "9f7cc4e0-6d3e-4e16-a1b3-7c9e1ac1a9c5"; id != expected {
+		t.Fatalf("Expected %q but got %q", expected, id)
+	}
+	id, err = NewClient(c).ExportDatabase("server", ExportParams{BlobCredentials: blob, ConnectionInfo: conn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := "9f7cc4e0-6d3e-4e16-a1b3-7c9e1ac1a9c5"; id != expected {
+		t.Fatalf("Expected %q but got %q", expected, id)
+	}
+
+	expected := []string{
+		"services/sqlservers/servers/server/DacOperations/Import",
+		"services/sqlservers/servers/server/DacOperations/Export",
+	}
+	if fmt.Sprint(c.urls) != fmt.Sprint(expected) {
+		t.Fatalf("Expected %q but got %q", expected, c.urls)
+	}
+	for _, request := range c.requests {
+		if !strings.Contains(string(request), blobStorageAccessKeyCredentialsType) {
+			t.Fatalf("Expected the credentials type in %s", request)
+		}
+	}
+
+	blob.StorageAccessKey = ""
+	if _, err := NewClient(c).ExportDatabase("server", ExportParams{BlobCredentials: blob, ConnectionInfo: conn}); err == nil {
+		t.Fatal("Expected an error for a missing storage access key")
+	}
+	if len(c.requests) != 2 {
+		t.Fatalf("Expected 2 requests but got %d", len(c.requests))
+	}
+}
+
+func importExportStatuses(statuses ...string) string {
+	response := `<ArrayOfStatusInfo xmlns="http://schemas.datacontract.org/2004/07/Microsoft.SqlServer.Management.Dac.ServiceTypes" xmlns:i="http://www.w3.org/2001/XMLSchema-instance">`
+	for i, status := range statuses {
+		response += fmt.Sprintf(`<StatusInfo><ErrorMessage>error %d</ErrorMessage><RequestId>request-%d</RequestId><Status>%s</Status></StatusInfo>`, i, i, status)
+	}
+	return response + `</ArrayOfStatusInfo>`
+}
+
+func TestGetImportExportStatus(t *testing.T) {
+	c := &postClient{responses: []string{
+		importExportStatuses(ImportExportStatusCompleted, ImportExportStatusPending),
+		importExportStatuses(ImportExportStatusCompleted),
+	}}
+
+	status, err := NewClient(c).GetImportExportStatus("server", ImportExportStatusParams{RequestID: "request-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.RequestID != "request-1" || status.Status != ImportExportStatusPending {
+		t.Fatalf("Unexpected status: %+v", status)
+	}
+	if expected := "services/sqlservers/servers/server/DacOperations/Status"; c.urls[0] != expected {
+		t.Fatalf("Expected %q but got %q", expected, c.urls[0])
+	}
+
+	if _, err := NewClient(c).GetImportExportStatus("server", ImportExportStatusParams{RequestID: "request-1"}); err == nil {
+		t.Fatal("Expected an error for an unknown request")
+	}
+}
+
+func TestWaitForImportExport(t *testing.T) {
+	params := ImportExportStatusParams{RequestID: "request-1"}
+
+	c := &postClient{responses: []string{importExportStatuses(ImportExportStatusPending, ImportExportStatusCompleted)}}
+	if status, err := NewClient(c).WaitForImportExport("server", params, nil); err != nil || status.RequestID != "request-1" {
+		t.Fatalf("Expected request-1 to complete but got %+v, %v", status, err)
+	}
+
+	c = &postClient{responses: []string{importExportStatuses(ImportExportStatusCompleted, ImportExportStatusFailed)}}
+	_, err := NewClient(c).WaitForImportExport("server", params, nil)
+	if err == nil || !strings.Contains(err.Error(), "error 1") {
+		t.Fatalf("Expected the error of request-1 but got %v", err)
+	}
+
+	cancel := make(chan struct{})
+	close(cancel)
+	c = &postClient{responses: []string{importExportStatuses(ImportExportStatusCompleted, "Running, Progress = 50%")}}
+	if _, err := NewClient(c).WaitForImportExport("server", params, cancel); err != management.ErrOperationCancelled {
+		t.Fatalf("Expected %v but got %v", management.ErrOperationCancelled, err)
+	}
+}
+
+func TestCopyDatabase(t *testing.T) {
+	c := &postClient{responses: []string{`<ServiceResource xmlns="http://schemas.microsoft.com/windowsazure">
+  <Name>4a2d2a3e-16d3-4a6f-9c4f-7a1c5e0e5a1b</Name>
+  <SourceServerName>server</SourceServerName>
+  <SourceDatabaseName>db</SourceDatabaseName>
+  <DestinationServerName>partner</DestinationServerName>
+  <DestinationDatabaseName>db</DestinationDatabaseName>
+  <IsContinuous>true</IsContinuous>
+  <ReplicationStateDescription>PENDING</ReplicationStateDescription>
+</ServiceResource>`}}
+
+	dbCopy, err := NewClient(c).CopyDatabase("server", "db", DatabaseCopyCreateParams{PartnerServer: "partner", IsContinuous: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dbCopy.DestinationServerName != "partner" || !dbCopy.IsContinuous || dbCopy.ReplicationStateDescription != DatabaseCopyStatePending {
+		t.Fatalf("Unexpected copy: %+v", dbCopy)
+	}
+	if expected := "services/sqlservers/servers/server/databases/db/databasecopies"; c.urls[0] != expected {
+		t.Fatalf("Expected %q but got %q", expected, c.urls[0])
+	}
+	if !strings.Contains(string(c.requests[0]), "<PartnerServer>partner</PartnerServer>") {
+		t.Fatalf("Unexpected request %s", c.requests[0])
+	}
+
+	if _, err := NewClient(c).CopyDatabase("server", "db", DatabaseCopyCreateParams{}); err == nil {
+		t.Fatal("Expected an error for a missing partner server")
+	}
+}
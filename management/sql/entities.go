@@ -27,6 +27,11 @@ const (
 	DatabaseServerVersion12 = "12.0"
 )
 
+type administratorLoginPassword struct {
+	XMLName  xml.Name `xml:"http://schemas.microsoft.com/sqlazure/2010/12/ AdministratorLoginPassword"`
+	Password string   `xml:",chardata"`
+}
+
 // DatabaseServer represents the set of data recieved from
 // a database server list operation.
 //
@@ -105,6 +110,14 @@ type ServiceResource struct {
 	CollationName      string
 	MaxSizeBytes       int64
 	ServiceObjectiveID string `xml:"ServiceObjectiveId,omitempty"`
+
+	// The service objective assignment fields report the progress of a
+	// change of the edition or service objective of the database.
+	AssignedServiceObjectiveID                 string `xml:"AssignedServiceObjectiveId"`
+	ServiceObjectiveAssignmentState            int
+	ServiceObjectiveAssignmentStateDescription string
+	ServiceObjectiveAssignmentErrorCode        int
+	ServiceObjectiveAssignmentErrorDescription string
 }
 
 type ListDatabasesResponse struct {
@@ -122,3 +135,201 @@ type ServiceResourceUpdateParams struct {
 	MaxSizeBytes       int64  `xml:",omitempty"`
 	ServiceObjectiveID string `xml:"ServiceObjectiveId,omitempty"`
 }
+
+const blobStorageAccessKeyCredentialsType = "BlobStorageAccessKeyCredentials"
+
+// BlobCredentials identifies the storage blob a database is imported from
+// or exported to, and the access key of its storage account.
+type BlobCredentials struct {
+	// Type is set by ImportDatabase and ExportDatabase.
+	Type             string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+	URI              string `xml:"Uri"`
+	StorageAccessKey string
+}
+
+// ConnectionInfo is the fully qualified domain name of a database server,
+// a login of the server and the database to import into or export from.
+type ConnectionInfo struct {
+	DatabaseName string
+	Password     string
+	ServerName   string
+	UserName     string
+}
+
+// ImportParams represents the set of parameters of an import of a .bacpac
+// file into a new database.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn781282.aspx
+type ImportParams struct {
+	XMLName          xml.Name `xml:"http://schemas.datacontract.org/2004/07/Microsoft.SqlServer.Management.Dac.ServiceTypes ImportInput"`
+	AzureEdition     string
+	BlobCredentials  BlobCredentials
+	ConnectionInfo   ConnectionInfo
+	DatabaseSizeInGB int
+}
+
+// ExportParams represents the set of parameters of an export of a database
+// to a .bacpac file.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn781282.aspx
+type ExportParams struct {
+	XMLName         xml.Name `xml:"http://schemas.datacontract.org/2004/07/Microsoft.SqlServer.Management.Dac.ServiceTypes ExportInput"`
+	BlobCredentials BlobCredentials
+	ConnectionInfo  ConnectionInfo
+}
+
+// ImportExportStatusParams identifies an import or export request and the
+// server login used to read its status.
+type ImportExportStatusParams struct {
+	XMLName    xml.Name `xml:"http://schemas.datacontract.org/2004/07/Microsoft.SqlServer.Management.Dac.ServiceTypes StatusInput"`
+	Password   string
+	RequestID  string `xml:"RequestId"`
+	ServerName string
+	UserName   string
+}
+
+// ImportExportStatus represents the status of an import or export request.
+type ImportExportStatus struct {
+	BlobURI          string `xml:"BlobUri"`
+	DatabaseName     string
+	ErrorMessage     string
+	LastModifiedTime string
+	QueuedTime       string
+	RequestID        string `xml:"RequestId"`
+	RequestType      string
+	ServerName       string
+	// Status is one of the ImportExportStatus constants, or a progress
+	// message while the request is running.
+	Status string
+}
+
+type importExportStatusResponse struct {
+	Statuses []ImportExportStatus `xml:"StatusInfo"`
+}
+
+const (
+	ImportExportStatusPending   = "Pending"
+	ImportExportStatusCompleted = "Completed"
+	ImportExportStatusFailed    = "Failed"
+)
+
+// DatabaseCopyCreateParams represents the set of parameters of a copy of a
+// database to another server.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn509573.aspx
+type DatabaseCopyCreateParams struct {
+	XMLName            xml.Name `xml:"http://schemas.microsoft.com/windowsazure ServiceResource"`
+	PartnerServer      string
+	PartnerDatabase    string `xml:",omitempty"`
+	IsContinuous       bool
+	IsOfflineSecondary bool `xml:",omitempty"`
+}
+
+// DatabaseCopy represents a copy of a database and the state of its
+// replication. ReplicationState is the numeric code of the state described
+// by ReplicationStateDescription, one of the DatabaseCopyState constants.
+type DatabaseCopy struct {
+	Name                        string
+	SourceServerName            string
+	SourceDatabaseName          string
+	DestinationServerName       string
+	DestinationDatabaseName     string
+	IsContinuous                bool
+	IsOfflineSecondary          bool
+	ReplicationState            int
+	ReplicationStateDescription string
+	PercentComplete             float64
+	StartDate                   string
+	ModifyDate                  string
+}
+
+// Values of DatabaseCopy.ReplicationStateDescription.
+const (
+	DatabaseCopyStatePending    = "PENDING"
+	DatabaseCopyStateSeeding    = "SEEDING"
+	DatabaseCopyStateCatchUp    = "CATCH_UP"
+	DatabaseCopyStateTerminated = "TERMINATED"
+	DatabaseCopyStateSuspended  = "SUSPENDED"
+)
+
+type ListDatabaseCopiesResponse struct {
+	DatabaseCopies []DatabaseCopy `xml:"ServiceResource"`
+}
+
+// ServiceObjective represents a performance level which can be assigned to
+// databases with its ID.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn505714.aspx
+type ServiceObjective struct {
+	Name              string
+	ID                string `xml:"Id"`
+	State             string
+	Description       string
+	IsDefault         bool
+	IsSystem          bool
+	Enabled           bool
+	DimensionSettings []DimensionSetting `xml:"DimensionSettings>ServiceResource"`
+}
+
+// DimensionSetting represents a setting of a service objective, such as
+// its performance level within an edition.
+type DimensionSetting struct {
+	Name        string
+	ID          string `xml:"Id"`
+	Description string
+	Ordinal     int
+	IsDefault   bool
+}
+
+type ListServiceObjectivesResponse struct {
+	ServiceObjectives []ServiceObjective `xml:"ServiceResource"`
+}
+
+// RestoreDatabaseParams represents the set of parameters of a restore of a
+// database to a point in time. Dates are in ISO 8601 format, for example
+// as returned from time.Time.Format(time.RFC3339).
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn509571.aspx
+type RestoreDatabaseParams struct {
+	XMLName            xml.Name `xml:"http://schemas.microsoft.com/windowsazure ServiceResource"`
+	SourceDatabaseName string
+	// SourceDatabaseDeletionDate is the DeletionDate of a restorable
+	// dropped database, and must be empty for existing databases.
+	SourceDatabaseDeletionDate string `xml:",omitempty"`
+	// TargetServerName defaults to the server of the source database.
+	TargetServerName   string `xml:",omitempty"`
+	TargetDatabaseName string
+	// PointInTime defaults to the latest restorable time.
+	PointInTime string `xml:",omitempty"`
+}
+
+// RestoreDatabaseOperation represents a restore request.
+type RestoreDatabaseOperation struct {
+	Name                       string
+	State                      string
+	RequestID                  string `xml:"RequestID"`
+	SourceDatabaseName         string
+	SourceDatabaseDeletionDate string
+	TargetServerName           string
+	TargetDatabaseName         string
+	PointInTime                string
+}
+
+// RestorableDroppedDatabase represents a dropped database which can be
+// restored to any point in time between its RecoveryPeriodStartDate and its
+// DeletionDate.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn509562.aspx
+type RestorableDroppedDatabase struct {
+	Name                    string
+	Edition                 string
+	MaxSizeBytes            int64
+	ServiceObjectiveID      string `xml:"ServiceObjectiveId"`
+	CreationDate            string
+	DeletionDate            string
+	RecoveryPeriodStartDate string
+}
+
+type ListRestorableDroppedDatabasesResponse struct {
+	Databases []RestorableDroppedDatabase `xml:"ServiceResource"`
+}
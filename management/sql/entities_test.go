@@ -0,0 +1,176 @@
+package sql
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func Test_ImportParams_Marshal(t *testing.T) {
+	params := ImportParams{
+		AzureEdition: "Standard",
+		BlobCredentials: BlobCredentials{
+			Type:             blobStorageAccessKeyCredentialsType,
+			URI:              "https://account.blob.core.windows.net/bacpacs/db.bacpac",
+			StorageAccessKey: "key",
+		},
+		ConnectionInfo: ConnectionInfo{
+			DatabaseName: "db",
+			Password:     "password",
+			ServerName:   "server.database.windows.net",
+			UserName:     "admin",
+		},
+		DatabaseSizeInGB: 5,
+	}
+
+	data, err := xml.Marshal(params)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the service is namespace aware, so only the decoded form matters
+	var decoded struct {
+		AzureEdition    string
+		BlobCredentials struct {
+			Type string `xml:"http://www.w3.org/2001/XMLSchema-instance type,attr"`
+			Uri  string
+		}
+		ConnectionInfo struct {
+			ServerName string
+		}
+		DatabaseSizeInGB int
+	}
+	if err := xml.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "BlobStorageAccessKeyCredentials"; decoded.BlobCredentials.Type != expected {
+		t.Fatalf("Expected %q but got %q in %s", expected, decoded.BlobCredentials.Type, data)
+	}
+	if decoded.BlobCredentials.Uri != params.BlobCredentials.URI || decoded.ConnectionInfo.ServerName != params.ConnectionInfo.ServerName {
+		t.Fatalf("Unexpected import request: %s", data)
+	}
+}
+
+func Test_importExportStatusResponse_Unmarshal(t *testing.T) {
+	response := []byte(`<ArrayOfStatusInfo xmlns="http://schemas.datacontract.org/2004/07/Microsoft.SqlServer.Management.Dac.ServiceTypes" xmlns:i="http://www.w3.org/2001/XMLSchema-instance">
+  <StatusInfo>
+    <BlobUri>https://account.blob.core.windows.net/bacpacs/db.bacpac</BlobUri>
+    <DatabaseName>db</DatabaseName>
+    <ErrorMessage i:nil="true"/>
+    <LastModifiedTime>2015-10-21T07:28:00Z</LastModifiedTime>
+    <QueuedTime>2015-10-21T07:20:00Z</QueuedTime>
+    <RequestId>9f7cc4e0-6d3e-4e16-a1b3-7c9e1ac1a9c5</RequestId>
+    <RequestType>Export</RequestType>
+    <ServerName>server.database.windows.net</ServerName>
+    <Status>Completed</Status>
+  </StatusInfo>
+</ArrayOfStatusInfo>`)
+
+	var statuses importExportStatusResponse
+	if err := xml.Unmarshal(response, &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses.Statuses) != 1 {
+		t.Fatalf("Expected 1 status but got %d", len(statuses.Statuses))
+	}
+	if s := statuses.Statuses[0]; s.Status != ImportExportStatusCompleted || s.RequestType != "Export" || s.BlobURI == "" {
+		t.Fatalf("Unexpected status: %+v", s)
+	}
+}
+
+func Test_ListDatabaseCopiesResponse_Unmarshal(t *testing.T) {
+	response := []byte(`<ServiceResources xmlns="http://schemas.microsoft.com/windowsazure">
+  <ServiceResource>
+    <Name>4a2d2a3e-16d3-4a6f-9c4f-7a1c5e0e5a1b</Name>
+    <SourceServerName>source</SourceServerName>
+    <SourceDatabaseName>db</SourceDatabaseName>
+    <DestinationServerName>destination</DestinationServerName>
+    <DestinationDatabaseName>db</DestinationDatabaseName>
+    <IsContinuous>true</IsContinuous>
+    <ReplicationState>1</ReplicationState>
+    <ReplicationStateDescription>CATCH_UP</ReplicationStateDescription>
+    <PercentComplete>100</PercentComplete>
+  </ServiceResource>
+</ServiceResources>`)
+
+	var copies ListDatabaseCopiesResponse
+	if err := xml.Unmarshal(response, &copies); err != nil {
+		t.Fatal(err)
+	}
+	if len(copies.DatabaseCopies) != 1 {
+		t.Fatalf("Expected 1 copy but got %d", len(copies.DatabaseCopies))
+	}
+	c := copies.DatabaseCopies[0]
+	if !c.IsContinuous || c.ReplicationStateDescription != DatabaseCopyStateCatchUp || c.PercentComplete != 100 {
+		t.Fatalf("Unexpected copy: %+v", c)
+	}
+}
+
+func Test_ListServiceObjectivesResponse_Unmarshal(t *testing.T) {
+	response := []byte(`<ServiceResources xmlns="http://schemas.microsoft.com/windowsazure">
+  <ServiceResource>
+    <Name>S1</Name>
+    <Type>Microsoft.SqlAzure.ServiceObjective</Type>
+    <State>Normal</State>
+    <Id>1b1ebd4d-d903-4baa-97f9-4ea675f5e928</Id>
+    <IsDefault>False</IsDefault>
+    <IsSystem>False</IsSystem>
+    <Description/>
+    <Enabled>True</Enabled>
+    <DimensionSettings>
+      <ServiceResource>
+        <Name>S1</Name>
+        <Type>Microsoft.SqlAzure.DimensionSetting</Type>
+        <Id>765fbb24-f8f7-4a4a-9b35-2bff3f4f2ff3</Id>
+        <Description>Standard S1 resource allocation.</Description>
+        <Ordinal>1</Ordinal>
+        <IsDefault>False</IsDefault>
+      </ServiceResource>
+    </DimensionSettings>
+  </ServiceResource>
+</ServiceResources>`)
+
+	var objectives ListServiceObjectivesResponse
+	if err := xml.Unmarshal(response, &objectives); err != nil {
+		t.Fatal(err)
+	}
+	if len(objectives.ServiceObjectives) != 1 {
+		t.Fatalf("Expected 1 service objective but got %d", len(objectives.ServiceObjectives))
+	}
+	o := objectives.ServiceObjectives[0]
+	if expected := "1b1ebd4d-d903-4baa-97f9-4ea675f5e928"; o.ID != expected {
+		t.Fatalf("Expected %q but got %q", expected, o.ID)
+	}
+	if !o.Enabled || o.IsDefault {
+		t.Fatalf("Unexpected service objective: %+v", o)
+	}
+	if len(o.DimensionSettings) != 1 || o.DimensionSettings[0].Ordinal != 1 {
+		t.Fatalf("Unexpected dimension settings: %+v", o.DimensionSettings)
+	}
+}
+
+func Test_RestoreDatabaseParams_Marshal(t *testing.T) {
+	data, err := xml.Marshal(RestoreDatabaseParams{
+		SourceDatabaseName: "db",
+		TargetDatabaseName: "db-restored",
+		PointInTime:        "2015-10-21T07:28:00Z",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<ServiceResource xmlns="http://schemas.microsoft.com/windowsazure"><SourceDatabaseName>db</SourceDatabaseName>` +
+		`<TargetDatabaseName>db-restored</TargetDatabaseName><PointInTime>2015-10-21T07:28:00Z</PointInTime></ServiceResource>`
+	if string(data) != expected {
+		t.Fatalf("Expected %q but got %q", expected, string(data))
+	}
+}
+
+func Test_administratorLoginPassword_Marshal(t *testing.T) {
+	data, err := xml.Marshal(administratorLoginPassword{Password: "p<ss"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<AdministratorLoginPassword xmlns="http://schemas.microsoft.com/sqlazure/2010/12/">p&lt;ss</AdministratorLoginPassword>`
+	if string(data) != expected {
+		t.Fatalf("Expected %q but got %q", expected, string(data))
+	}
+}
@@ -9,10 +9,11 @@ import (
 )
 
 const (
-	azureStorageServiceListURL         = "services/storageservices"
-	azureStorageServiceURL             = "services/storageservices/%s"
-	azureStorageServiceKeysURL         = "services/storageservices/%s/keys"
-	azureStorageAccountAvailabilityURL = "services/storageservices/operations/isavailable/%s"
+	azureStorageServiceListURL           = "services/storageservices"
+	azureStorageServiceURL               = "services/storageservices/%s"
+	azureStorageServiceKeysURL           = "services/storageservices/%s/keys"
+	azureStorageServiceRegenerateKeysURL = "services/storageservices/%s/keys?action=regenerate"
+	azureStorageAccountAvailabilityURL   = "services/storageservices/operations/isavailable/%s"
 
 	azureXmlns = "http://schemas.microsoft.com/windowsazure"
 
@@ -68,6 +69,34 @@ func (s StorageServiceClient) GetStorageServiceKeys(serviceName string) (GetStor
 	return r, err
 }
 
+// RegenerateStorageServiceKeys regenerates the primary or secondary access
+// key of the specified storage account and returns both keys.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/ee460795.aspx
+func (s StorageServiceClient) RegenerateStorageServiceKeys(serviceName string, keyType KeyType) (GetStorageServiceKeysResponse, error) {
+	var r GetStorageServiceKeysResponse
+	if serviceName == "" {
+		return r, fmt.Errorf(errParamNotSpecified, "serviceName")
+	}
+	if keyType == "" {
+		return r, fmt.Errorf(errParamNotSpecified, "keyType")
+	}
+
+	req, err := xml.Marshal(RegenerateKeysInput{KeyType: keyType})
+	if err != nil {
+		return r, err
+	}
+
+	requestURL := fmt.Sprintf(azureStorageServiceRegenerateKeysURL, serviceName)
+	data, err := s.client.SendAzurePostRequestWithReturnedResponse(requestURL, req)
+	if err != nil {
+		return r, err
+	}
+
+	err = xml.Unmarshal(data, &r)
+	return r, err
+}
+
 func (s StorageServiceClient) CreateStorageService(parameters StorageAccountCreateParameters) (management.OperationID, error) {
 	data, err := xml.Marshal(CreateStorageServiceInput{
 		StorageAccountCreateParameters: parameters})
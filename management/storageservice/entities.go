@@ -37,6 +37,22 @@ type GetStorageServiceKeysResponse struct {
 	SecondaryKey string `xml:"StorageServiceKeys>Secondary"`
 }
 
+// RegenerateKeysInput is the request body of Regenerate Storage Account Keys
+// call.
+type RegenerateKeysInput struct {
+	XMLName xml.Name `xml:"http://schemas.microsoft.com/windowsazure RegenerateKeys"`
+	KeyType KeyType
+}
+
+// KeyType identifies one of the access keys of a storage account.
+type KeyType string
+
+// Access keys of a storage account
+const (
+	KeyTypePrimary   KeyType = "Primary"
+	KeyTypeSecondary KeyType = "Secondary"
+)
+
 type CreateStorageServiceInput struct {
 	XMLName xml.Name `xml:"http://schemas.microsoft.com/windowsazure CreateStorageServiceInput"`
 	StorageAccountCreateParameters
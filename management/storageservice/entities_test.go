@@ -29,3 +29,15 @@ func Test_StorageServiceKeysResponse_Unmarshal(t *testing.T) {
 		t.Fatalf("Expected %q but got %q", expected, keysResponse.SecondaryKey)
 	}
 }
+
+func Test_RegenerateKeysInput_Marshal(t *testing.T) {
+	data, err := xml.Marshal(RegenerateKeysInput{KeyType: KeyTypeSecondary})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `<RegenerateKeys xmlns="http://schemas.microsoft.com/windowsazure"><KeyType>Secondary</KeyType></RegenerateKeys>`
+	if string(data) != expected {
+		t.Fatalf("Expected %q but got %q", expected, string(data))
+	}
+}
@@ -0,0 +1,135 @@
+// Package subscription provides a client for Subscriptions.
+package subscription
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+	"github.com/Azure/azure-sdk-for-go/management/virtualmachine"
+)
+
+const (
+	azureOperationsURL = "operations"
+
+	// defaultRoleSize is the size of roles which do not specify one.
+	defaultRoleSize = "Small"
+
+	errParamNotSpecified = "Parameter %s is not specified."
+)
+
+//NewClient is used to instantiate a new SubscriptionClient from an Azure client
+func NewClient(client management.Client) SubscriptionClient {
+	return SubscriptionClient{client: client}
+}
+
+// GetSubscription returns the details of the subscription of the client,
+// including its quotas and their current usage.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460731.aspx
+func (s SubscriptionClient) GetSubscription() (Subscription, error) {
+	var subscription Subscription
+
+	response, err := s.client.SendAzureGetRequest(management.SubscriptionURL)
+	if err != nil {
+		return subscription, err
+	}
+
+	err = xml.Unmarshal(response, &subscription)
+	return subscription, err
+}
+
+// ListOperations returns a page of the operations performed on the
+// subscription within the given time range. The service keeps the history
+// of operations for 90 days. The next page can be requested with the
+// ContinuationToken of the response.
+//
+// https://msdn.microsoft.com/en-us/library/azure/gg715318.aspx
+func (s SubscriptionClient) ListOperations(params ListOperationsParameters) (ListOperationsResponse, error) {
+	var operations ListOperationsResponse
+	if params.StartTime.IsZero() {
+		return operations, fmt.Errorf(errParamNotSpecified, "StartTime")
+	}
+	if params.EndTime.IsZero() {
+		return operations, fmt.Errorf(errParamNotSpecified, "EndTime")
+	}
+
+	v := url.Values{}
+	v.Add("StartTime", params.StartTime.UTC().Format(time.RFC3339))
+	v.Add("EndTime", params.EndTime.UTC().Format(time.RFC3339))
+	if params.ObjectIDFilter != "" {
+		v.Add("ObjectIdFilter", params.ObjectIDFilter)
+	}
+	if params.OperationResultFilter != "" {
+		v.Add("OperationResultFilter", string(params.OperationResultFilter))
+	}
+	if params.ContinuationToken != "" {
+		v.Add("ContinuationToken", params.ContinuationToken)
+	}
+
+	response, err := s.client.SendAzureGetRequest(azureOperationsURL + "?" + v.Encode())
+	if err != nil {
+		return operations, err
+	}
+
+	err = xml.Unmarshal(response, &operations)
+	return operations, err
+}
+
+// CheckRoleQuota checks whether the subscription has enough cores left to
+// deploy the given roles, so that deployments which would exceed the core
+// quota fail before they are submitted. It returns a CoreQuotaExceededError
+// if they do not fit.
+func (s SubscriptionClient) CheckRoleQuota(roles ...virtualmachine.Role) error {
+	subscription, err := s.GetSubscription()
+	if err != nil {
+		return err
+	}
+
+	sizes, err := virtualmachine.NewClient(s.client).GetRoleSizeList()
+	if err != nil {
+		return err
+	}
+
+	return CheckCoreQuota(subscription, sizes.RoleSizes, roles...)
+}
+
+// CheckCoreQuota checks the cores required by the given roles, according to
+// their RoleSize, against the cores available in the subscription. Roles
+// without a RoleSize are counted with the default size, Small.
+func CheckCoreQuota(subscription Subscription, sizes []virtualmachine.RoleSize, roles ...virtualmachine.Role) error {
+	required, err := RequiredCores(sizes, roles...)
+	if err != nil {
+		return err
+	}
+
+	if available := subscription.AvailableCores(); required > available {
+		return CoreQuotaExceededError{Required: required, Available: available}
+	}
+	return nil
+}
+
+// RequiredCores returns the total number of cores of the given roles
+// according to their RoleSize.
+func RequiredCores(sizes []virtualmachine.RoleSize, roles ...virtualmachine.Role) (int, error) {
+	cores := make(map[string]int, len(sizes))
+	for _, size := range sizes {
+		cores[size.Name] = size.Cores
+	}
+
+	required := 0
+	for _, role := range roles {
+		size := role.RoleSize
+		if size == "" {
+			size = defaultRoleSize
+		}
+		n, ok := cores[size]
+		if !ok {
+			return 0, fmt.Errorf("Unknown role size %q of role %s", size, role.RoleName)
+		}
+		required += n
+	}
+	return required, nil
+}
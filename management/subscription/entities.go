@@ -0,0 +1,143 @@
+package subscription
+
+import (
+	"encoding/xml"
+	"fmt"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+//SubscriptionClient is used to perform operations on Azure Subscriptions
+type SubscriptionClient struct {
+	client management.Client
+}
+
+// Subscription contains the details of a subscription, including its
+// quotas and their current usage.
+//
+// https://msdn.microsoft.com/en-us/library/azure/ee460731.aspx
+type Subscription struct {
+	XMLName                    xml.Name `xml:"http://schemas.microsoft.com/windowsazure Subscription"`
+	SubscriptionID             string
+	SubscriptionName           string
+	SubscriptionStatus         SubscriptionStatus
+	AccountAdminLiveEmailID    string `xml:"AccountAdminLiveEmailId"`
+	ServiceAdminLiveEmailID    string `xml:"ServiceAdminLiveEmailId"`
+	MaxCoreCount               int
+	MaxStorageAccounts         int
+	MaxHostedServices          int
+	CurrentCoreCount           int
+	CurrentHostedServices      int
+	CurrentStorageAccounts     int
+	MaxVirtualNetworkSites     int
+	CurrentVirtualNetworkSites int
+	MaxLocalNetworkSites       int
+	MaxDNSServers              int `xml:"MaxDnsServers"`
+	MaxPublicIPCount           int
+	CurrentPublicIPCount       int
+	MaxExtraVIPCount           int
+	AADTenantID                string
+	CreatedTime                string
+}
+
+type SubscriptionStatus string
+
+const (
+	SubscriptionStatusActive   = SubscriptionStatus("Active")
+	SubscriptionStatusDisabled = SubscriptionStatus("Disabled")
+)
+
+// AvailableCores returns the number of cores that can still be allocated.
+func (s Subscription) AvailableCores() int {
+	return s.MaxCoreCount - s.CurrentCoreCount
+}
+
+// AvailableHostedServices returns the number of cloud services that can
+// still be created.
+func (s Subscription) AvailableHostedServices() int {
+	return s.MaxHostedServices - s.CurrentHostedServices
+}
+
+// AvailableStorageAccounts returns the number of storage accounts that can
+// still be created.
+func (s Subscription) AvailableStorageAccounts() int {
+	return s.MaxStorageAccounts - s.CurrentStorageAccounts
+}
+
+// AvailableVirtualNetworkSites returns the number of virtual networks that
+// can still be created.
+func (s Subscription) AvailableVirtualNetworkSites() int {
+	return s.MaxVirtualNetworkSites - s.CurrentVirtualNetworkSites
+}
+
+// CoreQuotaExceededError is returned by CheckCoreQuota if a deployment
+// requires more cores than are available in the subscription.
+type CoreQuotaExceededError struct {
+	Required  int
+	Available int
+}
+
+func (e CoreQuotaExceededError) Error() string {
+	return fmt.Sprintf("Deployment requires %d cores but only %d are available in the subscription", e.Required, e.Available)
+}
+
+// ListOperationsParameters filters the operations returned by
+// ListOperations. StartTime and EndTime are required, the other fields are
+// optional.
+type ListOperationsParameters struct {
+	StartTime time.Time
+	EndTime   time.Time
+	// ObjectIDFilter restricts the operations to those on a resource and
+	// its children, identified by its path, such as
+	// /<subscription-id>/services/hostedservices/<service-name>.
+	ObjectIDFilter        string
+	OperationResultFilter management.OperationStatus
+	// ContinuationToken is the token returned with the previous page of
+	// operations.
+	ContinuationToken string
+}
+
+// ListOperationsResponse is a page of operations returned by
+// ListOperations. ContinuationToken is empty on the last page.
+//
+// https://msdn.microsoft.com/en-us/library/azure/gg715318.aspx
+type ListOperationsResponse struct {
+	XMLName           xml.Name    `xml:"http://schemas.microsoft.com/windowsazure SubscriptionOperationCollection"`
+	Operations        []Operation `xml:"SubscriptionOperations>SubscriptionOperation"`
+	ContinuationToken string
+}
+
+// Operation is an operation performed on the subscription.
+type Operation struct {
+	ID                     string               `xml:"OperationId"`
+	ObjectID               string               `xml:"OperationObjectId"`
+	Name                   string               `xml:"OperationName"`
+	Parameters             []OperationParameter `xml:"OperationParameters>OperationParameter"`
+	Caller                 OperationCaller      `xml:"OperationCaller"`
+	Status                 OperationStatus      `xml:"OperationStatus"`
+	OperationStartedTime   string
+	OperationCompletedTime string
+}
+
+// OperationParameter is a parameter an operation was called with.
+type OperationParameter struct {
+	Name  string
+	Value string
+}
+
+// OperationCaller identifies the caller of an operation.
+type OperationCaller struct {
+	UsedServiceManagementAPI          bool `xml:"UsedServiceManagementApi"`
+	UserEmailAddress                  string
+	SubscriptionCertificateThumbprint string
+	ClientIP                          string
+}
+
+// OperationStatus is the result of an operation.
+type OperationStatus struct {
+	ID             string
+	Status         management.OperationStatus
+	HTTPStatusCode int `xml:"HttpStatusCode"`
+	Error          *management.AzureError
+}
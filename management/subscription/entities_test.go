@@ -0,0 +1,183 @@
+package subscription
+
+import (
+	"encoding/xml"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+	"github.com/Azure/azure-sdk-for-go/management/virtualmachine"
+)
+
+func Test_Subscription_Unmarshal(t *testing.T) {
+	// trimmed from https://msdn.microsoft.com/en-us/library/azure/ee460731.aspx
+	response := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<Subscription xmlns="http://schemas.microsoft.com/windowsazure">
+  <SubscriptionID>subscription-id</SubscriptionID>
+  <SubscriptionName>subscription-name</SubscriptionName>
+  <SubscriptionStatus>Active</SubscriptionStatus>
+  <AccountAdminLiveEmailId>account-admin@example.com</AccountAdminLiveEmailId>
+  <MaxCoreCount>20</MaxCoreCount>
+  <MaxStorageAccounts>100</MaxStorageAccounts>
+  <MaxHostedServices>20</MaxHostedServices>
+  <CurrentCoreCount>14</CurrentCoreCount>
+  <CurrentHostedServices>3</CurrentHostedServices>
+  <CurrentStorageAccounts>7</CurrentStorageAccounts>
+  <MaxVirtualNetworkSites>50</MaxVirtualNetworkSites>
+  <CurrentVirtualNetworkSites>1</CurrentVirtualNetworkSites>
+  <MaxDnsServers>9</MaxDnsServers>
+</Subscription>`)
+
+	var subscription Subscription
+	if err := xml.Unmarshal(response, &subscription); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := SubscriptionStatusActive; subscription.SubscriptionStatus != expected {
+		t.Fatalf("Expected %q but got %q", expected, subscription.SubscriptionStatus)
+	}
+	if expected := "account-admin@example.com"; subscription.AccountAdminLiveEmailID != expected {
+		t.Fatalf("Expected %q but got %q", expected, subscription.AccountAdminLiveEmailID)
+	}
+	for _, c := range []struct{ got, expected int }{
+		{subscription.AvailableCores(), 6},
+		{subscription.AvailableHostedServices(), 17},
+		{subscription.AvailableStorageAccounts(), 93},
+		{subscription.AvailableVirtualNetworkSites(), 49},
+		{subscription.MaxDNSServers, 9},
+	} {
+		if c.got != c.expected {
+			t.Fatalf("Expected %d but got %d", c.expected, c.got)
+		}
+	}
+}
+
+func Test_CheckCoreQuota(t *testing.T) {
+	subscription := Subscription{MaxCoreCount: 20, CurrentCoreCount: 14}
+	sizes := []virtualmachine.RoleSize{
+		{Name: "Small", Cores: 1},
+		{Name: "Large", Cores: 4},
+		{Name: "ExtraLarge", Cores: 8},
+	}
+
+	if err := CheckCoreQuota(subscription, sizes, virtualmachine.Role{RoleSize: "Large"}, virtualmachine.Role{}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := CheckCoreQuota(subscription, sizes, virtualmachine.Role{RoleSize: "ExtraLarge"})
+	if expected := (CoreQuotaExceededError{Required: 8, Available: 6}); err != expected {
+		t.Fatalf("Expected %v but got %v", expected, err)
+	}
+
+	if err := CheckCoreQuota(subscription, sizes, virtualmachine.Role{RoleName: "vm", RoleSize: "Huge"}); err == nil {
+		t.Fatal("Expected an error for an unknown role size")
+	}
+}
+
+func Test_ListOperationsResponse_Unmarshal(t *testing.T) {
+	response := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<SubscriptionOperationCollection xmlns="http://schemas.microsoft.com/windowsazure">
+  <SubscriptionOperations>
+    <SubscriptionOperation>
+      <OperationId>operation-id</OperationId>
+      <OperationObjectId>/subscription-id/services/hostedservices/service</OperationObjectId>
+      <OperationName>DeleteRole</OperationName>
+      <OperationParameters xmlns:d2p1="http://schemas.datacontract.org/2004/07/Microsoft.WindowsAzure.ServiceManagementAPI">
+        <OperationParameter>
+          <d2p1:Name>roleName</d2p1:Name>
+          <d2p1:Value>vm</d2p1:Value>
+        </OperationParameter>
+      </OperationParameters>
+      <OperationCaller>
+        <UsedServiceManagementApi>true</UsedServiceManagementApi>
+        <SubscriptionCertificateThumbprint>thumbprint</SubscriptionCertificateThumbprint>
+        <ClientIP>10.0.0.1</ClientIP>
+      </OperationCaller>
+      <OperationStatus>
+        <ID>operation-id</ID>
+        <Status>Failed</Status>
+        <HttpStatusCode>404</HttpStatusCode>
+        <Error>
+          <Code>ResourceNotFound</Code>
+          <Message>message</Message>
+        </Error>
+      </OperationStatus>
+      <OperationStartedTime>2015-10-21T07:28:00Z</OperationStartedTime>
+      <OperationCompletedTime>2015-10-21T07:29:00Z</OperationCompletedTime>
+    </SubscriptionOperation>
+  </SubscriptionOperations>
+  <ContinuationToken>token</ContinuationToken>
+</SubscriptionOperationCollection>`)
+
+	var operations ListOperationsResponse
+	if err := xml.Unmarshal(response, &operations); err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := "token"; operations.ContinuationToken != expected {
+		t.Fatalf("Expected %q but got %q", expected, operations.ContinuationToken)
+	}
+	if len(operations.Operations) != 1 {
+		t.Fatalf("Expected 1 operation but got %d", len(operations.Operations))
+	}
+	op := operations.Operations[0]
+	if expected := "DeleteRole"; op.Name != expected {
+		t.Fatalf("Expected %q but got %q", expected, op.Name)
+	}
+	if expected := []OperationParameter{{Name: "roleName", Value: "vm"}}; len(op.Parameters) != 1 || op.Parameters[0] != expected[0] {
+		t.Fatalf("Expected %v but got %v", expected, op.Parameters)
+	}
+	if !op.Caller.UsedServiceManagementAPI || op.Caller.ClientIP != "10.0.0.1" {
+		t.Fatalf("Unexpected caller: %+v", op.Caller)
+	}
+	if op.Status.Status != management.OperationStatusFailed || op.Status.HTTPStatusCode != 404 ||
+		!management.IsResourceNotFoundError(*op.Status.Error) {
+		t.Fatalf("Unexpected status: %+v", op.Status)
+	}
+}
+
+// requestRecorder is a management.Client which records the URLs of GET
+// requests and responds to them with an empty body.
+type requestRecorder struct {
+	management.Client
+	urls []string
+}
+
+func (r *requestRecorder) SendAzureGetRequest(url string) ([]byte, error) {
+	r.urls = append(r.urls, url)
+	return []byte(`<SubscriptionOperationCollection xmlns="http://schemas.microsoft.com/windowsazure"/>`), nil
+}
+
+func Test_ListOperations_Query(t *testing.T) {
+	r := &requestRecorder{}
+	_, err := NewClient(r).ListOperations(ListOperationsParameters{
+		StartTime:             time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC),
+		EndTime:               time.Date(2015, 10, 22, 0, 0, 0, 0, time.FixedZone("", 3600)),
+		ObjectIDFilter:        "/subscription-id/services/hostedservices/service",
+		OperationResultFilter: management.OperationStatusSucceeded,
+		ContinuationToken:     "token",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(r.urls[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := url.Values{
+		"StartTime":             {"2015-10-21T07:28:00Z"},
+		"EndTime":               {"2015-10-21T23:00:00Z"},
+		"ObjectIdFilter":        {"/subscription-id/services/hostedservices/service"},
+		"OperationResultFilter": {"Succeeded"},
+		"ContinuationToken":     {"token"},
+	}
+	if u.Path != "operations" || u.Query().Encode() != expected.Encode() {
+		t.Fatalf("Unexpected request URL %q", r.urls[0])
+	}
+
+	if _, err := NewClient(r).ListOperations(ListOperationsParameters{}); err == nil {
+		t.Fatal("Expected an error without a time range")
+	}
+}
@@ -0,0 +1,246 @@
+// Package trafficmanager provides a client for Traffic Manager profiles
+// and definitions.
+package trafficmanager
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+const (
+	azureProfilesURL           = "services/WATM/profiles"
+	azureProfileURL            = "services/WATM/profiles/%s"
+	azureDefinitionsURL        = "services/WATM/profiles/%s/definitions"
+	azureDefinitionURL         = "services/WATM/profiles/%s/definitions/%d"
+	azureDomainAvailabilityURL = "services/WATM/operations/isavailable/%s"
+
+	domainNameSuffix = ".trafficmanager.net"
+
+	errParamNotSpecified = "Parameter %s is not specified."
+)
+
+//NewClient is used to instantiate a new TrafficManagerClient from an Azure client
+func NewClient(client management.Client) TrafficManagerClient {
+	return TrafficManagerClient{client: client}
+}
+
+// CreateProfile creates a profile for the given domain name, which must end
+// in trafficmanager.net. The profile directs no traffic until a definition
+// is created for it.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758254.aspx
+func (c TrafficManagerClient) CreateProfile(profileName, domainName string) error {
+	if profileName == "" {
+		return fmt.Errorf(errParamNotSpecified, "profileName")
+	}
+	if domainName == "" {
+		return fmt.Errorf(errParamNotSpecified, "domainName")
+	}
+	if !strings.HasSuffix(domainName, domainNameSuffix) {
+		return fmt.Errorf("Domain name %s does not end in %s", domainName, domainNameSuffix)
+	}
+
+	req, err := xml.Marshal(createProfileParameters{
+		DomainName: domainName,
+		Name:       profileName,
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = c.client.SendAzurePostRequest(azureProfilesURL, req) // not a long running operation
+	return err
+}
+
+// ListProfiles returns the profiles of the subscription.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758249.aspx
+func (c TrafficManagerClient) ListProfiles() (ListProfilesResponse, error) {
+	var l ListProfilesResponse
+	response, err := c.client.SendAzureGetRequest(azureProfilesURL)
+	if err != nil {
+		return l, err
+	}
+
+	err = xml.Unmarshal(response, &l)
+	return l, err
+}
+
+// GetProfile returns a profile.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758248.aspx
+func (c TrafficManagerClient) GetProfile(profileName string) (Profile, error) {
+	var p Profile
+	if profileName == "" {
+		return p, fmt.Errorf(errParamNotSpecified, "profileName")
+	}
+
+	requestURL := fmt.Sprintf(azureProfileURL, profileName)
+	response, err := c.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return p, err
+	}
+
+	err = xml.Unmarshal(response, &p)
+	return p, err
+}
+
+// UpdateProfile enables or disables a profile and selects the version of
+// its definition to use.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758250.aspx
+func (c TrafficManagerClient) UpdateProfile(profileName string, params UpdateProfileParameters) error {
+	if profileName == "" {
+		return fmt.Errorf(errParamNotSpecified, "profileName")
+	}
+	if params.Status == "" {
+		return fmt.Errorf(errParamNotSpecified, "Status")
+	}
+
+	req, err := xml.Marshal(params)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureProfileURL, profileName)
+	_, err = c.client.SendAzurePutRequest(requestURL, "", req) // not a long running operation
+	return err
+}
+
+// DeleteProfile deletes a profile and its definitions.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758256.aspx
+func (c TrafficManagerClient) DeleteProfile(profileName string) error {
+	if profileName == "" {
+		return fmt.Errorf(errParamNotSpecified, "profileName")
+	}
+
+	requestURL := fmt.Sprintf(azureProfileURL, profileName)
+	_, err := c.client.SendAzureDeleteRequest(requestURL) // not a long running operation
+	return err
+}
+
+// CreateDefinition creates the definition of a profile, replacing the
+// current one, and enables the profile with it. This is also how the
+// endpoints, monitors or load-balancing method of a profile are updated.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758257.aspx
+func (c TrafficManagerClient) CreateDefinition(profileName string, definition Definition) error {
+	if profileName == "" {
+		return fmt.Errorf(errParamNotSpecified, "profileName")
+	}
+	if err := validateDefinition(definition); err != nil {
+		return err
+	}
+
+	// status, version and monitor status are assigned by the service
+	definition.Status, definition.Version = "", 0
+	definition.Policy.MonitorStatus = ""
+	endpoints := make([]Endpoint, len(definition.Policy.Endpoints))
+	for i, e := range definition.Policy.Endpoints {
+		e.MonitorStatus = ""
+		endpoints[i] = e
+	}
+	definition.Policy.Endpoints = endpoints
+
+	req, err := xml.Marshal(definition)
+	if err != nil {
+		return err
+	}
+
+	requestURL := fmt.Sprintf(azureDefinitionsURL, profileName)
+	_, err = c.client.SendAzurePostRequest(requestURL, req) // not a long running operation
+	return err
+}
+
+// ListDefinitions returns the definitions of a profile.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758251.aspx
+func (c TrafficManagerClient) ListDefinitions(profileName string) (ListDefinitionsResponse, error) {
+	var l ListDefinitionsResponse
+	if profileName == "" {
+		return l, fmt.Errorf(errParamNotSpecified, "profileName")
+	}
+
+	requestURL := fmt.Sprintf(azureDefinitionsURL, profileName)
+	response, err := c.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return l, err
+	}
+
+	err = xml.Unmarshal(response, &l)
+	return l, err
+}
+
+// GetDefinition returns a version of the definition of a profile, including
+// the health of its endpoints.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758247.aspx
+func (c TrafficManagerClient) GetDefinition(profileName string, version int) (Definition, error) {
+	var d Definition
+	if profileName == "" {
+		return d, fmt.Errorf(errParamNotSpecified, "profileName")
+	}
+
+	requestURL := fmt.Sprintf(azureDefinitionURL, profileName, version)
+	response, err := c.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return d, err
+	}
+
+	err = xml.Unmarshal(response, &d)
+	return d, err
+}
+
+// CheckDomainNameAvailability checks whether a domain name, with or without
+// the trafficmanager.net suffix, can be used for a new profile.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn510368.aspx
+func (c TrafficManagerClient) CheckDomainNameAvailability(domainName string) (AvailabilityResponse, error) {
+	var r AvailabilityResponse
+	if domainName == "" {
+		return r, fmt.Errorf(errParamNotSpecified, "domainName")
+	}
+	if !strings.HasSuffix(domainName, domainNameSuffix) {
+		domainName += domainNameSuffix
+	}
+
+	requestURL := fmt.Sprintf(azureDomainAvailabilityURL, domainName)
+	response, err := c.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return r, err
+	}
+
+	err = xml.Unmarshal(response, &r)
+	return r, err
+}
+
+// validateDefinition checks the parts of a definition the service requires.
+func validateDefinition(d Definition) error {
+	switch d.Policy.LoadBalancingMethod {
+	case LoadBalancingMethodPerformance, LoadBalancingMethodFailover, LoadBalancingMethodRoundRobin:
+	case "":
+		return fmt.Errorf(errParamNotSpecified, "Policy.LoadBalancingMethod")
+	default:
+		return fmt.Errorf("Unknown load balancing method: %s", d.Policy.LoadBalancingMethod)
+	}
+	if len(d.Monitors) != 1 {
+		return fmt.Errorf("A definition requires exactly one monitor, got %d", len(d.Monitors))
+	}
+	if len(d.Policy.Endpoints) == 0 {
+		return fmt.Errorf(errParamNotSpecified, "Policy.Endpoints")
+	}
+	for _, e := range d.Policy.Endpoints {
+		if e.DomainName == "" {
+			return fmt.Errorf(errParamNotSpecified, "Endpoint.DomainName")
+		}
+		if e.Location == "" && d.Policy.LoadBalancingMethod == LoadBalancingMethodPerformance &&
+			(e.Type == EndpointTypeAny || e.Type == EndpointTypeTrafficManager) {
+			return fmt.Errorf("Endpoint %s requires a location for performance load balancing", e.DomainName)
+		}
+	}
+	return nil
+}
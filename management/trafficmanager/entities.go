@@ -0,0 +1,215 @@
+package trafficmanager
+
+import (
+	"encoding/xml"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+//TrafficManagerClient is used to perform operations on Azure Traffic Manager profiles
+type TrafficManagerClient struct {
+	client management.Client
+}
+
+type createProfileParameters struct {
+	XMLName    xml.Name `xml:"http://schemas.microsoft.com/windowsazure Profile"`
+	DomainName string
+	Name       string
+}
+
+// ListProfilesResponse contains the Traffic Manager profiles of the
+// subscription.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758249.aspx
+type ListProfilesResponse struct {
+	XMLName  xml.Name  `xml:"http://schemas.microsoft.com/windowsazure Profiles"`
+	Profiles []Profile `xml:"Profile"`
+}
+
+// Profile is a Traffic Manager profile, which maps a domain name in
+// trafficmanager.net to the endpoints of its enabled definition.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758248.aspx
+type Profile struct {
+	XMLName       xml.Name `xml:"http://schemas.microsoft.com/windowsazure Profile"`
+	DomainName    string
+	Name          string
+	Status        Status
+	StatusDetails ProfileStatusDetails
+	Definitions   []DefinitionStatus `xml:"Definitions>Definition"`
+}
+
+// ProfileStatusDetails holds the version of the definition a profile uses.
+type ProfileStatusDetails struct {
+	EnabledVersion int
+}
+
+// DefinitionStatus is the status of a version of the definition of a
+// profile.
+type DefinitionStatus struct {
+	Status  Status
+	Version int
+}
+
+// Status is the status of a profile, a definition or an endpoint.
+type Status string
+
+const (
+	StatusEnabled  = Status("Enabled")
+	StatusDisabled = Status("Disabled")
+)
+
+// UpdateProfileParameters enables or disables a profile and selects the
+// version of its definition to use.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758250.aspx
+type UpdateProfileParameters struct {
+	XMLName       xml.Name `xml:"http://schemas.microsoft.com/windowsazure Profile"`
+	Status        Status
+	StatusDetails ProfileStatusDetails
+}
+
+// ListDefinitionsResponse contains the definitions of a profile.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758251.aspx
+type ListDefinitionsResponse struct {
+	XMLName     xml.Name     `xml:"http://schemas.microsoft.com/windowsazure Definitions"`
+	Definitions []Definition `xml:"Definition"`
+}
+
+// Definition describes how a profile balances DNS queries across its
+// endpoints and how the endpoints are monitored. Creating a definition
+// replaces the current one of the profile.
+//
+// https://msdn.microsoft.com/en-us/library/azure/hh758257.aspx
+type Definition struct {
+	XMLName    xml.Name   `xml:"http://schemas.microsoft.com/windowsazure Definition"`
+	DNSOptions DNSOptions `xml:"DnsOptions"`
+	Status     Status     `xml:",omitempty"`
+	Version    int        `xml:",omitempty"`
+	Monitors   []Monitor  `xml:"Monitors>Monitor"`
+	Policy     Policy
+}
+
+// DNSOptions controls how long DNS resolvers cache the answers of a
+// profile.
+type DNSOptions struct {
+	TimeToLiveInSeconds int
+}
+
+// Monitor describes how the health of the endpoints is probed.
+type Monitor struct {
+	IntervalInSeconds         int
+	TimeoutInSeconds          int
+	ToleratedNumberOfFailures int
+	Protocol                  MonitorProtocol
+	Port                      int
+	HTTPOptions               HTTPOptions `xml:"HttpOptions"`
+}
+
+// MonitorProtocol is the protocol endpoints are probed with.
+type MonitorProtocol string
+
+const (
+	MonitorProtocolHTTP  = MonitorProtocol("HTTP")
+	MonitorProtocolHTTPS = MonitorProtocol("HTTPS")
+)
+
+// HTTPOptions is the request a monitor sends to probe an endpoint and the
+// status code it expects from a healthy one.
+type HTTPOptions struct {
+	Verb               string
+	RelativePath       string
+	ExpectedStatusCode int
+}
+
+// DefaultMonitor returns a monitor which probes the root path of the
+// endpoints over HTTP on port 80 every 30 seconds.
+func DefaultMonitor() Monitor {
+	return Monitor{
+		IntervalInSeconds:         30,
+		TimeoutInSeconds:          10,
+		ToleratedNumberOfFailures: 3,
+		Protocol:                  MonitorProtocolHTTP,
+		Port:                      80,
+		HTTPOptions: HTTPOptions{
+			Verb:               "GET",
+			RelativePath:       "/",
+			ExpectedStatusCode: 200,
+		},
+	}
+}
+
+// Policy is the load-balancing method of a definition and its endpoints.
+type Policy struct {
+	LoadBalancingMethod LoadBalancingMethod
+	Endpoints           []Endpoint    `xml:"Endpoints>Endpoint"`
+	MonitorStatus       MonitorStatus `xml:",omitempty"`
+}
+
+// LoadBalancingMethod determines which endpoint DNS queries are answered
+// with.
+type LoadBalancingMethod string
+
+const (
+	// LoadBalancingMethodPerformance answers with the endpoint closest to
+	// the client.
+	LoadBalancingMethodPerformance = LoadBalancingMethod("Performance")
+	// LoadBalancingMethodFailover answers with the first healthy endpoint
+	// in the order they are listed.
+	LoadBalancingMethodFailover = LoadBalancingMethod("Failover")
+	// LoadBalancingMethodRoundRobin distributes answers across the healthy
+	// endpoints, in proportion to their weights.
+	LoadBalancingMethodRoundRobin = LoadBalancingMethod("RoundRobin")
+)
+
+// Endpoint is a service which a profile directs traffic to.
+type Endpoint struct {
+	DomainName        string
+	Status            Status
+	Type              EndpointType  `xml:",omitempty"`
+	Location          string        `xml:",omitempty"`
+	MinChildEndpoints int           `xml:",omitempty"`
+	Weight            int           `xml:",omitempty"`
+	MonitorStatus     MonitorStatus `xml:",omitempty"`
+}
+
+// EndpointType is the kind of service an endpoint refers to.
+type EndpointType string
+
+const (
+	EndpointTypeCloudService   = EndpointType("CloudService")
+	EndpointTypeAzureWebsite   = EndpointType("AzureWebsite")
+	EndpointTypeAny            = EndpointType("Any")
+	EndpointTypeTrafficManager = EndpointType("TrafficManager")
+)
+
+// CloudServiceEndpoint returns an enabled endpoint for the production
+// deployment of a cloud service, as named in the hostedservice package.
+func CloudServiceEndpoint(serviceName string) Endpoint {
+	return Endpoint{
+		DomainName: serviceName + ".cloudapp.net",
+		Status:     StatusEnabled,
+		Type:       EndpointTypeCloudService,
+	}
+}
+
+// MonitorStatus is the health of a definition or an endpoint as reported by
+// its monitors.
+type MonitorStatus string
+
+const (
+	MonitorStatusOnline           = MonitorStatus("Online")
+	MonitorStatusDegraded         = MonitorStatus("Degraded")
+	MonitorStatusInactive         = MonitorStatus("Inactive")
+	MonitorStatusDisabled         = MonitorStatus("Disabled")
+	MonitorStatusStopped          = MonitorStatus("Stopped")
+	MonitorStatusCheckingEndpoint = MonitorStatus("CheckingEndpoint")
+)
+
+// AvailabilityResponse tells whether a domain name can be used for a new
+// profile.
+type AvailabilityResponse struct {
+	XMLName xml.Name `xml:"http://schemas.microsoft.com/windowsazure AvailabilityResponse"`
+	Result  bool
+}
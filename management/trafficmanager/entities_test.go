@@ -0,0 +1,166 @@
+package trafficmanager
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func Test_ListProfilesResponse_Unmarshal(t *testing.T) {
+	response := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<Profiles xmlns="http://schemas.microsoft.com/windowsazure">
+  <Profile>
+    <DomainName>myapp.trafficmanager.net</DomainName>
+    <Name>myapp</Name>
+    <Status>Enabled</Status>
+    <StatusDetails>
+      <EnabledVersion>1</EnabledVersion>
+    </StatusDetails>
+    <Definitions>
+      <Definition>
+        <Status>Enabled</Status>
+        <Version>1</Version>
+      </Definition>
+    </Definitions>
+  </Profile>
+</Profiles>`)
+
+	var list ListProfilesResponse
+	if err := xml.Unmarshal(response, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Profiles) != 1 {
+		t.Fatalf("Expected 1 profile but got %d", len(list.Profiles))
+	}
+
+	p := list.Profiles[0]
+	if expected := "myapp.trafficmanager.net"; p.DomainName != expected {
+		t.Fatalf("Expected %q but got %q", expected, p.DomainName)
+	}
+	if p.Status != StatusEnabled || p.StatusDetails.EnabledVersion != 1 {
+		t.Fatalf("Unexpected profile status: %+v", p)
+	}
+	if len(p.Definitions) != 1 || p.Definitions[0].Version != 1 {
+		t.Fatalf("Unexpected definitions: %+v", p.Definitions)
+	}
+}
+
+func Test_Definition_Unmarshal(t *testing.T) {
+	response := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<Definition xmlns="http://schemas.microsoft.com/windowsazure">
+  <DnsOptions>
+    <TimeToLiveInSeconds>300</TimeToLiveInSeconds>
+  </DnsOptions>
+  <Status>Enabled</Status>
+  <Version>1</Version>
+  <Monitors>
+    <Monitor>
+      <IntervalInSeconds>30</IntervalInSeconds>
+      <TimeoutInSeconds>10</TimeoutInSeconds>
+      <ToleratedNumberOfFailures>3</ToleratedNumberOfFailures>
+      <Protocol>HTTP</Protocol>
+      <Port>80</Port>
+      <HttpOptions>
+        <Verb>GET</Verb>
+        <RelativePath>/health</RelativePath>
+        <ExpectedStatusCode>200</ExpectedStatusCode>
+      </HttpOptions>
+    </Monitor>
+  </Monitors>
+  <Policy>
+    <LoadBalancingMethod>Failover</LoadBalancingMethod>
+    <Endpoints>
+      <Endpoint>
+        <DomainName>myapp-west.cloudapp.net</DomainName>
+        <Status>Enabled</Status>
+        <Type>CloudService</Type>
+        <MonitorStatus>Online</MonitorStatus>
+      </Endpoint>
+      <Endpoint>
+        <DomainName>myapp-east.cloudapp.net</DomainName>
+        <Status>Enabled</Status>
+        <Type>CloudService</Type>
+        <MonitorStatus>Degraded</MonitorStatus>
+      </Endpoint>
+    </Endpoints>
+    <MonitorStatus>Online</MonitorStatus>
+  </Policy>
+</Definition>`)
+
+	var d Definition
+	if err := xml.Unmarshal(response, &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.DNSOptions.TimeToLiveInSeconds != 300 || d.Version != 1 {
+		t.Fatalf("Unexpected definition: %+v", d)
+	}
+	if len(d.Monitors) != 1 || d.Monitors[0].HTTPOptions.RelativePath != "/health" {
+		t.Fatalf("Unexpected monitors: %+v", d.Monitors)
+	}
+	if d.Policy.LoadBalancingMethod != LoadBalancingMethodFailover || d.Policy.MonitorStatus != MonitorStatusOnline {
+		t.Fatalf("Unexpected policy: %+v", d.Policy)
+	}
+	if len(d.Policy.Endpoints) != 2 || d.Policy.Endpoints[1].MonitorStatus != MonitorStatusDegraded {
+		t.Fatalf("Unexpected endpoints: %+v", d.Policy.Endpoints)
+	}
+}
+
+func Test_Definition_Marshal(t *testing.T) {
+	d := Definition{
+		DNSOptions: DNSOptions{TimeToLiveInSeconds: 300},
+		Monitors:   []Monitor{DefaultMonitor()},
+		Policy: Policy{
+			LoadBalancingMethod: LoadBalancingMethodRoundRobin,
+			Endpoints:           []Endpoint{CloudServiceEndpoint("myapp-west")},
+		},
+	}
+
+	data, err := xml.Marshal(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<Definition xmlns="http://schemas.microsoft.com/windowsazure">` +
+		`<DnsOptions><TimeToLiveInSeconds>300</TimeToLiveInSeconds></DnsOptions>` +
+		`<Monitors><Monitor><IntervalInSeconds>30</IntervalInSeconds><TimeoutInSeconds>10</TimeoutInSeconds>` +
+		`<ToleratedNumberOfFailures>3</ToleratedNumberOfFailures><Protocol>HTTP</Protocol><Port>80</Port>` +
+		`<HttpOptions><Verb>GET</Verb><RelativePath>/</RelativePath><ExpectedStatusCode>200</ExpectedStatusCode></HttpOptions>` +
+		`</Monitor></Monitors>` +
+		`<Policy><LoadBalancingMethod>RoundRobin</LoadBalancingMethod><Endpoints><Endpoint>` +
+		`<DomainName>myapp-west.cloudapp.net</DomainName><Status>Enabled</Status><Type>CloudService</Type>` +
+		`</Endpoint></Endpoints></Policy></Definition>`
+	if string(data) != expected {
+		t.Fatalf("Expected %q but got %q", expected, string(data))
+	}
+}
+
+func Test_validateDefinition(t *testing.T) {
+	valid := Definition{
+		Monitors: []Monitor{DefaultMonitor()},
+		Policy: Policy{
+			LoadBalancingMethod: LoadBalancingMethodPerformance,
+			Endpoints:           []Endpoint{CloudServiceEndpoint("myapp")},
+		},
+	}
+	if err := validateDefinition(valid); err != nil {
+		t.Fatalf("Expected valid definition but got %v", err)
+	}
+
+	noMonitor := valid
+	noMonitor.Monitors = nil
+	noMethod := valid
+	noMethod.Policy.LoadBalancingMethod = ""
+	noEndpoints := valid
+	noEndpoints.Policy.Endpoints = nil
+	externalWithoutLocation := valid
+	externalWithoutLocation.Policy.Endpoints = []Endpoint{{DomainName: "www.example.com", Status: StatusEnabled, Type: EndpointTypeAny}}
+
+	for name, d := range map[string]Definition{
+		"no monitor":                noMonitor,
+		"no load balancing method":  noMethod,
+		"no endpoints":              noEndpoints,
+		"external without location": externalWithoutLocation,
+	} {
+		if err := validateDefinition(d); err == nil {
+			t.Fatalf("Expected an error for a definition with %s", name)
+		}
+	}
+}
@@ -9,6 +9,8 @@ import (
 
 const (
 	azureNetworkConfigurationURL = "services/networking/media"
+
+	errParamNotSpecified = "Parameter %s is not specified."
 )
 
 // NewClient is used to return new VirtualNetworkClient instance
@@ -0,0 +1,398 @@
+package virtualnetwork
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+// maxConfigurationAttempts is the number of times UpdateVirtualNetworkConfiguration
+// reads and modifies the configuration before giving up.
+const maxConfigurationAttempts = 5
+
+// configurationRetryDelay is the time UpdateVirtualNetworkConfiguration waits
+// before reading the configuration again, giving concurrent writers and
+// network operations time to complete.
+var configurationRetryDelay = 10 * time.Second
+
+// ErrConfigurationChanged is returned by UpdateVirtualNetworkConfiguration
+// and the helpers built on it if the network configuration kept changing
+// between reading and writing it.
+var ErrConfigurationChanged = errors.New("virtualnetwork: network configuration changed concurrently")
+
+// UpdateVirtualNetworkConfiguration reads the network configuration of the
+// subscription, applies update to it, validates the result and writes it
+// back. Only problems introduced by update are reported, so that problems
+// the configuration already had do not block unrelated changes. If the
+// configuration is changed by someone else before it is written, or Azure
+// reports a conflicting network operation, the update is applied again to
+// the new configuration after a delay. Errors returned by update are
+// returned as is.
+//
+// The Azure API does not support conditional writes, so this narrows but
+// does not close the window in which concurrent changes are lost.
+func (c VirtualNetworkClient) UpdateVirtualNetworkConfiguration(update func(*NetworkConfiguration) error) (management.OperationID, error) {
+	for attempt := 0; attempt < maxConfigurationAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(configurationRetryDelay)
+		}
+
+		original, err := c.getConfigurationDocument()
+		if err != nil {
+			return "", err
+		}
+
+		networkConfiguration := c.NewNetworkConfiguration()
+		if len(original) > 0 {
+			if err := xml.Unmarshal(original, &networkConfiguration); err != nil {
+				return "", err
+			}
+		}
+		existing := make(map[string]bool)
+		for _, err := range validationErrors(networkConfiguration) {
+			existing[err.Error()] = true
+		}
+		if err := update(&networkConfiguration); err != nil {
+			return "", err
+		}
+		for _, err := range validationErrors(networkConfiguration) {
+			if !existing[err.Error()] {
+				return "", err
+			}
+		}
+
+		current, err := c.getConfigurationDocument()
+		if err != nil {
+			return "", err
+		}
+		if !bytes.Equal(original, current) {
+			continue
+		}
+
+		id, err := c.SetVirtualNetworkConfiguration(networkConfiguration)
+		if azureErr, ok := err.(management.AzureError); ok && azureErr.Code == "ConflictError" {
+			continue
+		}
+		return id, err
+	}
+	return "", ErrConfigurationChanged
+}
+
+// getConfigurationDocument returns the network configuration document of
+// the subscription, which is empty if it has none.
+func (c VirtualNetworkClient) getConfigurationDocument() ([]byte, error) {
+	response, err := c.client.SendAzureGetRequest(azureNetworkConfigurationURL)
+	if management.IsResourceNotFoundError(err) {
+		return nil, nil
+	}
+	return response, err
+}
+
+// AddVirtualNetworkSite adds a virtual network site to the network
+// configuration. Its address space must not overlap with the address spaces
+// of the other virtual network sites and its subnets must be within it.
+func (c VirtualNetworkClient) AddVirtualNetworkSite(site VirtualNetworkSite) (management.OperationID, error) {
+	if site.Name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Name")
+	}
+	return c.UpdateVirtualNetworkConfiguration(func(n *NetworkConfiguration) error {
+		if n.virtualNetworkSite(site.Name) != nil {
+			return fmt.Errorf("Virtual network site %s already exists", site.Name)
+		}
+		n.Configuration.VirtualNetworkSites = append(n.Configuration.VirtualNetworkSites, site)
+		return nil
+	})
+}
+
+// RemoveVirtualNetworkSite removes the virtual network site with the given
+// name from the network configuration.
+func (c VirtualNetworkClient) RemoveVirtualNetworkSite(name string) (management.OperationID, error) {
+	if name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "name")
+	}
+	return c.UpdateVirtualNetworkConfiguration(func(n *NetworkConfiguration) error {
+		sites := n.Configuration.VirtualNetworkSites
+		for i := range sites {
+			if sites[i].Name == name {
+				n.Configuration.VirtualNetworkSites = append(sites[:i], sites[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("Virtual network site %s does not exist", name)
+	})
+}
+
+// AddSubnet adds a subnet to the virtual network site with the given name.
+// Its address prefix must be within the address space of the site and must
+// not overlap with the other subnets of the site.
+func (c VirtualNetworkClient) AddSubnet(siteName string, subnet Subnet) (management.OperationID, error) {
+	if siteName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "siteName")
+	}
+	if subnet.Name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Name")
+	}
+	return c.UpdateVirtualNetworkConfiguration(func(n *NetworkConfiguration) error {
+		site := n.virtualNetworkSite(siteName)
+		if site == nil {
+			return fmt.Errorf("Virtual network site %s does not exist", siteName)
+		}
+		for _, s := range site.Subnets {
+			if s.Name == subnet.Name {
+				return fmt.Errorf("Subnet %s already exists in virtual network site %s", subnet.Name, siteName)
+			}
+		}
+		site.Subnets = append(site.Subnets, subnet)
+		return nil
+	})
+}
+
+// RemoveSubnet removes the subnet with the given name from the virtual
+// network site with the given name.
+func (c VirtualNetworkClient) RemoveSubnet(siteName, subnetName string) (management.OperationID, error) {
+	if siteName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "siteName")
+	}
+	if subnetName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "subnetName")
+	}
+	return c.UpdateVirtualNetworkConfiguration(func(n *NetworkConfiguration) error {
+		site := n.virtualNetworkSite(siteName)
+		if site == nil {
+			return fmt.Errorf("Virtual network site %s does not exist", siteName)
+		}
+		for i := range site.Subnets {
+			if site.Subnets[i].Name == subnetName {
+				site.Subnets = append(site.Subnets[:i], site.Subnets[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("Subnet %s does not exist in virtual network site %s", subnetName, siteName)
+	})
+}
+
+// AddDNSServer adds a DNS server to the network configuration, which can
+// then be referenced by virtual network sites.
+func (c VirtualNetworkClient) AddDNSServer(server DNSServer) (management.OperationID, error) {
+	if server.Name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Name")
+	}
+	if net.ParseIP(server.IPAddress) == nil {
+		return "", fmt.Errorf("Invalid IP address of DNS server %s: %q", server.Name, server.IPAddress)
+	}
+	return c.UpdateVirtualNetworkConfiguration(func(n *NetworkConfiguration) error {
+		for _, s := range n.Configuration.DNS.DNSServers {
+			if s.Name == server.Name {
+				return fmt.Errorf("DNS server %s already exists", server.Name)
+			}
+		}
+		n.Configuration.DNS.DNSServers = append(n.Configuration.DNS.DNSServers, server)
+		return nil
+	})
+}
+
+// RemoveDNSServer removes the DNS server with the given name from the
+// network configuration. It must not be referenced by any virtual network
+// site.
+func (c VirtualNetworkClient) RemoveDNSServer(name string) (management.OperationID, error) {
+	if name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "name")
+	}
+	return c.UpdateVirtualNetworkConfiguration(func(n *NetworkConfiguration) error {
+		for _, site := range n.Configuration.VirtualNetworkSites {
+			for _, ref := range site.DNSServersRef {
+				if ref.Name == name {
+					return fmt.Errorf("DNS server %s is referenced by virtual network site %s", name, site.Name)
+				}
+			}
+		}
+		servers := n.Configuration.DNS.DNSServers
+		for i := range servers {
+			if servers[i].Name == name {
+				n.Configuration.DNS.DNSServers = append(servers[:i], servers[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("DNS server %s does not exist", name)
+	})
+}
+
+// AddLocalNetworkSite adds a local network site, an on-premises network
+// which virtual network sites can connect to through a VPN gateway, to the
+// network configuration. Its address space must not overlap with the
+// address spaces of the virtual network sites.
+func (c VirtualNetworkClient) AddLocalNetworkSite(site LocalNetworkSite) (management.OperationID, error) {
+	if site.Name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "Name")
+	}
+	return c.UpdateVirtualNetworkConfiguration(func(n *NetworkConfiguration) error {
+		if n.localNetworkSite(site.Name) != nil {
+			return fmt.Errorf("Local network site %s already exists", site.Name)
+		}
+		n.Configuration.LocalNetworkSites = append(n.Configuration.LocalNetworkSites, site)
+		return nil
+	})
+}
+
+// RemoveLocalNetworkSite removes the local network site with the given name
+// from the network configuration. No virtual network site may be connected
+// to it.
+func (c VirtualNetworkClient) RemoveLocalNetworkSite(name string) (management.OperationID, error) {
+	if name == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "name")
+	}
+	return c.UpdateVirtualNetworkConfiguration(func(n *NetworkConfiguration) error {
+		for _, site := range n.Configuration.VirtualNetworkSites {
+			if site.Gateway == nil {
+				continue
+			}
+			for _, ref := range site.Gateway.ConnectionsToLocalNetwork {
+				if ref.Name == name {
+					return fmt.Errorf("Local network site %s is connected to virtual network site %s", name, site.Name)
+				}
+			}
+		}
+		sites := n.Configuration.LocalNetworkSites
+		for i := range sites {
+			if sites[i].Name == name {
+				n.Configuration.LocalNetworkSites = append(sites[:i], sites[i+1:]...)
+				return nil
+			}
+		}
+		return fmt.Errorf("Local network site %s does not exist", name)
+	})
+}
+
+// ValidateNetworkConfiguration checks that the address prefixes in the
+// network configuration are valid CIDRs, that the address spaces of virtual
+// network sites do not overlap with each other or with those of local
+// network sites, that the subnets of each site are within its address space
+// and do not overlap, and that the DNS servers referenced by sites exist. It
+// returns the first problem found.
+func ValidateNetworkConfiguration(n NetworkConfiguration) error {
+	if errs := validationErrors(n); len(errs) > 0 {
+		return errs[0]
+	}
+	return nil
+}
+
+// validationErrors returns all problems ValidateNetworkConfiguration checks
+// for.
+func validationErrors(n NetworkConfiguration) []error {
+	var errs []error
+
+	dnsServers := make(map[string]bool)
+	for _, s := range n.Configuration.DNS.DNSServers {
+		dnsServers[s.Name] = true
+	}
+
+	type prefix struct {
+		owner string
+		net   *net.IPNet
+	}
+	// the address spaces of local network sites may overlap with each other,
+	// only those of virtual network sites are checked against them
+	var spaces []prefix
+	for _, site := range n.Configuration.LocalNetworkSites {
+		for _, p := range site.AddressSpace.AddressPrefix {
+			ipNet, err := parseCIDR(p, "local network site "+site.Name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			spaces = append(spaces, prefix{"local network site " + site.Name, ipNet})
+		}
+	}
+
+	for _, site := range n.Configuration.VirtualNetworkSites {
+		var siteSpace []*net.IPNet
+		for _, p := range site.AddressSpace.AddressPrefix {
+			ipNet, err := parseCIDR(p, "virtual network site "+site.Name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			for _, other := range spaces {
+				if overlaps(ipNet, other.net) {
+					errs = append(errs, fmt.Errorf("Address space %s of virtual network site %s overlaps with %s of %s", p, site.Name, other.net, other.owner))
+				}
+			}
+			spaces = append(spaces, prefix{"virtual network site " + site.Name, ipNet})
+			siteSpace = append(siteSpace, ipNet)
+		}
+
+		var subnets []prefix
+		for _, subnet := range site.Subnets {
+			ipNet, err := parseCIDR(subnet.AddressPrefix, "subnet "+subnet.Name)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			if !withinAny(ipNet, siteSpace) {
+				errs = append(errs, fmt.Errorf("Subnet %s (%s) is not within the address space of virtual network site %s", subnet.Name, subnet.AddressPrefix, site.Name))
+			}
+			for _, other := range subnets {
+				if overlaps(ipNet, other.net) {
+					errs = append(errs, fmt.Errorf("Subnet %s (%s) of virtual network site %s overlaps with %s", subnet.Name, subnet.AddressPrefix, site.Name, other.owner))
+				}
+			}
+			subnets = append(subnets, prefix{"subnet " + subnet.Name, ipNet})
+		}
+
+		for _, ref := range site.DNSServersRef {
+			if !dnsServers[ref.Name] {
+				errs = append(errs, fmt.Errorf("Virtual network site %s references unknown DNS server %s", site.Name, ref.Name))
+			}
+		}
+	}
+	return errs
+}
+
+func (n *NetworkConfiguration) virtualNetworkSite(name string) *VirtualNetworkSite {
+	for i := range n.Configuration.VirtualNetworkSites {
+		if n.Configuration.VirtualNetworkSites[i].Name == name {
+			return &n.Configuration.VirtualNetworkSites[i]
+		}
+	}
+	return nil
+}
+
+func (n *NetworkConfiguration) localNetworkSite(name string) *LocalNetworkSite {
+	for i := range n.Configuration.LocalNetworkSites {
+		if n.Configuration.LocalNetworkSites[i].Name == name {
+			return &n.Configuration.LocalNetworkSites[i]
+		}
+	}
+	return nil
+}
+
+func parseCIDR(s, owner string) (*net.IPNet, error) {
+	_, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid address prefix %q of %s: %v", s, owner, err)
+	}
+	return ipNet, nil
+}
+
+// overlaps reports whether two networks share any address, which is the
+// case if one contains the other.
+func overlaps(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// withinAny reports whether a network is contained in any of the others.
+func withinAny(n *net.IPNet, others []*net.IPNet) bool {
+	nOnes, _ := n.Mask.Size()
+	for _, o := range others {
+		oOnes, _ := o.Mask.Size()
+		if o.Contains(n.IP) && oOnes <= nOnes {
+			return true
+		}
+	}
+	return false
+}
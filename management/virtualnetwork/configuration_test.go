@@ -0,0 +1,355 @@
+package virtualnetwork
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+// configurationServer is a management.Client which keeps a network
+// configuration document. changes documents replace the current one on the
+// reads which follow the first one, emulating concurrent writers.
+type configurationServer struct {
+	management.Client
+	document  []byte
+	changes   [][]byte
+	conflicts int // number of writes which fail with a ConflictError
+	gets      int
+	puts      int
+}
+
+func (s *configurationServer) SendAzureGetRequest(url string) ([]byte, error) {
+	if s.gets++; s.gets > 1 && len(s.changes) > 0 {
+		s.document, s.changes = s.changes[0], s.changes[1:]
+	}
+	if s.document == nil {
+		return nil, management.AzureError{Code: "ResourceNotFound"}
+	}
+	return s.document, nil
+}
+
+func (s *configurationServer) SendAzurePutRequest(url, contentType string, data []byte) (management.OperationID, error) {
+	if s.conflicts > 0 {
+		s.conflicts--
+		return "", management.AzureError{Code: "ConflictError"}
+	}
+	s.puts++
+	s.document = data
+	return "id", nil
+}
+
+func (s *configurationServer) configuration(t *testing.T) NetworkConfiguration {
+	var n NetworkConfiguration
+	if err := xml.Unmarshal(s.document, &n); err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
+
+func testSite(name string, prefix string, subnets ...Subnet) VirtualNetworkSite {
+	return VirtualNetworkSite{
+		Name:         name,
+		Location:     "West US",
+		AddressSpace: AddressSpace{AddressPrefix: []string{prefix}},
+		Subnets:      subnets,
+	}
+}
+
+func Test_ValidateNetworkConfiguration(t *testing.T) {
+	onprem := []LocalNetworkSite{{Name: "onprem", AddressSpace: AddressSpace{[]string{"192.168.0.0/16", "10.2.0.0/16"}}}}
+	tests := []struct {
+		sites []VirtualNetworkSite
+		local []LocalNetworkSite
+		err   string
+	}{
+		{[]VirtualNetworkSite{
+			testSite("a", "10.0.0.0/16", Subnet{Name: "s1", AddressPrefix: "10.0.0.0/24"}, Subnet{Name: "s2", AddressPrefix: "10.0.1.0/24"}),
+			testSite("b", "10.1.0.0/16"),
+		}, nil, ""},
+		{[]VirtualNetworkSite{testSite("a", "10.0.0.0/16"), testSite("b", "10.0.128.0/17")}, nil, "overlaps"},
+		{[]VirtualNetworkSite{testSite("a", "10.0.0.0/16", Subnet{Name: "s1", AddressPrefix: "10.1.0.0/24"})}, nil, "not within"},
+		{[]VirtualNetworkSite{testSite("a", "10.0.0.0/16", Subnet{Name: "s1", AddressPrefix: "10.0.0.0/8"})}, nil, "not within"},
+		{[]VirtualNetworkSite{testSite("a", "10.0.0.0/16", Subnet{Name: "s1", AddressPrefix: "10.0.0.0/24"}, Subnet{Name: "s2", AddressPrefix: "10.0.0.128/25"})}, nil, "overlaps"},
+		{[]VirtualNetworkSite{testSite("a", "10.0.0.0/33")}, nil, "Invalid address prefix"},
+		{[]VirtualNetworkSite{{Name: "a", DNSServersRef: []DNSServerRef{{"dns"}}}}, nil, "unknown DNS server"},
+		{[]VirtualNetworkSite{testSite("a", "10.0.0.0/16")}, onprem, ""},
+		{[]VirtualNetworkSite{testSite("a", "10.2.128.0/17")}, onprem, "overlaps with 10.2.0.0/16 of local network site onprem"},
+		{nil, []LocalNetworkSite{{Name: "onprem", AddressSpace: AddressSpace{[]string{"192.168.0.0/33"}}}}, "Invalid address prefix"},
+	}
+
+	for i, test := range tests {
+		n := NetworkConfiguration{}
+		n.Configuration.VirtualNetworkSites = test.sites
+		n.Configuration.LocalNetworkSites = test.local
+		err := ValidateNetworkConfiguration(n)
+		if test.err == "" && err != nil || test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Fatalf("Test %d: expected error %q but got %v", i+1, test.err, err)
+		}
+	}
+}
+
+func Test_AddAndRemove(t *testing.T) {
+	s := &configurationServer{}
+	c := NewClient(s)
+
+	if _, err := c.AddDNSServer(DNSServer{Name: "dns", IPAddress: "10.0.0.4"}); err != nil {
+		t.Fatal(err)
+	}
+	site := testSite("vnet", "10.0.0.0/16", Subnet{Name: "s1", AddressPrefix: "10.0.0.0/24"})
+	site.DNSServersRef = []DNSServerRef{{"dns"}}
+	if _, err := c.AddVirtualNetworkSite(site); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddSubnet("vnet", Subnet{Name: "s2", AddressPrefix: "10.0.1.0/24"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.AddLocalNetworkSite(LocalNetworkSite{Name: "onprem", VPNGatewayAddress: "1.2.3.4", AddressSpace: AddressSpace{[]string{"192.168.0.0/16"}}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, err := range []error{
+		second(c.AddVirtualNetworkSite(testSite("vnet", "10.1.0.0/16"))),
+		second(c.AddVirtualNetworkSite(testSite("other", "10.0.0.0/8"))),
+		second(c.AddSubnet("vnet", Subnet{Name: "s3", AddressPrefix: "10.0.1.128/25"})),
+		second(c.AddSubnet("missing", Subnet{Name: "s3", AddressPrefix: "10.0.2.0/24"})),
+		second(c.RemoveDNSServer("dns")),
+		second(c.RemoveSubnet("vnet", "missing")),
+	} {
+		if err == nil {
+			t.Fatal("Expected an error")
+		}
+	}
+
+	n := s.configuration(t)
+	if n.XMLNs != xmlNamespace {
+		t.Fatalf("Expected namespace %q but got %q", xmlNamespace, n.XMLNs)
+	}
+	sites := n.Configuration.VirtualNetworkSites
+	if len(sites) != 1 || len(sites[0].Subnets) != 2 || sites[0].Subnets[1].Name != "s2" {
+		t.Fatalf("Unexpected virtual network sites: %+v", sites)
+	}
+	if len(n.Configuration.LocalNetworkSites) != 1 || len(n.Configuration.DNS.DNSServers) != 1 {
+		t.Fatalf("Unexpected configuration: %+v", n.Configuration)
+	}
+
+	if _, err := c.RemoveSubnet("vnet", "s1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RemoveVirtualNetworkSite("vnet"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RemoveDNSServer("dns"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.RemoveLocalNetworkSite("onprem"); err != nil {
+		t.Fatal(err)
+	}
+	n = s.configuration(t)
+	if len(n.Configuration.VirtualNetworkSites)+len(n.Configuration.LocalNetworkSites)+len(n.Configuration.DNS.DNSServers) != 0 {
+		t.Fatalf("Expected an empty configuration but got %+v", n.Configuration)
+	}
+}
+
+func Test_UpdateVirtualNetworkConfiguration_ExistingProblems(t *testing.T) {
+	n := NetworkConfiguration{XMLNs: xmlNamespace}
+	n.Configuration.VirtualNetworkSites = []VirtualNetworkSite{
+		testSite("a", "10.0.0.0/16"),
+		testSite("b", "10.0.128.0/17"),
+	}
+	document, err := xml.Marshal(n)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &configurationServer{document: document}
+	c := NewClient(s)
+
+	if _, err := c.AddDNSServer(DNSServer{Name: "dns", IPAddress: "10.0.0.4"}); err != nil {
+		t.Fatalf("Expected the existing overlap to be ignored but got %v", err)
+	}
+	if _, err := c.AddLocalNetworkSite(LocalNetworkSite{Name: "onprem", AddressSpace: AddressSpace{[]string{"10.0.0.0/24"}}}); err == nil {
+		t.Fatal("Expected the new overlap to be reported")
+	}
+	if s.puts != 1 {
+		t.Fatalf("Expected 1 write but got %d", s.puts)
+	}
+}
+
+func Test_UpdateVirtualNetworkConfiguration_Retry(t *testing.T) {
+	defer func(d time.Duration) { configurationRetryDelay = d }(configurationRetryDelay)
+	configurationRetryDelay = 10 * time.Millisecond
+
+	initial, err := xml.Marshal(NetworkConfiguration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	changed := NetworkConfiguration{}
+	changed.Configuration.VirtualNetworkSites = []VirtualNetworkSite{testSite("concurrent", "10.1.0.0/16")}
+	concurrent, err := xml.Marshal(changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the document changes after the first read, so the site is added to
+	// the concurrently written configuration
+	s := &configurationServer{document: initial, changes: [][]byte{concurrent}}
+	if _, err := NewClient(s).AddVirtualNetworkSite(testSite("vnet", "10.0.0.0/16")); err != nil {
+		t.Fatal(err)
+	}
+	if s.puts != 1 {
+		t.Fatalf("Expected 1 write but got %d", s.puts)
+	}
+	if sites := s.configuration(t).Configuration.VirtualNetworkSites; len(sites) != 2 || sites[0].Name != "concurrent" {
+		t.Fatalf("Unexpected virtual network sites: %+v", sites)
+	}
+
+	// a concurrent site with an overlapping address space is detected
+	s = &configurationServer{document: initial, changes: [][]byte{concurrent}}
+	if _, err := NewClient(s).AddVirtualNetworkSite(testSite("vnet", "10.1.0.0/24")); err == nil {
+		t.Fatal("Expected an error for an overlapping address space")
+	}
+
+	// a document which keeps changing is reported
+	s = &configurationServer{document: initial}
+	for i := 0; i < 2*maxConfigurationAttempts; i++ {
+		s.changes = append(s.changes, concurrent, initial)
+	}
+	if _, err := NewClient(s).AddVirtualNetworkSite(testSite("vnet", "10.0.0.0/16")); err != ErrConfigurationChanged {
+		t.Fatalf("Expected %v but got %v", ErrConfigurationChanged, err)
+	}
+	if s.puts != 0 {
+		t.Fatalf("Expected no write but got %d", s.puts)
+	}
+
+	// conflicting network operations are retried after a delay
+	s = &configurationServer{document: initial, conflicts: 2}
+	start := time.Now()
+	if _, err := NewClient(s).AddVirtualNetworkSite(testSite("vnet", "10.0.0.0/16")); err != nil {
+		t.Fatal(err)
+	}
+	if s.puts != 1 {
+		t.Fatalf("Expected 1 write but got %d", s.puts)
+	}
+	if elapsed := time.Since(start); elapsed < 2*configurationRetryDelay {
+		t.Fatalf("Expected retries after %v but took %v", configurationRetryDelay, elapsed)
+	}
+}
+
+func second(_ management.OperationID, err error) error {
+	return err
+}
+
+// fullConfiguration is a network configuration as returned by Azure, with
+// elements this package does not model in a subnet and a local network site.
+const fullConfiguration = `<?xml version="1.0" encoding="utf-8"?>
+<NetworkConfiguration xmlns:xsd="http://www.w3.org/2001/XMLSchema" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns="http://schemas.microsoft.com/ServiceHosting/2011/07/NetworkConfiguration">
+  <VirtualNetworkConfiguration>
+    <Dns>
+      <DnsServers>
+        <DnsServer name="dns" IPAddress="10.0.0.4" />
+      </DnsServers>
+    </Dns>
+    <LocalNetworkSites>
+      <LocalNetworkSite name="onprem">
+        <AddressSpace>
+          <AddressPrefix>192.168.0.0/16</AddressPrefix>
+        </AddressSpace>
+        <VPNGatewayAddress>1.2.3.4</VPNGatewayAddress>
+        <Unmodeled kind="test">
+          <Child>value</Child>
+        </Unmodeled>
+      </LocalNetworkSite>
+    </LocalNetworkSites>
+    <VirtualNetworkSites>
+      <VirtualNetworkSite name="vnet" Location="West US">
+        <AddressSpace>
+          <AddressPrefix>10.0.0.0/16</AddressPrefix>
+        </AddressSpace>
+        <Subnets>
+          <Subnet name="frontend">
+            <AddressPrefix>10.0.0.0/24</AddressPrefix>
+            <NetworkSecurityGroup name="nsg" />
+          </Subnet>
+          <Subnet name="GatewaySubnet">
+            <AddressPrefix>10.0.1.0/29</AddressPrefix>
+          </Subnet>
+        </Subnets>
+        <DnsServersRef>
+          <DnsServerRef name="dns" />
+        </DnsServersRef>
+        <Gateway profile="Small">
+          <VPNClientAddressPool>
+            <AddressPrefix>172.16.0.0/24</AddressPrefix>
+          </VPNClientAddressPool>
+          <ConnectionsToLocalNetwork>
+            <LocalNetworkSiteRef name="onprem">
+              <Connection type="IPsec" />
+            </LocalNetworkSiteRef>
+          </ConnectionsToLocalNetwork>
+        </Gateway>
+      </VirtualNetworkSite>
+    </VirtualNetworkSites>
+  </VirtualNetworkConfiguration>
+</NetworkConfiguration>`
+
+// xmlTokens returns the elements, attributes and text of an XML document in
+// order, ignoring namespace declarations and whitespace between elements.
+func xmlTokens(t *testing.T, document []byte) []string {
+	var tokens []string
+	d := xml.NewDecoder(bytes.NewReader(document))
+	for {
+		token, err := d.Token()
+		if err == io.EOF {
+			return tokens
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch token := token.(type) {
+		case xml.StartElement:
+			var attrs []string
+			for _, a := range token.Attr {
+				if a.Name.Space != "xmlns" && a.Name.Local != "xmlns" {
+					attrs = append(attrs, a.Name.Local+"="+a.Value)
+				}
+			}
+			sort.Strings(attrs)
+			tokens = append(tokens, fmt.Sprintf("<%s %s %v>", token.Name.Space, token.Name.Local, attrs))
+		case xml.EndElement:
+			tokens = append(tokens, "</"+token.Name.Local+">")
+		case xml.CharData:
+			if text := strings.TrimSpace(string(token)); text != "" {
+				tokens = append(tokens, text)
+			}
+		}
+	}
+}
+
+func Test_UpdateVirtualNetworkConfiguration_KeepsUnmodeledContent(t *testing.T) {
+	s := &configurationServer{document: []byte(fullConfiguration)}
+	_, err := NewClient(s).UpdateVirtualNetworkConfiguration(func(n *NetworkConfiguration) error {
+		gateway := n.Configuration.VirtualNetworkSites[0].Gateway
+		if gateway == nil || gateway.Profile != "Small" || gateway.VPNClientAddressPool == nil {
+			t.Fatalf("Unexpected gateway: %+v", gateway)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.puts != 1 {
+		t.Fatalf("Expected 1 write but got %d", s.puts)
+	}
+
+	expected, got := xmlTokens(t, []byte(fullConfiguration)), xmlTokens(t, s.document)
+	if !reflect.DeepEqual(expected, got) {
+		t.Fatalf("Expected %q but got %q", expected, got)
+	}
+}
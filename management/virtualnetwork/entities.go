@@ -17,15 +17,26 @@ type VirtualNetworkClient struct {
 
 // NetworkConfiguration represents the network configuration for an entire Azure
 // subscription.
+//
+// Elements of the configuration which are not modeled by the types of this
+// package are kept in the Unknown fields of their parents, so that reading
+// and writing back a configuration does not lose them. They are written
+// after the modeled elements of their parent.
 type NetworkConfiguration struct {
 	XMLName         xml.Name                    `xml:"NetworkConfiguration"`
 	XMLNamespaceXsd string                      `xml:"xmlns:xsd,attr"`
 	XMLNamespaceXsi string                      `xml:"xmlns:xsi,attr"`
 	XMLNs           string                      `xml:"xmlns,attr"`
 	Configuration   VirtualNetworkConfiguration `xml:"VirtualNetworkConfiguration"`
+	Unknown         []UnknownElement            `xml:",any"`
+}
 
-	// TODO: Nicer builder methods for these that abstract away the
-	// underlying structure.
+// UnknownElement is an element of the network configuration which is not
+// modeled by the types of this package.
+type UnknownElement struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	InnerXML string     `xml:",innerxml"`
 }
 
 // NewNetworkConfiguration creates a new empty NetworkConfiguration structure
@@ -50,10 +61,12 @@ type VirtualNetworkConfiguration struct {
 	DNS                 DNS                  `xml:"Dns,omitempty"`
 	LocalNetworkSites   []LocalNetworkSite   `xml:"LocalNetworkSites>LocalNetworkSite"`
 	VirtualNetworkSites []VirtualNetworkSite `xml:"VirtualNetworkSites>VirtualNetworkSite"`
+	Unknown             []UnknownElement     `xml:",any"`
 }
 
 type DNS struct {
-	DNSServers []DNSServer `xml:"DnsServers>DnsServer,omitempty"`
+	DNSServers []DNSServer      `xml:"DnsServers>DnsServer,omitempty"`
+	Unknown    []UnknownElement `xml:",any"`
 }
 
 type DNSServer struct {
@@ -67,17 +80,41 @@ type DNSServerRef struct {
 }
 
 type VirtualNetworkSite struct {
-	Name          string         `xml:"name,attr"`
-	Location      string         `xml:"Location,attr"`
-	AddressSpace  AddressSpace   `xml:"AddressSpace"`
-	Subnets       []Subnet       `xml:"Subnets>Subnet"`
-	DNSServersRef []DNSServerRef `xml:"DnsServersRef>DnsServerRef,omitempty"`
+	Name          string           `xml:"name,attr"`
+	Location      string           `xml:"Location,attr,omitempty"`
+	AffinityGroup string           `xml:"AffinityGroup,attr,omitempty"`
+	AddressSpace  AddressSpace     `xml:"AddressSpace"`
+	Subnets       []Subnet         `xml:"Subnets>Subnet"`
+	DNSServersRef []DNSServerRef   `xml:"DnsServersRef>DnsServerRef,omitempty"`
+	Gateway       *Gateway         `xml:",omitempty"`
+	Unknown       []UnknownElement `xml:",any"`
+}
+
+// Gateway describes the VPN gateway of a virtual network site: its size,
+// the addresses of point-to-site clients and the local network sites it
+// connects to.
+type Gateway struct {
+	Profile                   string                `xml:"profile,attr,omitempty"`
+	VPNClientAddressPool      *AddressSpace         `xml:",omitempty"`
+	ConnectionsToLocalNetwork []LocalNetworkSiteRef `xml:"ConnectionsToLocalNetwork>LocalNetworkSiteRef"`
+	Unknown                   []UnknownElement      `xml:",any"`
+}
+
+type LocalNetworkSiteRef struct {
+	Name       string                 `xml:"name,attr"`
+	Connection LocalNetworkConnection `xml:"Connection"`
+	Unknown    []UnknownElement       `xml:",any"`
+}
+
+type LocalNetworkConnection struct {
+	Type string `xml:"type,attr"` // IPsec
 }
 
 type LocalNetworkSite struct {
 	Name              string `xml:"name,attr"`
-	VPNGatewayAddress string
 	AddressSpace      AddressSpace
+	VPNGatewayAddress string
+	Unknown           []UnknownElement `xml:",any"`
 }
 
 type AddressSpace struct {
@@ -87,4 +124,5 @@ type AddressSpace struct {
 type Subnet struct {
 	Name          string `xml:"name,attr"`
 	AddressPrefix string
+	Unknown       []UnknownElement `xml:",any"`
 }
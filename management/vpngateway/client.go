@@ -0,0 +1,212 @@
+// Package vpngateway provides a client for the VPN gateways of Virtual
+// Networks.
+package vpngateway
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+const (
+	azureGatewayURL            = "services/networking/%s/gateway"
+	azureGatewayConnectionsURL = "services/networking/%s/gateway/connections"
+	azureGatewayConnectionURL  = "services/networking/%s/gateway/connection/%s"
+	azureSharedKeyURL          = "services/networking/%s/gateway/connection/%s/sharedkey"
+	resetSharedKeyURL          = "services/networking/%s/gateway/connection/%s/sharedkey?action=reset"
+
+	errParamNotSpecified = "Parameter %s is not specified."
+)
+
+//NewClient is used to instantiate a new VPNGatewayClient from an Azure client
+func NewClient(client management.Client) VPNGatewayClient {
+	return VPNGatewayClient{client: client}
+}
+
+// CreateGateway creates the VPN gateway of a virtual network, which must
+// have a subnet named GatewaySubnet. Provisioning a gateway can take more
+// than half an hour.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154119.aspx
+func (c VPNGatewayClient) CreateGateway(networkName string, gatewayType GatewayType) (management.OperationID, error) {
+	if networkName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "networkName")
+	}
+	if gatewayType == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "gatewayType")
+	}
+
+	req, err := xml.Marshal(createGatewayParameters{GatewayType: gatewayType})
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(azureGatewayURL, networkName)
+	return c.client.SendAzurePostRequest(requestURL, req)
+}
+
+// GetGateway returns the VPN gateway of a virtual network.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154109.aspx
+func (c VPNGatewayClient) GetGateway(networkName string) (Gateway, error) {
+	var gateway Gateway
+	if networkName == "" {
+		return gateway, fmt.Errorf(errParamNotSpecified, "networkName")
+	}
+
+	requestURL := fmt.Sprintf(azureGatewayURL, networkName)
+	response, err := c.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return gateway, err
+	}
+
+	err = xml.Unmarshal(response, &gateway)
+	return gateway, err
+}
+
+// DeleteGateway deletes the VPN gateway of a virtual network.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154129.aspx
+func (c VPNGatewayClient) DeleteGateway(networkName string) (management.OperationID, error) {
+	if networkName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "networkName")
+	}
+
+	requestURL := fmt.Sprintf(azureGatewayURL, networkName)
+	return c.client.SendAzureDeleteRequest(requestURL)
+}
+
+// ConnectLocalNetworkSite connects the VPN gateway of a virtual network to
+// a local network site which the virtual network is configured for.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn146389.aspx
+func (c VPNGatewayClient) ConnectLocalNetworkSite(networkName, localSiteName string) (management.OperationID, error) {
+	return c.UpdateConnection(networkName, localSiteName, ConnectionOperationConnect)
+}
+
+// DisconnectLocalNetworkSite disconnects the VPN gateway of a virtual
+// network from a local network site.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn146389.aspx
+func (c VPNGatewayClient) DisconnectLocalNetworkSite(networkName, localSiteName string) (management.OperationID, error) {
+	return c.UpdateConnection(networkName, localSiteName, ConnectionOperationDisconnect)
+}
+
+// UpdateConnection connects, disconnects or tests the connection of the VPN
+// gateway of a virtual network to a local network site.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn146389.aspx
+func (c VPNGatewayClient) UpdateConnection(networkName, localSiteName string, operation ConnectionOperation) (management.OperationID, error) {
+	if networkName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "networkName")
+	}
+	if localSiteName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "localSiteName")
+	}
+	if operation == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "operation")
+	}
+
+	req, err := xml.Marshal(updateConnectionParameters{Operation: operation})
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(azureGatewayConnectionURL, networkName, localSiteName)
+	return c.client.SendAzurePutRequest(requestURL, "", req)
+}
+
+// ListConnections returns the connections of the VPN gateway of a virtual
+// network to local network sites, with their traffic counters.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154120.aspx
+func (c VPNGatewayClient) ListConnections(networkName string) (ListConnectionsResponse, error) {
+	var connections ListConnectionsResponse
+	if networkName == "" {
+		return connections, fmt.Errorf(errParamNotSpecified, "networkName")
+	}
+
+	requestURL := fmt.Sprintf(azureGatewayConnectionsURL, networkName)
+	response, err := c.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return connections, err
+	}
+
+	err = xml.Unmarshal(response, &connections)
+	return connections, err
+}
+
+// GetSharedKey returns the pre-shared key of the connection of the VPN
+// gateway of a virtual network to a local network site.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154122.aspx
+func (c VPNGatewayClient) GetSharedKey(networkName, localSiteName string) (string, error) {
+	if networkName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "networkName")
+	}
+	if localSiteName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "localSiteName")
+	}
+
+	requestURL := fmt.Sprintf(azureSharedKeyURL, networkName, localSiteName)
+	response, err := c.client.SendAzureGetRequest(requestURL)
+	if err != nil {
+		return "", err
+	}
+
+	var key SharedKey
+	err = xml.Unmarshal(response, &key)
+	return key.Value, err
+}
+
+// SetSharedKey sets the pre-shared key of the connection of the VPN gateway
+// of a virtual network to a local network site. The on-premises VPN device
+// must be configured with the same key.
+//
+// https://msdn.microsoft.com/en-us/library/azure/dn894127.aspx
+func (c VPNGatewayClient) SetSharedKey(networkName, localSiteName, key string) (management.OperationID, error) {
+	if networkName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "networkName")
+	}
+	if localSiteName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "localSiteName")
+	}
+	if key == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "key")
+	}
+
+	req, err := xml.Marshal(SharedKey{Value: key})
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(azureSharedKeyURL, networkName, localSiteName)
+	return c.client.SendAzurePutRequest(requestURL, "", req)
+}
+
+// ResetSharedKey replaces the pre-shared key of the connection of the VPN
+// gateway of a virtual network to a local network site with a new random
+// key of the given length, which can be read with GetSharedKey once the
+// operation has completed.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154114.aspx
+func (c VPNGatewayClient) ResetSharedKey(networkName, localSiteName string, keyLength int) (management.OperationID, error) {
+	if networkName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "networkName")
+	}
+	if localSiteName == "" {
+		return "", fmt.Errorf(errParamNotSpecified, "localSiteName")
+	}
+	if keyLength <= 0 {
+		return "", fmt.Errorf("Invalid shared key length: %d", keyLength)
+	}
+
+	req, err := xml.Marshal(resetSharedKeyParameters{KeyLength: keyLength})
+	if err != nil {
+		return "", err
+	}
+
+	requestURL := fmt.Sprintf(resetSharedKeyURL, networkName, localSiteName)
+	return c.client.SendAzurePostRequest(requestURL, req)
+}
@@ -0,0 +1,117 @@
+package vpngateway
+
+import (
+	"encoding/xml"
+
+	"github.com/Azure/azure-sdk-for-go/management"
+)
+
+//VPNGatewayClient is used to perform operations on the VPN gateways of Azure Virtual Networks
+type VPNGatewayClient struct {
+	client management.Client
+}
+
+// GatewayType specifies how a VPN gateway routes traffic to local network
+// sites.
+type GatewayType string
+
+const (
+	// GatewayTypeStaticRouting creates a policy-based gateway, which can
+	// connect to a single local network site.
+	GatewayTypeStaticRouting = GatewayType("StaticRouting")
+	// GatewayTypeDynamicRouting creates a route-based gateway, which is
+	// required for multiple sites and point-to-site connections.
+	GatewayTypeDynamicRouting = GatewayType("DynamicRouting")
+)
+
+type createGatewayParameters struct {
+	XMLName     xml.Name    `xml:"http://schemas.microsoft.com/windowsazure CreateGatewayParameters"`
+	GatewayType GatewayType `xml:"gatewayType"`
+}
+
+// Gateway is the VPN gateway of a virtual network.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154109.aspx
+type Gateway struct {
+	XMLName     xml.Name `xml:"http://schemas.microsoft.com/windowsazure Gateway"`
+	State       GatewayState
+	VIPAddress  string
+	LastEvent   *GatewayEvent
+	GatewayType GatewayType
+	GatewaySize string
+	DefaultSite string
+}
+
+// GatewayState is the provisioning state of a gateway.
+type GatewayState string
+
+const (
+	GatewayStateNotProvisioned = GatewayState("NotProvisioned")
+	GatewayStateDeprovisioning = GatewayState("Deprovisioning")
+	GatewayStateProvisioning   = GatewayState("Provisioning")
+	GatewayStateProvisioned    = GatewayState("Provisioned")
+)
+
+// GatewayEvent is an event reported by a gateway or a connection.
+type GatewayEvent struct {
+	Timestamp string
+	ID        string `xml:"Id"`
+	Message   string
+	Data      string
+}
+
+// ConnectionOperation changes the state of a connection.
+type ConnectionOperation string
+
+const (
+	ConnectionOperationConnect    = ConnectionOperation("Connect")
+	ConnectionOperationDisconnect = ConnectionOperation("Disconnect")
+	ConnectionOperationTest       = ConnectionOperation("Test")
+)
+
+type updateConnectionParameters struct {
+	XMLName   xml.Name `xml:"http://schemas.microsoft.com/windowsazure UpdateConnection"`
+	Operation ConnectionOperation
+}
+
+// ListConnectionsResponse contains the connections of a gateway to local
+// network sites.
+//
+// https://msdn.microsoft.com/en-us/library/azure/jj154120.aspx
+type ListConnectionsResponse struct {
+	XMLName     xml.Name     `xml:"http://schemas.microsoft.com/windowsazure Connections"`
+	Connections []Connection `xml:"Connection"`
+}
+
+// Connection is a connection of a gateway to a local network site,
+// including the number of bytes transferred over it.
+type Connection struct {
+	LocalNetworkSiteName      string
+	ConnectivityState         ConnectivityState
+	LastEvent                 *GatewayEvent
+	IngressBytesTransferred   int64
+	EgressBytesTransferred    int64
+	LastConnectionEstablished string
+	AllocatedIPAddresses      []string `xml:"AllocatedIPAddresses>string"`
+}
+
+// ConnectivityState is the state of a connection to a local network site.
+type ConnectivityState string
+
+const (
+	ConnectivityStateConnected    = ConnectivityState("Connected")
+	ConnectivityStateConnecting   = ConnectivityState("Connecting")
+	ConnectivityStateNotConnected = ConnectivityState("NotConnected")
+)
+
+// SharedKey is the pre-shared key of the IPsec connection to a local
+// network site.
+type SharedKey struct {
+	XMLName xml.Name `xml:"http://schemas.microsoft.com/windowsazure SharedKey"`
+	Value   string
+}
+
+type resetSharedKeyParameters struct {
+	XMLName   xml.Name `xml:"http://schemas.microsoft.com/windowsazure ResetSharedKey"`
+	KeyLength int
+}
@@ -0,0 +1,86 @@
+package vpngateway
+
+import (
+	"encoding/xml"
+	"testing"
+)
+
+func Test_Gateway_Unmarshal(t *testing.T) {
+	response := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<Gateway xmlns="http://schemas.microsoft.com/windowsazure">
+  <State>Provisioned</State>
+  <VIPAddress>23.96.1.2</VIPAddress>
+  <LastEvent>
+    <Timestamp>2015-10-21T07:28:00Z</Timestamp>
+    <Id>id</Id>
+    <Message>message</Message>
+  </LastEvent>
+  <GatewayType>DynamicRouting</GatewayType>
+  <GatewaySize>Default</GatewaySize>
+</Gateway>`)
+
+	var gateway Gateway
+	if err := xml.Unmarshal(response, &gateway); err != nil {
+		t.Fatal(err)
+	}
+	if gateway.State != GatewayStateProvisioned || gateway.GatewayType != GatewayTypeDynamicRouting {
+		t.Fatalf("Unexpected gateway: %+v", gateway)
+	}
+	if gateway.LastEvent == nil || gateway.LastEvent.ID != "id" {
+		t.Fatalf("Unexpected last event: %+v", gateway.LastEvent)
+	}
+}
+
+func Test_ListConnectionsResponse_Unmarshal(t *testing.T) {
+	response := []byte(`<?xml version="1.0" encoding="utf-8"?>
+<Connections xmlns="http://schemas.microsoft.com/windowsazure">
+  <Connection>
+    <LocalNetworkSiteName>onprem</LocalNetworkSiteName>
+    <ConnectivityState>Connected</ConnectivityState>
+    <IngressBytesTransferred>1234</IngressBytesTransferred>
+    <EgressBytesTransferred>5678901234</EgressBytesTransferred>
+    <LastConnectionEstablished>2015-10-21T07:28:00Z</LastConnectionEstablished>
+    <AllocatedIPAddresses>
+      <string>10.0.0.1</string>
+      <string>10.0.0.2</string>
+    </AllocatedIPAddresses>
+  </Connection>
+</Connections>`)
+
+	var list ListConnectionsResponse
+	if err := xml.Unmarshal(response, &list); err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Connections) != 1 {
+		t.Fatalf("Expected 1 connection but got %d", len(list.Connections))
+	}
+	c := list.Connections[0]
+	if c.ConnectivityState != ConnectivityStateConnected || c.IngressBytesTransferred != 1234 || c.EgressBytesTransferred != 5678901234 {
+		t.Fatalf("Unexpected connection: %+v", c)
+	}
+	if len(c.AllocatedIPAddresses) != 2 || c.AllocatedIPAddresses[1] != "10.0.0.2" {
+		t.Fatalf("Unexpected allocated IP addresses: %v", c.AllocatedIPAddresses)
+	}
+}
+
+func Test_Parameters_Marshal(t *testing.T) {
+	for _, test := range []struct {
+		v        interface{}
+		expected string
+	}{
+		{createGatewayParameters{GatewayType: GatewayTypeStaticRouting},
+			`<CreateGatewayParameters xmlns="http://schemas.microsoft.com/windowsazure"><gatewayType>StaticRouting</gatewayType></CreateGatewayParameters>`},
+		{updateConnectionParameters{Operation: ConnectionOperationConnect},
+			`<UpdateConnection xmlns="http://schemas.microsoft.com/windowsazure"><Operation>Connect</Operation></UpdateConnection>`},
+		{resetSharedKeyParameters{KeyLength: 32},
+			`<ResetSharedKey xmlns="http://schemas.microsoft.com/windowsazure"><KeyLength>32</KeyLength></ResetSharedKey>`},
+	} {
+		data, err := xml.Marshal(test.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != test.expected {
+			t.Fatalf("Expected %q but got %q", test.expected, string(data))
+		}
+	}
+}
@@ -0,0 +1,85 @@
+// Package accountkeys fetches and regenerates the access keys of storage
+// accounts with the management APIs, for use with the key failover and
+// rotation of the storage package. It is kept apart from the storage package
+// so that data-plane users do not depend on the management clients.
+package accountkeys
+
+import (
+	"errors"
+	"fmt"
+
+	armstorage "github.com/Azure/azure-sdk-for-go/arm/storage"
+	"github.com/Azure/azure-sdk-for-go/management/storageservice"
+	"github.com/Azure/azure-sdk-for-go/storage"
+)
+
+// NewClientFromAccountsClient constructs a storage.Client for the given Azure
+// Resource Manager storage account, fetching both of its access keys with
+// List Keys.
+func NewClientFromAccountsClient(ac armstorage.AccountsClient, resourceGroupName, accountName string) (storage.Client, error) {
+	keys, err := ac.ListKeys(resourceGroupName, accountName)
+	if err != nil {
+		return storage.Client{}, err
+	}
+	if keys.Key1 == nil {
+		return storage.Client{}, errors.New("storage: List Keys did not return the primary account key")
+	}
+	var secondary string
+	if keys.Key2 != nil {
+		secondary = *keys.Key2
+	}
+	return storage.NewBasicClientWithKeys(accountName, *keys.Key1, secondary)
+}
+
+// NewClientFromStorageServiceClient constructs a storage.Client for the given classic
+// storage account, fetching both of its access keys with Get Storage
+// Account Keys.
+func NewClientFromStorageServiceClient(sc storageservice.StorageServiceClient, serviceName string) (storage.Client, error) {
+	keys, err := sc.GetStorageServiceKeys(serviceName)
+	if err != nil {
+		return storage.Client{}, err
+	}
+	return storage.NewBasicClientWithKeys(serviceName, keys.PrimaryKey, keys.SecondaryKey)
+}
+
+// AccountsClientKeyRegenerator returns a storage.KeyRegenerator which
+// regenerates keys of an Azure Resource Manager storage account.
+func AccountsClientKeyRegenerator(ac armstorage.AccountsClient, resourceGroupName, accountName string) storage.KeyRegenerator {
+	return func(k storage.AccountKey) (string, error) {
+		keyName := "key1"
+		if k == storage.SecondaryAccountKey {
+			keyName = "key2"
+		}
+		keys, err := ac.RegenerateKey(resourceGroupName, accountName, armstorage.AccountRegenerateKeyParameters{KeyName: &keyName})
+		if err != nil {
+			return "", err
+		}
+		key := keys.Key1
+		if k == storage.SecondaryAccountKey {
+			key = keys.Key2
+		}
+		if key == nil {
+			return "", fmt.Errorf("storage: Regenerate Key did not return the %s account key", k)
+		}
+		return *key, nil
+	}
+}
+
+// StorageServiceClientKeyRegenerator returns a storage.KeyRegenerator which
+// regenerates keys of a classic storage account.
+func StorageServiceClientKeyRegenerator(sc storageservice.StorageServiceClient, serviceName string) storage.KeyRegenerator {
+	return func(k storage.AccountKey) (string, error) {
+		keyType := storageservice.KeyTypePrimary
+		if k == storage.SecondaryAccountKey {
+			keyType = storageservice.KeyTypeSecondary
+		}
+		keys, err := sc.RegenerateStorageServiceKeys(serviceName, keyType)
+		if err != nil {
+			return "", err
+		}
+		if k == storage.SecondaryAccountKey {
+			return keys.SecondaryKey, nil
+		}
+		return keys.PrimaryKey, nil
+	}
+}
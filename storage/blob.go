@@ -19,22 +19,52 @@ type BlobStorageClient struct {
 	client Client
 }
 
-// A Container is an entry in ContainerListResponse.
+// A Container is an entry in ContainerListResponse. Metadata is only
+// populated if the containers are listed with Include set to "metadata".
 type Container struct {
 	Name       string              `xml:"Name"`
 	Properties ContainerProperties `xml:"Properties"`
-	// TODO (ahmetalpbalkan) Metadata
+	Metadata   map[string]string   `xml:"-"`
+}
+
+// UnmarshalXML decodes a Container element, including the user-defined
+// metadata elements whose names are not known in advance.
+func (c *Container) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var aux struct {
+		Name       string              `xml:"Name"`
+		Properties ContainerProperties `xml:"Properties"`
+		Metadata   *struct {
+			Items []struct {
+				XMLName xml.Name
+				Value   string `xml:",chardata"`
+			} `xml:",any"`
+		} `xml:"Metadata"`
+	}
+	if err := d.DecodeElement(&aux, &start); err != nil {
+		return err
+	}
+
+	c.Name = aux.Name
+	c.Properties = aux.Properties
+	c.Metadata = nil
+	if aux.Metadata != nil {
+		c.Metadata = make(map[string]string, len(aux.Metadata.Items))
+		for _, item := range aux.Metadata.Items {
+			c.Metadata[strings.ToLower(item.XMLName.Local)] = item.Value
+		}
+	}
+	return nil
 }
 
 // ContainerProperties contains various properties of a container returned from
-// various endpoints like ListContainers.
+// various endpoints like ListContainers or GetContainerProperties.
 type ContainerProperties struct {
-	LastModified  string `xml:"Last-Modified"`
-	Etag          string `xml:"Etag"`
-	LeaseStatus   string `xml:"LeaseStatus"`
-	LeaseState    string `xml:"LeaseState"`
-	LeaseDuration string `xml:"LeaseDuration"`
-	// TODO (ahmetalpbalkan) remaining fields
+	LastModified  string              `xml:"Last-Modified"`
+	Etag          string              `xml:"Etag"`
+	LeaseStatus   string              `xml:"LeaseStatus"`
+	LeaseState    string              `xml:"LeaseState"`
+	LeaseDuration string              `xml:"LeaseDuration"`
+	PublicAccess  ContainerAccessType `xml:"PublicAccess"`
 }
 
 // ContainerListResponse contains the response fields from
@@ -88,6 +118,12 @@ type BlobListResponse struct {
 	NextMarker string   `xml:"NextMarker"`
 	MaxResults int64    `xml:"MaxResults"`
 	Blobs      []Blob   `xml:"Blobs>Blob"`
+
+	// BlobPrefixes holds the names of the virtual directories at the level
+	// of the prefix, each ending with the delimiter, if blobs are listed
+	// with a Delimiter.
+	Delimiter    string   `xml:"Delimiter"`
+	BlobPrefixes []string `xml:"Blobs>BlobPrefix>Name"`
 }
 
 // ListContainersParameters defines the set of customizable parameters to make a
@@ -274,6 +310,21 @@ type PageRange struct {
 	End   int64 `xml:"End"`
 }
 
+// Header names and actions of Lease Blob requests
+const (
+	leaseAction     = "x-ms-lease-action"
+	leaseDuration   = "x-ms-lease-duration"
+	leaseID         = "x-ms-lease-id"
+	leaseProposedID = "x-ms-proposed-lease-id"
+	leaseTime       = "x-ms-lease-time"
+
+	acquireLease = "acquire"
+	renewLease   = "renew"
+	releaseLease = "release"
+	changeLease  = "change"
+	breakLease   = "break"
+)
+
 var (
 	errBlobCopyAborted    = errors.New("storage: blob copy is aborted")
 	errBlobCopyIDMismatch = errors.New("storage: blob copy id is a mismatch")
@@ -392,6 +443,78 @@ func (b BlobStorageClient) deleteContainer(name string) (*storageResponse, error
 	return b.client.exec(verb, uri, headers, nil)
 }
 
+// GetContainerProperties returns the system properties of the container with
+// given name. PublicAccess is only populated by service versions reporting
+// the access level of the container along with its properties.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179370.aspx
+func (b BlobStorageClient) GetContainerProperties(name string) (*ContainerProperties, error) {
+	uri := b.client.getEndpoint(blobServiceName, pathForContainer(name), url.Values{"restype": {"container"}})
+	headers := b.client.getStandardHeaders()
+
+	resp, err := b.client.exec("HEAD", uri, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.body.Close()
+
+	if err := checkRespCode(resp.statusCode, []int{http.StatusOK}); err != nil {
+		return nil, err
+	}
+
+	return &ContainerProperties{
+		LastModified:  resp.headers.Get("Last-Modified"),
+		Etag:          resp.headers.Get("Etag"),
+		LeaseStatus:   resp.headers.Get("x-ms-lease-status"),
+		LeaseState:    resp.headers.Get("x-ms-lease-state"),
+		LeaseDuration: resp.headers.Get("x-ms-lease-duration"),
+		PublicAccess:  ContainerAccessType(resp.headers.Get("x-ms-blob-public-access")),
+	}, nil
+}
+
+// SetContainerMetadata replaces the metadata for the container with given
+// name. Keys are subject to the same case munging as in SetBlobMetadata.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179362.aspx
+func (b BlobStorageClient) SetContainerMetadata(name string, metadata map[string]string) error {
+	params := url.Values{"restype": {"container"}, "comp": {"metadata"}}
+	uri := b.client.getEndpoint(blobServiceName, pathForContainer(name), params)
+	headers := b.client.getStandardHeaders()
+	for k, v := range metadata {
+		headers[userDefinedMetadataHeaderPrefix+k] = v
+	}
+	headers["Content-Length"] = "0"
+
+	resp, err := b.client.exec("PUT", uri, headers, nil)
+	if err != nil {
+		return err
+	}
+	defer resp.body.Close()
+
+	return checkRespCode(resp.statusCode, []int{http.StatusOK})
+}
+
+// GetContainerMetadata returns all user-defined metadata for the container
+// with given name. All metadata keys will be returned in lower case.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/ee691976.aspx
+func (b BlobStorageClient) GetContainerMetadata(name string) (map[string]string, error) {
+	params := url.Values{"restype": {"container"}, "comp": {"metadata"}}
+	uri := b.client.getEndpoint(blobServiceName, pathForContainer(name), params)
+	headers := b.client.getStandardHeaders()
+
+	resp, err := b.client.exec("GET", uri, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.body.Close()
+
+	if err := checkRespCode(resp.statusCode, []int{http.StatusOK}); err != nil {
+		return nil, err
+	}
+	return getMetadataFromHeaders(resp.headers), nil
+}
+
 // ListBlobs returns an object that contains list of blobs in the container,
 // pagination token and other information in the response of List Blobs call.
 //
@@ -609,8 +732,14 @@ func (b BlobStorageClient) GetBlobMetadata(container, name string) (map[string]s
 		return nil, err
 	}
 
+	return getMetadataFromHeaders(resp.headers), nil
+}
+
+// getMetadataFromHeaders returns the user-defined metadata in the headers of
+// a response, with keys in lower case.
+func getMetadataFromHeaders(h http.Header) map[string]string {
 	metadata := make(map[string]string)
-	for k, v := range resp.headers {
+	for k, v := range h {
 		// Can't trust CanonicalHeaderKey() to munge case
 		// reliably. "_" is allowed in identifiers:
 		// https://msdn.microsoft.com/en-us/library/azure/dd179414.aspx
@@ -628,7 +757,7 @@ func (b BlobStorageClient) GetBlobMetadata(container, name string) (map[string]s
 		k = k[len(userDefinedMetadataHeaderPrefix):]
 		metadata[k] = v[len(v)-1]
 	}
-	return metadata, nil
+	return metadata
 }
 
 // CreateBlockBlob initializes an empty block blob with no blocks.
@@ -648,6 +777,12 @@ func (b BlobStorageClient) CreateBlockBlob(container, name string) error {
 //
 // See https://msdn.microsoft.com/en-us/library/azure/dd179451.aspx
 func (b BlobStorageClient) CreateBlockBlobFromReader(container, name string, size uint64, blob io.Reader, props *BlobProperties) error {
+	return b.createBlockBlobFromReader(container, name, size, blob, props, nil)
+}
+
+// createBlockBlobFromReader is CreateBlockBlobFromReader which also sets the
+// given user-defined metadata on the blob within the same Put Blob request.
+func (b BlobStorageClient) createBlockBlobFromReader(container, name string, size uint64, blob io.Reader, props *BlobProperties, metadata map[string]string) error {
 	path := fmt.Sprintf("%s/%s", container, name)
 	uri := b.client.getEndpoint(blobServiceName, path, url.Values{})
 	headers := b.client.getStandardHeaders()
@@ -657,8 +792,11 @@ func (b BlobStorageClient) CreateBlockBlobFromReader(container, name string, siz
 	if props != nil && props.ContentType != "" {
 		headers["Content-Type"] = props.ContentType
 	}
-	
+
 	setPropertyHeaders(headers, props)
+	for k, v := range metadata {
+		headers[userDefinedMetadataHeaderPrefix+k] = v
+	}
 
 	resp, err := b.client.exec("PUT", uri, headers, blob)
 	if err != nil {
@@ -898,6 +1036,117 @@ func (b BlobStorageClient) waitForBlobCopy(container, name, copyID string) error
 	}
 }
 
+// AcquireLease creates a lease on the specified blob for the given duration
+// in seconds (between 15 and 60, or -1 for an infinite lease) and returns the
+// lease ID. A proposed lease ID can be specified, otherwise the service
+// generates one.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/ee691972.aspx
+func (b BlobStorageClient) AcquireLease(container, name string, leaseTimeInSeconds int, proposedLeaseID string) (string, error) {
+	headers := b.client.getStandardHeaders()
+	headers[leaseAction] = acquireLease
+	headers[leaseDuration] = strconv.Itoa(leaseTimeInSeconds)
+	if proposedLeaseID != "" {
+		headers[leaseProposedID] = proposedLeaseID
+	}
+
+	resp, err := b.leaseCommonPut(container, name, headers, http.StatusCreated)
+	if err != nil {
+		return "", err
+	}
+
+	returnedLeaseID := resp.headers.Get(leaseID)
+	if returnedLeaseID == "" {
+		return "", errors.New("storage: LeaseID not returned by the service")
+	}
+	return returnedLeaseID, nil
+}
+
+// RenewLease renews the lease with the given ID on the specified blob.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/ee691972.aspx
+func (b BlobStorageClient) RenewLease(container, name, currentLeaseID string) error {
+	headers := b.client.getStandardHeaders()
+	headers[leaseAction] = renewLease
+	headers[leaseID] = currentLeaseID
+
+	_, err := b.leaseCommonPut(container, name, headers, http.StatusOK)
+	return err
+}
+
+// ReleaseLease releases the lease with the given ID on the specified blob so
+// that another client can acquire a lease immediately.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/ee691972.aspx
+func (b BlobStorageClient) ReleaseLease(container, name, currentLeaseID string) error {
+	headers := b.client.getStandardHeaders()
+	headers[leaseAction] = releaseLease
+	headers[leaseID] = currentLeaseID
+
+	_, err := b.leaseCommonPut(container, name, headers, http.StatusOK)
+	return err
+}
+
+// ChangeLease changes the ID of the lease held on the specified blob and
+// returns the new lease ID.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/ee691972.aspx
+func (b BlobStorageClient) ChangeLease(container, name, currentLeaseID, proposedLeaseID string) (string, error) {
+	headers := b.client.getStandardHeaders()
+	headers[leaseAction] = changeLease
+	headers[leaseID] = currentLeaseID
+	headers[leaseProposedID] = proposedLeaseID
+
+	resp, err := b.leaseCommonPut(container, name, headers, http.StatusOK)
+	if err != nil {
+		return "", err
+	}
+
+	newLeaseID := resp.headers.Get(leaseID)
+	if newLeaseID == "" {
+		return "", errors.New("storage: LeaseID not returned by the service")
+	}
+	return newLeaseID, nil
+}
+
+// BreakLease breaks the lease on the specified blob without knowing its ID
+// and returns the number of seconds remaining until the lease is broken.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/ee691972.aspx
+func (b BlobStorageClient) BreakLease(container, name string) (int, error) {
+	headers := b.client.getStandardHeaders()
+	headers[leaseAction] = breakLease
+
+	resp, err := b.leaseCommonPut(container, name, headers, http.StatusAccepted)
+	if err != nil {
+		return 0, err
+	}
+
+	breakTime := resp.headers.Get(leaseTime)
+	if breakTime == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(breakTime)
+}
+
+// leaseCommonPut sends a Lease Blob request with the given headers and checks
+// the response status code.
+func (b BlobStorageClient) leaseCommonPut(container, name string, headers map[string]string, expectedStatus int) (*storageResponse, error) {
+	uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, name), url.Values{"comp": {"lease"}})
+	headers["Content-Length"] = "0"
+
+	resp, err := b.client.exec("PUT", uri, headers, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.body.Close()
+
+	if err := checkRespCode(resp.statusCode, []int{expectedStatus}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // DeleteBlob deletes the given blob from the specified container.
 // If the blob does not exists at the time of the Delete Blob operation, it
 // returns error. See https://msdn.microsoft.com/en-us/library/azure/dd179413.aspx
@@ -916,10 +1165,12 @@ func (b BlobStorageClient) DeleteBlob(container, name string) error {
 // See https://msdn.microsoft.com/en-us/library/azure/dd179413.aspx
 func (b BlobStorageClient) DeleteBlobIfExists(container, name string) (bool, error) {
 	resp, err := b.deleteBlob(container, name)
-	if resp != nil && (resp.statusCode == http.StatusAccepted || resp.statusCode == http.StatusNotFound) {
-		return resp.statusCode == http.StatusAccepted, nil
+	if resp != nil {
+		defer resp.body.Close()
+		if resp.statusCode == http.StatusAccepted || resp.statusCode == http.StatusNotFound {
+			return resp.statusCode == http.StatusAccepted, nil
+		}
 	}
-	defer resp.body.Close()
 	return false, err
 }
 
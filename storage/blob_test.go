@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"encoding/base64"
+	"encoding/xml"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -154,6 +155,83 @@ func (s *StorageBlobSuite) TestListContainersPagination(c *chk.C) {
 	c.Assert(seen, chk.DeepEquals, created)
 }
 
+func (s *StorageBlobSuite) Test_containerListResponseMetadata(c *chk.C) {
+	body := `<?xml version="1.0" encoding="utf-8"?>
+<EnumerationResults ServiceEndpoint="https://foo.blob.core.windows.net/">
+  <Containers>
+    <Container>
+      <Name>cnt1</Name>
+      <Properties><Etag>"0x1"</Etag><LeaseStatus>unlocked</LeaseStatus></Properties>
+      <Metadata><Owner>alice</Owner><retention_class>short</retention_class></Metadata>
+    </Container>
+    <Container>
+      <Name>cnt2</Name>
+      <Properties><Etag>"0x2"</Etag></Properties>
+    </Container>
+  </Containers>
+  <NextMarker />
+</EnumerationResults>`
+
+	var out ContainerListResponse
+	c.Assert(xml.Unmarshal([]byte(body), &out), chk.IsNil)
+	c.Assert(len(out.Containers), chk.Equals, 2)
+	c.Assert(out.Containers[0].Name, chk.Equals, "cnt1")
+	c.Assert(out.Containers[0].Properties.Etag, chk.Equals, `"0x1"`)
+	c.Assert(out.Containers[0].Properties.LeaseStatus, chk.Equals, "unlocked")
+	c.Assert(out.Containers[0].Metadata, chk.DeepEquals, map[string]string{
+		"owner":           "alice",
+		"retention_class": "short",
+	})
+	c.Assert(out.Containers[1].Name, chk.Equals, "cnt2")
+	c.Assert(out.Containers[1].Metadata, chk.IsNil)
+}
+
+func (s *StorageBlobSuite) TestGetAndSetContainerMetadata(c *chk.C) {
+	cli := getBlobClient(c)
+	cnt := randContainer()
+	c.Assert(cli.CreateContainer(cnt, ContainerAccessTypePrivate), chk.IsNil)
+	defer cli.DeleteContainer(cnt)
+
+	m, err := cli.GetContainerMetadata(cnt)
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(m), chk.Equals, 0)
+
+	mPut := map[string]string{
+		"Owner":           "alice",
+		"retention_class": "short",
+	}
+	c.Assert(cli.SetContainerMetadata(cnt, mPut), chk.IsNil)
+
+	m, err = cli.GetContainerMetadata(cnt)
+	c.Assert(err, chk.IsNil)
+	c.Check(m, chk.DeepEquals, map[string]string{
+		"owner":           "alice",
+		"retention_class": "short",
+	})
+
+	resp, err := cli.ListContainers(ListContainersParameters{Prefix: cnt, Include: "metadata"})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(resp.Containers), chk.Equals, 1)
+	c.Check(resp.Containers[0].Metadata, chk.DeepEquals, m)
+}
+
+func (s *StorageBlobSuite) TestGetContainerProperties(c *chk.C) {
+	cli := getBlobClient(c)
+	cnt := randContainer()
+
+	_, err := cli.GetContainerProperties(cnt)
+	c.Assert(err, chk.NotNil)
+
+	c.Assert(cli.CreateContainer(cnt, ContainerAccessTypePrivate), chk.IsNil)
+	defer cli.DeleteContainer(cnt)
+
+	props, err := cli.GetContainerProperties(cnt)
+	c.Assert(err, chk.IsNil)
+	c.Assert(props.Etag, chk.Not(chk.Equals), "")
+	c.Assert(props.LastModified, chk.Not(chk.Equals), "")
+	c.Assert(props.LeaseStatus, chk.Equals, "unlocked")
+}
+
 func (s *StorageBlobSuite) TestContainerExists(c *chk.C) {
 	cnt := randContainer()
 	cli := getBlobClient(c)
@@ -280,6 +358,38 @@ func (s *StorageBlobSuite) TestDeleteBlobIfExists(c *chk.C) {
 	c.Assert(ok, chk.Equals, false)
 }
 
+func (s *StorageBlobSuite) TestLeaseBlob(c *chk.C) {
+	cnt := randContainer()
+	blob := randString(20)
+
+	cli := getBlobClient(c)
+	c.Assert(cli.CreateContainer(cnt, ContainerAccessTypePrivate), chk.IsNil)
+	defer cli.DeleteContainer(cnt)
+	c.Assert(cli.CreateBlockBlob(cnt, blob), chk.IsNil)
+
+	id, err := cli.AcquireLease(cnt, blob, 30, "")
+	c.Assert(err, chk.IsNil)
+	c.Assert(id, chk.Not(chk.Equals), "")
+
+	// blob cannot be leased twice
+	_, err = cli.AcquireLease(cnt, blob, 30, "")
+	c.Assert(err, chk.NotNil)
+
+	c.Assert(cli.RenewLease(cnt, blob, id), chk.IsNil)
+
+	proposed := "dfe6dde8-68d5-4910-9248-c97c61768fbb"
+	id, err = cli.ChangeLease(cnt, blob, id, proposed)
+	c.Assert(err, chk.IsNil)
+	c.Assert(id, chk.Equals, proposed)
+	c.Assert(cli.ReleaseLease(cnt, blob, id), chk.IsNil)
+
+	_, err = cli.AcquireLease(cnt, blob, -1, "")
+	c.Assert(err, chk.IsNil)
+	breakTime, err := cli.BreakLease(cnt, blob)
+	c.Assert(err, chk.IsNil)
+	c.Assert(breakTime, chk.Equals, 0)
+}
+
 func (s *StorageBlobSuite) TestGetBlobProperties(c *chk.C) {
 	cnt := randContainer()
 	blob := randString(20)
@@ -444,7 +554,7 @@ func (s *StorageBlobSuite) TestCreateBlockBlobFromReader(c *chk.C) {
 
 	name := randString(20)
 	data := randBytes(8888)
-	c.Assert(cli.CreateBlockBlobFromReader(cnt, name, uint64(len(data)), bytes.NewReader(data)), chk.IsNil)
+	c.Assert(cli.CreateBlockBlobFromReader(cnt, name, uint64(len(data)), bytes.NewReader(data), nil), chk.IsNil)
 
 	body, err := cli.GetBlob(cnt, name)
 	c.Assert(err, chk.IsNil)
@@ -463,7 +573,7 @@ func (s *StorageBlobSuite) TestCreateBlockBlobFromReaderWithShortData(c *chk.C)
 
 	name := randString(20)
 	data := randBytes(8888)
-	err := cli.CreateBlockBlobFromReader(cnt, name, 9999, bytes.NewReader(data))
+	err := cli.CreateBlockBlobFromReader(cnt, name, 9999, bytes.NewReader(data), nil)
 	c.Assert(err, chk.Not(chk.IsNil))
 
 	_, err = cli.GetBlob(cnt, name)
@@ -0,0 +1,408 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// blobFSBlockSize is the size of the blocks written by BlobFileSystem.Create.
+const blobFSBlockSize = 4 * 1024 * 1024
+
+var errRenameIncomplete = errors.New("storage: some blobs could not be renamed")
+
+// FileSystem is a hierarchical file system with slash-separated names. It is
+// implemented by BlobFileSystem, and can be implemented on the local disk
+// through the os package, so that both can be used interchangeably.
+type FileSystem interface {
+	Stat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.FileInfo, error)
+	Open(name string) (ReadSeekCloser, error)
+	Create(name string) (io.WriteCloser, error)
+	Remove(name string) error
+	Rename(oldname, newname string) error
+}
+
+// ReadSeekCloser is the interface of files opened for reading in a
+// FileSystem, which is also implemented by *os.File.
+type ReadSeekCloser interface {
+	io.Reader
+	io.Seeker
+	io.Closer
+}
+
+// BlobFileSystem is a FileSystem over the blobs of a container, in which "/"
+// separates the virtual directories in blob names. Directories exist as long
+// as there are blobs in them. Errors about missing files and directories are
+// *os.PathError values satisfying os.IsNotExist.
+type BlobFileSystem struct {
+	blob      BlobStorageClient
+	container string
+}
+
+// NewBlobFileSystem returns a BlobFileSystem over the blobs of the given
+// container, which must exist.
+func NewBlobFileSystem(blob BlobStorageClient, container string) BlobFileSystem {
+	return BlobFileSystem{blob: blob, container: container}
+}
+
+// Stat returns the FileInfo of the named blob or virtual directory. The Sys
+// method of the FileInfo of a blob returns its *BlobProperties.
+func (fs BlobFileSystem) Stat(name string) (os.FileInfo, error) {
+	name = cleanBlobPath(name)
+	if name == "" {
+		return blobDirInfo("/"), nil
+	}
+
+	props, err := fs.blob.GetBlobProperties(fs.container, name)
+	if err == nil {
+		return newBlobFileInfo(name, props), nil
+	}
+	if !isStorageErrorWithStatus(err, http.StatusNotFound) {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+
+	resp, err := fs.blob.ListBlobs(fs.container, ListBlobsParameters{Prefix: name + "/", MaxResults: 1})
+	if err != nil {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: err}
+	}
+	if len(resp.Blobs) == 0 {
+		return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrNotExist}
+	}
+	return blobDirInfo(name), nil
+}
+
+// ReadDir returns the FileInfos of the blobs and virtual directories directly
+// in the named directory, sorted by name.
+func (fs BlobFileSystem) ReadDir(name string) ([]os.FileInfo, error) {
+	name = cleanBlobPath(name)
+	prefix := ""
+	if name != "" {
+		prefix = name + "/"
+	}
+
+	var out []os.FileInfo
+	params := ListBlobsParameters{Prefix: prefix, Delimiter: "/"}
+	for {
+		resp, err := fs.blob.ListBlobs(fs.container, params)
+		if err != nil {
+			return nil, &os.PathError{Op: "readdir", Path: name, Err: err}
+		}
+		for i := range resp.Blobs {
+			out = append(out, newBlobFileInfo(resp.Blobs[i].Name, &resp.Blobs[i].Properties))
+		}
+		for _, p := range resp.BlobPrefixes {
+			out = append(out, blobDirInfo(strings.TrimSuffix(p, "/")))
+		}
+		if resp.NextMarker == "" {
+			break
+		}
+		params.Marker = resp.NextMarker
+	}
+
+	if len(out) == 0 && name != "" {
+		return nil, &os.PathError{Op: "readdir", Path: name, Err: os.ErrNotExist}
+	}
+	sort.Sort(byFileName(out))
+	return out, nil
+}
+
+// Open opens the named blob for reading. The content is streamed from the
+// current offset, and seeking restarts the stream.
+func (fs BlobFileSystem) Open(name string) (ReadSeekCloser, error) {
+	name = cleanBlobPath(name)
+	props, err := fs.blob.GetBlobProperties(fs.container, name)
+	if err != nil {
+		if isStorageErrorWithStatus(err, http.StatusNotFound) {
+			err = os.ErrNotExist
+		}
+		return nil, &os.PathError{Op: "open", Path: name, Err: err}
+	}
+	return &blobFileReader{fs: fs, name: name, size: props.ContentLength}, nil
+}
+
+// Create creates or truncates the named blob and returns a writer for its
+// content. The content is uploaded in blocks as it is written and the blob is
+// only replaced when the writer is closed.
+func (fs BlobFileSystem) Create(name string) (io.WriteCloser, error) {
+	name = cleanBlobPath(name)
+	if name == "" {
+		return nil, &os.PathError{Op: "create", Path: "/", Err: errors.New("storage: is a directory")}
+	}
+	return &blobFileWriter{fs: fs, name: name}, nil
+}
+
+// Remove removes the named blob. Virtual directories cannot be removed, they
+// cease to exist once they have no blobs.
+func (fs BlobFileSystem) Remove(name string) error {
+	name = cleanBlobPath(name)
+	err := fs.blob.DeleteBlob(fs.container, name)
+	if isStorageErrorWithStatus(err, http.StatusNotFound) {
+		if _, statErr := fs.Stat(name); statErr == nil {
+			err = errors.New("storage: directory not empty")
+		} else {
+			err = os.ErrNotExist
+		}
+	}
+	if err != nil {
+		return &os.PathError{Op: "remove", Path: name, Err: err}
+	}
+	return nil
+}
+
+// Rename copies the named blob or all the blobs of the named directory to the
+// new name and deletes the originals. It is not atomic: if it fails, blobs may
+// exist under both names. The new name cannot be inside the old one.
+// Renaming an existing file or directory to itself does nothing.
+func (fs BlobFileSystem) Rename(oldname, newname string) error {
+	oldname, newname = cleanBlobPath(oldname), cleanBlobPath(newname)
+	if strings.HasPrefix(newname, oldname+"/") {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: errors.New("storage: cannot rename to a path inside itself")}
+	}
+	fi, err := fs.Stat(oldname)
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err.(*os.PathError).Err}
+	}
+	if oldname == newname {
+		return nil
+	}
+
+	if !fi.IsDir() {
+		err = fs.blob.CopyBlob(fs.container, newname, fs.blob.GetBlobURL(fs.container, oldname))
+		if err == nil {
+			err = fs.blob.DeleteBlob(fs.container, oldname)
+		}
+	} else if oldname == "" {
+		err = errors.New("storage: cannot rename the root directory")
+	} else {
+		var res BulkResult
+		res, err = fs.blob.CopyBlobsWithPrefix(fs.container, oldname+"/", fs.container, newname+"/", BulkOptions{})
+		if err == nil && len(res.Errors) == 0 {
+			res, err = fs.blob.DeleteBlobsWithPrefix(fs.container, oldname+"/", BulkOptions{})
+		}
+		if err == nil && len(res.Errors) > 0 {
+			err = errRenameIncomplete
+		}
+	}
+	if err != nil {
+		return &os.LinkError{Op: "rename", Old: oldname, New: newname, Err: err}
+	}
+	return nil
+}
+
+// Walk walks the file tree of the file system rooted at root like
+// filepath.Walk, calling walkFn for each file or directory in lexical order.
+// Names passed to walkFn are slash-separated.
+func Walk(fs FileSystem, root string, walkFn filepath.WalkFunc) error {
+	fi, err := fs.Stat(root)
+	if err != nil {
+		err = walkFn(root, nil, err)
+	} else {
+		err = walk(fs, root, fi, walkFn)
+	}
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+func walk(fs FileSystem, name string, fi os.FileInfo, walkFn filepath.WalkFunc) error {
+	if !fi.IsDir() {
+		return walkFn(name, fi, nil)
+	}
+
+	entries, err := fs.ReadDir(name)
+	if err := walkFn(name, fi, err); err != nil || entries == nil {
+		return err
+	}
+	for _, entry := range entries {
+		err := walk(fs, path.Join(name, entry.Name()), entry, walkFn)
+		if err != nil && !(entry.IsDir() && err == filepath.SkipDir) {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanBlobPath returns the blob name of a slash-separated path, which is
+// empty for the root directory.
+func cleanBlobPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// blobFileInfo implements os.FileInfo for blobs and virtual directories.
+type blobFileInfo struct {
+	name  string
+	props *BlobProperties
+}
+
+func newBlobFileInfo(name string, props *BlobProperties) blobFileInfo {
+	return blobFileInfo{name: path.Base(name), props: props}
+}
+
+func blobDirInfo(name string) blobFileInfo {
+	return blobFileInfo{name: path.Base(name)}
+}
+
+func (fi blobFileInfo) Name() string { return fi.name }
+func (fi blobFileInfo) IsDir() bool  { return fi.props == nil }
+func (fi blobFileInfo) Sys() interface{} {
+	if fi.props == nil {
+		return nil
+	}
+	return fi.props
+}
+
+func (fi blobFileInfo) Size() int64 {
+	if fi.props == nil {
+		return 0
+	}
+	return fi.props.ContentLength
+}
+
+func (fi blobFileInfo) Mode() os.FileMode {
+	if fi.props == nil {
+		return os.ModeDir | 0755
+	}
+	return 0644
+}
+
+func (fi blobFileInfo) ModTime() time.Time {
+	if fi.props == nil {
+		return time.Time{}
+	}
+	t, _ := time.Parse(http.TimeFormat, fi.props.LastModified)
+	return t
+}
+
+type byFileName []os.FileInfo
+
+func (a byFileName) Len() int           { return len(a) }
+func (a byFileName) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
+func (a byFileName) Less(i, j int) bool { return a[i].Name() < a[j].Name() }
+
+// blobFileReader reads a blob from an offset, which can be changed by
+// seeking.
+type blobFileReader struct {
+	fs     BlobFileSystem
+	name   string
+	size   int64
+	offset int64
+	body   io.ReadCloser
+}
+
+func (r *blobFileReader) Read(p []byte) (int, error) {
+	if r.offset >= r.size {
+		return 0, io.EOF
+	}
+	if r.body == nil {
+		body, err := r.fs.blob.GetBlobRange(r.fs.container, r.name, fmt.Sprintf("%d-%d", r.offset, r.size-1))
+		if err != nil {
+			return 0, err
+		}
+		r.body = body
+	}
+
+	n, err := r.body.Read(p)
+	r.offset += int64(n)
+	if err == io.EOF && r.offset < r.size {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (r *blobFileReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += r.offset
+	case io.SeekEnd:
+		offset += r.size
+	default:
+		return r.offset, errors.New("storage: invalid whence")
+	}
+	if offset < 0 {
+		return r.offset, errors.New("storage: seek to negative offset")
+	}
+
+	if offset != r.offset {
+		r.Close()
+		r.offset = offset
+	}
+	return r.offset, nil
+}
+
+func (r *blobFileReader) Close() error {
+	if r.body == nil {
+		return nil
+	}
+	err := r.body.Close()
+	r.body = nil
+	return err
+}
+
+// blobFileWriter writes a blob in blocks, and commits them when closed.
+type blobFileWriter struct {
+	fs     BlobFileSystem
+	name   string
+	buf    bytes.Buffer
+	blocks []Block
+	err    error
+}
+
+func (w *blobFileWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n := len(p)
+	for len(p) > 0 {
+		chunk := blobFSBlockSize - w.buf.Len()
+		if chunk > len(p) {
+			chunk = len(p)
+		}
+		w.buf.Write(p[:chunk])
+		p = p[chunk:]
+		if w.buf.Len() == blobFSBlockSize {
+			if w.err = w.putBlock(); w.err != nil {
+				return n - len(p), w.err
+			}
+		}
+	}
+	return n, nil
+}
+
+func (w *blobFileWriter) putBlock() error {
+	id := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%08d", len(w.blocks))))
+	if err := w.fs.blob.PutBlock(w.fs.container, w.name, id, w.buf.Bytes()); err != nil {
+		return err
+	}
+	w.blocks = append(w.blocks, Block{ID: id, Status: BlockStatusUncommitted})
+	w.buf.Reset()
+	return nil
+}
+
+func (w *blobFileWriter) Close() error {
+	if w.err != nil {
+		return w.err
+	}
+	w.err = errors.New("storage: write to closed blob")
+
+	if len(w.blocks) == 0 {
+		return w.fs.blob.CreateBlockBlobFromReader(w.fs.container, w.name, uint64(w.buf.Len()), &w.buf, nil)
+	}
+	if w.buf.Len() > 0 {
+		if err := w.putBlock(); err != nil {
+			return err
+		}
+	}
+	return w.fs.blob.PutBlockList(w.fs.container, w.name, w.blocks)
+}
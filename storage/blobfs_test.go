@@ -0,0 +1,233 @@
+package storage
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	chk "github.com/Azure/azure-sdk-for-go/Godeps/_workspace/src/gopkg.in/check.v1"
+)
+
+type StorageBlobFSSuite struct{}
+
+var _ = chk.Suite(&StorageBlobFSSuite{})
+
+var _ FileSystem = BlobFileSystem{}
+
+func newTestBlobFS(c *chk.C, names ...string) (BlobFileSystem, *fakeBlobFSServer, func()) {
+	f := newFakeBlobFSServer(names...)
+	cli, err := NewBasicClient("foo", "YmFy")
+	c.Assert(err, chk.IsNil)
+	ts := useTestServer(&cli, f)
+	return NewBlobFileSystem(cli.GetBlobService(), "cnt"), f, ts.Close
+}
+
+func (s *StorageBlobFSSuite) Test_cleanBlobPath(c *chk.C) {
+	for in, out := range map[string]string{
+		"":        "",
+		"/":       "",
+		".":       "",
+		"a":       "a",
+		"/a/b/":   "a/b",
+		"a//b/./": "a/b",
+		"a/../b":  "b",
+	} {
+		c.Assert(cleanBlobPath(in), chk.Equals, out, chk.Commentf("%q", in))
+	}
+}
+
+func (s *StorageBlobFSSuite) TestStat(c *chk.C) {
+	fs, _, done := newTestBlobFS(c, "cnt/a.txt", "cnt/dir/b.txt")
+	defer done()
+
+	fi, err := fs.Stat("/a.txt")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fi.Name(), chk.Equals, "a.txt")
+	c.Assert(fi.IsDir(), chk.Equals, false)
+	c.Assert(fi.Size(), chk.Equals, int64(len("cnt/a.txt")))
+	c.Assert(fi.ModTime().Equal(fakeBlobLastModified), chk.Equals, true)
+	c.Assert(fi.Sys(), chk.FitsTypeOf, &BlobProperties{})
+
+	fi, err = fs.Stat("dir")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fi.Name(), chk.Equals, "dir")
+	c.Assert(fi.IsDir(), chk.Equals, true)
+	c.Assert(fi.Mode()&os.ModeDir, chk.Not(chk.Equals), os.FileMode(0))
+
+	fi, err = fs.Stat("/")
+	c.Assert(err, chk.IsNil)
+	c.Assert(fi.IsDir(), chk.Equals, true)
+
+	_, err = fs.Stat("missing")
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
+
+func (s *StorageBlobFSSuite) TestReadDir(c *chk.C) {
+	fs, _, done := newTestBlobFS(c, "cnt/a", "cnt/d1/b", "cnt/d1/d2/c", "cnt/d1/e", "cnt/d3/f", "cnt/z")
+	defer done()
+
+	names := func(fis []os.FileInfo) []string {
+		var out []string
+		for _, fi := range fis {
+			n := fi.Name()
+			if fi.IsDir() {
+				n += "/"
+			}
+			out = append(out, n)
+		}
+		return out
+	}
+
+	fis, err := fs.ReadDir("")
+	c.Assert(err, chk.IsNil)
+	c.Assert(names(fis), chk.DeepEquals, []string{"a", "d1/", "d3/", "z"})
+
+	fis, err = fs.ReadDir("d1")
+	c.Assert(err, chk.IsNil)
+	c.Assert(names(fis), chk.DeepEquals, []string{"b", "d2/", "e"})
+
+	_, err = fs.ReadDir("missing")
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
+
+func (s *StorageBlobFSSuite) TestOpen_ReadAndSeek(c *chk.C) {
+	fs, f, done := newTestBlobFS(c)
+	defer done()
+	f.blobs["cnt/file"] = &fakeBlob{data: []byte("0123456789")}
+
+	r, err := fs.Open("file")
+	c.Assert(err, chk.IsNil)
+	defer r.Close()
+
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(r, buf)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(buf), chk.Equals, "0123")
+
+	off, err := r.Seek(-3, io.SeekEnd)
+	c.Assert(err, chk.IsNil)
+	c.Assert(off, chk.Equals, int64(7))
+	rest, err := ioutil.ReadAll(r)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(rest), chk.Equals, "789")
+
+	off, err = r.Seek(2, io.SeekStart)
+	c.Assert(err, chk.IsNil)
+	off, err = r.Seek(1, io.SeekCurrent)
+	c.Assert(err, chk.IsNil)
+	c.Assert(off, chk.Equals, int64(3))
+	_, err = io.ReadFull(r, buf)
+	c.Assert(err, chk.IsNil)
+	c.Assert(string(buf), chk.Equals, "3456")
+
+	_, err = r.Seek(-1, io.SeekStart)
+	c.Assert(err, chk.NotNil)
+	_, err = r.Seek(0, 3)
+	c.Assert(err, chk.NotNil)
+
+	_, err = r.Seek(20, io.SeekStart)
+	c.Assert(err, chk.IsNil)
+	n, err := r.Read(buf)
+	c.Assert(n, chk.Equals, 0)
+	c.Assert(err, chk.Equals, io.EOF)
+
+	_, err = fs.Open("missing")
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
+
+func (s *StorageBlobFSSuite) TestCreate(c *chk.C) {
+	fs, f, done := newTestBlobFS(c)
+	defer done()
+
+	w, err := fs.Create("/dir/small")
+	c.Assert(err, chk.IsNil)
+	_, err = io.WriteString(w, "hello")
+	c.Assert(err, chk.IsNil)
+	c.Assert(f.get("cnt/dir/small"), chk.IsNil)
+	c.Assert(w.Close(), chk.IsNil)
+	c.Assert(string(f.get("cnt/dir/small").data), chk.Equals, "hello")
+
+	_, err = w.Write([]byte("more"))
+	c.Assert(err, chk.NotNil)
+
+	// larger blobs are written in blocks
+	large := bytes.Repeat([]byte("0123456789abcdef"), blobFSBlockSize/8+1)
+	w, err = fs.Create("large")
+	c.Assert(err, chk.IsNil)
+	_, err = io.Copy(w, bytes.NewReader(large))
+	c.Assert(err, chk.IsNil)
+	c.Assert(w.Close(), chk.IsNil)
+	c.Assert(bytes.Equal(f.get("cnt/large").data, large), chk.Equals, true)
+
+	_, err = fs.Create("/")
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *StorageBlobFSSuite) TestRemove(c *chk.C) {
+	fs, f, done := newTestBlobFS(c, "cnt/a", "cnt/dir/b")
+	defer done()
+
+	c.Assert(fs.Remove("a"), chk.IsNil)
+	c.Assert(os.IsNotExist(fs.Remove("a")), chk.Equals, true)
+
+	err := fs.Remove("dir")
+	c.Assert(err, chk.NotNil)
+	c.Assert(os.IsNotExist(err), chk.Equals, false)
+	c.Assert(f.names(), chk.DeepEquals, []string{"cnt/dir/b"})
+}
+
+func (s *StorageBlobFSSuite) TestRename(c *chk.C) {
+	fs, f, done := newTestBlobFS(c, "cnt/a", "cnt/dir/b", "cnt/dir/sub/c", "cnt/dirx")
+	defer done()
+
+	c.Assert(fs.Rename("a", "renamed"), chk.IsNil)
+	c.Assert(fs.Rename("dir", "moved/dir"), chk.IsNil)
+	c.Assert(f.names(), chk.DeepEquals, []string{"cnt/dirx", "cnt/moved/dir/b", "cnt/moved/dir/sub/c", "cnt/renamed"})
+	c.Assert(string(f.get("cnt/moved/dir/sub/c").data), chk.Equals, "cnt/dir/sub/c")
+
+	err := fs.Rename("missing", "x")
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+
+	// renaming to itself keeps the blobs
+	c.Assert(fs.Rename("renamed", "renamed"), chk.IsNil)
+	c.Assert(fs.Rename("moved/dir", "/moved/dir/"), chk.IsNil)
+	c.Assert(fs.Rename("", "/"), chk.IsNil)
+	c.Assert(os.IsNotExist(fs.Rename("missing", "missing")), chk.Equals, true)
+	c.Assert(f.names(), chk.DeepEquals, []string{"cnt/dirx", "cnt/moved/dir/b", "cnt/moved/dir/sub/c", "cnt/renamed"})
+
+	c.Assert(fs.Rename("moved", "moved/inside"), chk.NotNil)
+	c.Assert(fs.Rename("renamed", "renamed/inside"), chk.NotNil)
+	c.Assert(f.names(), chk.DeepEquals, []string{"cnt/dirx", "cnt/moved/dir/b", "cnt/moved/dir/sub/c", "cnt/renamed"})
+}
+
+func (s *StorageBlobFSSuite) TestWalk(c *chk.C) {
+	fs, _, done := newTestBlobFS(c, "cnt/a", "cnt/d1/b", "cnt/d1/d2/c", "cnt/d3/e", "cnt/z")
+	defer done()
+
+	var visited []string
+	err := Walk(fs, "", func(name string, fi os.FileInfo, err error) error {
+		c.Assert(err, chk.IsNil)
+		visited = append(visited, name)
+		if name == "d3" {
+			return filepath.SkipDir
+		}
+		return nil
+	})
+	c.Assert(err, chk.IsNil)
+	c.Assert(visited, chk.DeepEquals, []string{"", "a", "d1", "d1/b", "d1/d2", "d1/d2/c", "d3", "z"})
+
+	visited = nil
+	err = Walk(fs, "d1", func(name string, fi os.FileInfo, err error) error {
+		visited = append(visited, name)
+		return nil
+	})
+	c.Assert(err, chk.IsNil)
+	c.Assert(visited, chk.DeepEquals, []string{"d1", "d1/b", "d1/d2", "d1/d2/c"})
+
+	err = Walk(fs, "missing", func(name string, fi os.FileInfo, err error) error {
+		return err
+	})
+	c.Assert(os.IsNotExist(err), chk.Equals, true)
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// DefaultBulkConcurrency is the number of blobs processed in parallel by the
+// bulk operations unless configured otherwise.
+const DefaultBulkConcurrency = 16
+
+// BulkOptions defines the set of customizable parameters of the bulk blob
+// operations, which apply a single operation to every blob under a prefix.
+type BulkOptions struct {
+	// Concurrency is the number of blobs processed in parallel. Zero
+	// selects DefaultBulkConcurrency.
+	Concurrency int
+
+	// DryRun lists and reports the blobs which would be processed without
+	// modifying any of them.
+	DryRun bool
+
+	// IncludeSnapshots makes DeleteBlobsWithPrefix delete the snapshots of
+	// the blobs along with them. Otherwise blobs having snapshots cannot be
+	// deleted and are reported as failed. Snapshots are read-only, so other
+	// operations only ever process the base blobs.
+	IncludeSnapshots bool
+
+	// Progress, if not nil, is called after each blob is processed. Calls
+	// are serialized.
+	Progress func(BulkProgress)
+}
+
+// BulkProgress reports the outcome of processing a single blob in a bulk
+// operation along with the totals so far.
+type BulkProgress struct {
+	Blob      string
+	Err       error
+	Processed int
+	Failed    int
+}
+
+// BulkItemError is the error of processing a single blob in a bulk operation.
+type BulkItemError struct {
+	Blob string
+	Err  error
+}
+
+// BulkResult contains the outcome of a bulk operation. Processed is the
+// number of blobs processed successfully, or which would have been processed
+// in dry-run mode.
+type BulkResult struct {
+	Processed int
+	Errors    []BulkItemError
+}
+
+// DeleteBlobsWithPrefix deletes every blob in the container whose name starts
+// with prefix.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179413.aspx
+func (b BlobStorageClient) DeleteBlobsWithPrefix(container, prefix string, opts BulkOptions) (BulkResult, error) {
+	return b.forEachBlob(container, prefix, opts, func(blob Blob) error {
+		uri := b.client.getEndpoint(blobServiceName, pathForBlob(container, blob.Name), url.Values{})
+		headers := b.client.getStandardHeaders()
+		if opts.IncludeSnapshots {
+			headers["x-ms-delete-snapshots"] = "include"
+		}
+
+		resp, err := b.client.exec("DELETE", uri, headers, nil)
+		if err != nil {
+			return err
+		}
+		defer resp.body.Close()
+		return checkRespCode(resp.statusCode, []int{http.StatusAccepted})
+	})
+}
+
+// CopyBlobsWithPrefix copies every blob in the source container whose name
+// starts with prefix to the destination container, replacing prefix in the
+// blob names with destinationPrefix. Each copy is waited for to complete.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd894037.aspx
+func (b BlobStorageClient) CopyBlobsWithPrefix(container, prefix, destinationContainer, destinationPrefix string, opts BulkOptions) (BulkResult, error) {
+	return b.forEachBlob(container, prefix, opts, func(blob Blob) error {
+		name := destinationPrefix + strings.TrimPrefix(blob.Name, prefix)
+		return b.CopyBlob(destinationContainer, name, b.GetBlobURL(container, blob.Name))
+	})
+}
+
+// SetBlobPropertiesWithPrefix replaces the properties of every blob in the
+// container whose name starts with prefix.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/ee691966.aspx
+func (b BlobStorageClient) SetBlobPropertiesWithPrefix(container, prefix string, props BlobProperties, opts BulkOptions) (BulkResult, error) {
+	return b.forEachBlob(container, prefix, opts, func(blob Blob) error {
+		return b.SetBlobProperties(container, blob.Name, props)
+	})
+}
+
+// SetBlobMetadataWithPrefix replaces the metadata of every blob in the
+// container whose name starts with prefix.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179414.aspx
+func (b BlobStorageClient) SetBlobMetadataWithPrefix(container, prefix string, metadata map[string]string, opts BulkOptions) (BulkResult, error) {
+	return b.forEachBlob(container, prefix, opts, func(blob Blob) error {
+		return b.SetBlobMetadata(container, blob.Name, metadata)
+	})
+}
+
+// forEachBlob lists the blobs under prefix page by page and calls fn on
+// each of them from a pool of workers. Errors of fn are collected in the
+// result; an error is only returned if listing the blobs fails, in which
+// case the blobs listed so far are still processed.
+func (b BlobStorageClient) forEachBlob(container, prefix string, opts BulkOptions, fn func(Blob) error) (BulkResult, error) {
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBulkConcurrency
+	}
+
+	var (
+		mu     sync.Mutex
+		result BulkResult
+		wg     sync.WaitGroup
+		blobs  = make(chan Blob)
+	)
+	report := func(name string, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		if err != nil {
+			result.Errors = append(result.Errors, BulkItemError{Blob: name, Err: err})
+		} else {
+			result.Processed++
+		}
+		if opts.Progress != nil {
+			opts.Progress(BulkProgress{
+				Blob:      name,
+				Err:       err,
+				Processed: result.Processed,
+				Failed:    len(result.Errors),
+			})
+		}
+	}
+
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for blob := range blobs {
+				var err error
+				if !opts.DryRun {
+					err = fn(blob)
+				}
+				report(blob.Name, err)
+			}
+		}()
+	}
+
+	params := ListBlobsParameters{Prefix: prefix}
+	var err error
+	for {
+		var resp BlobListResponse
+		resp, err = b.ListBlobs(container, params)
+		if err != nil {
+			break
+		}
+		for _, blob := range resp.Blobs {
+			blobs <- blob
+		}
+		if resp.NextMarker == "" {
+			break
+		}
+		params.Marker = resp.NextMarker
+	}
+	close(blobs)
+	wg.Wait()
+
+	return result, err
+}
@@ -0,0 +1,276 @@
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	chk "github.com/Azure/azure-sdk-for-go/Godeps/_workspace/src/gopkg.in/check.v1"
+)
+
+type StorageBulkSuite struct{}
+
+var _ = chk.Suite(&StorageBulkSuite{})
+
+type fakeBlob struct {
+	data        []byte
+	contentType string
+	metadata    map[string]string
+	snapshots   int
+}
+
+// fakeBlobServer emulates the subset of the Blob service used by the bulk
+// operations on containers kept in memory. Blob listings are paginated at
+// pageSize blobs. Containers exist as long as they have blobs.
+type fakeBlobServer struct {
+	mu       sync.Mutex
+	blobs    map[string]*fakeBlob // keyed by "container/name"
+	pageSize int
+	requests int
+}
+
+func newFakeBlobServer(names ...string) *fakeBlobServer {
+	f := &fakeBlobServer{blobs: make(map[string]*fakeBlob), pageSize: 2}
+	for _, n := range names {
+		f.blobs[n] = &fakeBlob{data: []byte(n)}
+	}
+	return f
+}
+
+func (f *fakeBlobServer) names() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var out []string
+	for n := range f.blobs {
+		out = append(out, n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (f *fakeBlobServer) get(name string) *fakeBlob {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.blobs[name]
+}
+
+func (f *fakeBlobServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+
+	fail := func(code int, errCode string) {
+		w.WriteHeader(code)
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>%s</Code><Message>m</Message></Error>`, errCode)
+	}
+
+	q := r.URL.Query()
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if q.Get("restype") == "container" && q.Get("comp") == "list" {
+		f.list(w, path, q)
+		return
+	}
+
+	blob, ok := f.blobs[path]
+	switch {
+	case r.Method == "PUT" && r.Header.Get("x-ms-copy-source") != "":
+		u, err := url.Parse(r.Header.Get("x-ms-copy-source"))
+		if err != nil {
+			fail(http.StatusBadRequest, "InvalidHeaderValue")
+			return
+		}
+		src, ok := f.blobs[strings.TrimPrefix(u.Path, "/")]
+		if !ok {
+			fail(http.StatusNotFound, "CannotVerifyCopySource")
+			return
+		}
+		f.blobs[path] = &fakeBlob{data: src.data, contentType: src.contentType, metadata: src.metadata}
+		w.Header().Set("x-ms-copy-id", "copy")
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == "PUT" && q.Get("comp") == "":
+		data, _ := ioutil.ReadAll(r.Body)
+		f.blobs[path] = &fakeBlob{data: data, contentType: r.Header.Get("x-ms-blob-content-type")}
+		w.WriteHeader(http.StatusCreated)
+	case !ok:
+		fail(http.StatusNotFound, "BlobNotFound")
+	case r.Method == "GET" || r.Method == "HEAD":
+		w.Header().Set("Content-Length", strconv.Itoa(len(blob.data)))
+		w.Header().Set("Content-Type", blob.contentType)
+		w.Header().Set("x-ms-blob-type", string(BlobTypeBlock))
+		w.Header().Set("x-ms-copy-id", "copy")
+		w.Header().Set("x-ms-copy-status", blobCopyStatusSuccess)
+		w.WriteHeader(http.StatusOK)
+		if r.Method == "GET" {
+			w.Write(blob.data)
+		}
+	case r.Method == "DELETE":
+		if blob.snapshots > 0 && r.Header.Get("x-ms-delete-snapshots") != "include" {
+			fail(http.StatusConflict, "SnapshotsPresent")
+			return
+		}
+		delete(f.blobs, path)
+		w.WriteHeader(http.StatusAccepted)
+	case r.Method == "PUT" && q.Get("comp") == "properties":
+		blob.contentType = r.Header.Get("x-ms-blob-content-type")
+		w.WriteHeader(http.StatusOK)
+	case r.Method == "PUT" && q.Get("comp") == "metadata":
+		blob.metadata = make(map[string]string)
+		for k, v := range r.Header {
+			if strings.HasPrefix(k, userDefinedMetadataHeaderPrefix) {
+				blob.metadata[strings.ToLower(k[len(userDefinedMetadataHeaderPrefix):])] = v[0]
+			}
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		fail(http.StatusBadRequest, "UnsupportedHttpVerb")
+	}
+}
+
+func (f *fakeBlobServer) list(w http.ResponseWriter, container string, q url.Values) {
+	var names []string
+	found := false
+	for n := range f.blobs {
+		found = found || strings.HasPrefix(n, container+"/")
+		if strings.HasPrefix(n, container+"/"+q.Get("prefix")) {
+			names = append(names, strings.TrimPrefix(n, container+"/"))
+		}
+	}
+	if !found {
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>ContainerNotFound</Code><Message>m</Message></Error>`)
+		return
+	}
+	sort.Strings(names)
+
+	var out BlobListResponse
+	for _, n := range names {
+		if n <= q.Get("marker") && q.Get("marker") != "" {
+			continue
+		}
+		if len(out.Blobs) == f.pageSize {
+			out.NextMarker = out.Blobs[len(out.Blobs)-1].Name
+			break
+		}
+		blob := f.blobs[container+"/"+n]
+		out.Blobs = append(out.Blobs, Blob{Name: n, Properties: BlobProperties{
+			ContentLength: int64(len(blob.data)),
+			ContentType:   blob.contentType,
+			BlobType:      BlobTypeBlock,
+		}})
+	}
+	b, _ := xml.Marshal(out)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
+
+func newBulkTestClient(c *chk.C, f *fakeBlobServer) (BlobStorageClient, func()) {
+	cli, err := NewBasicClient("foo", "YmFy")
+	c.Assert(err, chk.IsNil)
+	ts := useTestServer(&cli, f)
+	return cli.GetBlobService(), ts.Close
+}
+
+func (s *StorageBulkSuite) TestDeleteBlobsWithPrefix(c *chk.C) {
+	f := newFakeBlobServer("cnt/build/1", "cnt/build/2", "cnt/build/3", "cnt/build/4", "cnt/build/5", "cnt/keep", "other/build/1")
+	cli, done := newBulkTestClient(c, f)
+	defer done()
+
+	var progress []BulkProgress
+	res, err := cli.DeleteBlobsWithPrefix("cnt", "build/", BulkOptions{
+		Concurrency: 3,
+		Progress:    func(p BulkProgress) { progress = append(progress, p) },
+	})
+	c.Assert(err, chk.IsNil)
+	c.Assert(res.Processed, chk.Equals, 5)
+	c.Assert(res.Errors, chk.HasLen, 0)
+	c.Assert(f.names(), chk.DeepEquals, []string{"cnt/keep", "other/build/1"})
+
+	c.Assert(progress, chk.HasLen, 5)
+	c.Assert(progress[4].Processed, chk.Equals, 5)
+	seen := map[string]bool{}
+	for _, p := range progress {
+		seen[p.Blob] = true
+	}
+	c.Assert(seen, chk.HasLen, 5)
+}
+
+func (s *StorageBulkSuite) TestDeleteBlobsWithPrefix_DryRun(c *chk.C) {
+	f := newFakeBlobServer("cnt/a", "cnt/b", "cnt/c")
+	cli, done := newBulkTestClient(c, f)
+	defer done()
+
+	res, err := cli.DeleteBlobsWithPrefix("cnt", "", BulkOptions{DryRun: true})
+	c.Assert(err, chk.IsNil)
+	c.Assert(res.Processed, chk.Equals, 3)
+	c.Assert(f.names(), chk.HasLen, 3)
+	c.Assert(f.requests, chk.Equals, 2) // listing only
+}
+
+func (s *StorageBulkSuite) TestDeleteBlobsWithPrefix_Snapshots(c *chk.C) {
+	f := newFakeBlobServer("cnt/a", "cnt/b", "cnt/c")
+	f.blobs["cnt/b"].snapshots = 2
+	cli, done := newBulkTestClient(c, f)
+	defer done()
+
+	res, err := cli.DeleteBlobsWithPrefix("cnt", "", BulkOptions{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(res.Processed, chk.Equals, 2)
+	c.Assert(res.Errors, chk.HasLen, 1)
+	c.Assert(res.Errors[0].Blob, chk.Equals, "b")
+	c.Assert(res.Errors[0].Err, chk.NotNil)
+	c.Assert(f.names(), chk.DeepEquals, []string{"cnt/b"})
+
+	res, err = cli.DeleteBlobsWithPrefix("cnt", "", BulkOptions{IncludeSnapshots: true})
+	c.Assert(err, chk.IsNil)
+	c.Assert(res.Processed, chk.Equals, 1)
+	c.Assert(f.names(), chk.HasLen, 0)
+}
+
+func (s *StorageBulkSuite) TestDeleteBlobsWithPrefix_ListingFails(c *chk.C) {
+	f := newFakeBlobServer("cnt/a")
+	cli, done := newBulkTestClient(c, f)
+	defer done()
+
+	_, err := cli.DeleteBlobsWithPrefix("missing", "", BulkOptions{})
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *StorageBulkSuite) TestCopyBlobsWithPrefix(c *chk.C) {
+	f := newFakeBlobServer("src/logs/1", "src/logs/2", "src/logs/3", "src/other")
+	cli, done := newBulkTestClient(c, f)
+	defer done()
+
+	res, err := cli.CopyBlobsWithPrefix("src", "logs/", "dst", "archive/", BulkOptions{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(res.Processed, chk.Equals, 3)
+	c.Assert(f.names(), chk.DeepEquals, []string{
+		"dst/archive/1", "dst/archive/2", "dst/archive/3",
+		"src/logs/1", "src/logs/2", "src/logs/3", "src/other",
+	})
+	c.Assert(string(f.get("dst/archive/2").data), chk.Equals, "src/logs/2")
+}
+
+func (s *StorageBulkSuite) TestSetBlobPropertiesAndMetadataWithPrefix(c *chk.C) {
+	f := newFakeBlobServer("cnt/img/a.png", "cnt/img/b.png", "cnt/doc.txt")
+	cli, done := newBulkTestClient(c, f)
+	defer done()
+
+	res, err := cli.SetBlobPropertiesWithPrefix("cnt", "img/", BlobProperties{ContentType: "image/png"}, BulkOptions{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(res.Processed, chk.Equals, 2)
+	c.Assert(f.get("cnt/img/a.png").contentType, chk.Equals, "image/png")
+	c.Assert(f.get("cnt/doc.txt").contentType, chk.Equals, "")
+
+	res, err = cli.SetBlobMetadataWithPrefix("cnt", "", map[string]string{"retention": "short"}, BulkOptions{Concurrency: 1})
+	c.Assert(err, chk.IsNil)
+	c.Assert(res.Processed, chk.Equals, 3)
+	for _, n := range f.names() {
+		c.Assert(f.get(n).metadata, chk.DeepEquals, map[string]string{"retention": "short"})
+	}
+}
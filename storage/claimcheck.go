@@ -0,0 +1,274 @@
+package storage
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// DefaultClaimCheckThreshold is the message size above which
+// ClaimCheckQueueClient stores payloads in blobs unless configured otherwise.
+// It leaves room below the 64 KiB message limit for the XML encoding of the
+// message text.
+const DefaultClaimCheckThreshold = 48 * 1024
+
+const (
+	// claimCheckPrefix starts the text of every reference envelope.
+	claimCheckPrefix = `{"ClaimCheck":`
+	// claimCheckInlinePrefix starts the text of messages put on the queue
+	// directly whose text would otherwise be taken for an envelope. Their
+	// text is base64-encoded in an inline envelope.
+	claimCheckInlinePrefix = `{"ClaimCheckInline":`
+)
+
+// ClaimCheckQueueClient puts and gets queue messages of arbitrary size using
+// the claim-check pattern: payloads larger than the threshold are stored in a
+// blob container and only a small reference envelope is put on the queue.
+// Retrieved messages have their payloads resolved from the referenced blobs,
+// and the blobs are deleted along with their messages. Only references to
+// payload blobs of the same queue in the container of the client are
+// followed, so that the author of a message cannot make the client read or
+// delete other blobs.
+type ClaimCheckQueueClient struct {
+	queue     QueueServiceClient
+	blob      BlobStorageClient
+	container string
+	threshold int
+}
+
+// ClaimCheckMessage is a message retrieved through a ClaimCheckQueueClient.
+// MessageText holds the resolved payload.
+type ClaimCheckMessage struct {
+	GetMessageResponse
+
+	// Container and Blob name the blob holding the payload, and are empty
+	// if the payload was put on the queue directly.
+	Container string
+	Blob      string
+}
+
+type claimCheckEnvelope struct {
+	ClaimCheck claimCheckReference
+}
+
+type claimCheckInlineEnvelope struct {
+	ClaimCheckInline []byte
+}
+
+type claimCheckReference struct {
+	Container string
+	Blob      string
+	Size      int
+}
+
+// NewClaimCheckQueueClient returns a ClaimCheckQueueClient which stores
+// payloads larger than threshold bytes as blobs in the given container, which
+// must exist. A threshold of zero selects DefaultClaimCheckThreshold.
+func NewClaimCheckQueueClient(queue QueueServiceClient, blob BlobStorageClient, container string, threshold int) ClaimCheckQueueClient {
+	if threshold <= 0 {
+		threshold = DefaultClaimCheckThreshold
+	}
+	return ClaimCheckQueueClient{
+		queue:     queue,
+		blob:      blob,
+		container: container,
+		threshold: threshold,
+	}
+}
+
+// PutMessage adds a new message to the back of the message queue. If the
+// message is larger than the threshold, it is uploaded to a blob first and a
+// reference to the blob is put on the queue instead.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179346.aspx
+func (c ClaimCheckQueueClient) PutMessage(queue string, message string, params PutMessageParameters) error {
+	text, err := escapeClaimCheckText(message)
+	if err != nil {
+		return err
+	}
+	if len(text) <= c.threshold {
+		return c.queue.PutMessage(queue, text, params)
+	}
+
+	name, err := claimCheckBlobName(queue)
+	if err != nil {
+		return err
+	}
+	if err := c.blob.CreateBlockBlobFromReader(c.container, name, uint64(len(message)), strings.NewReader(message), nil); err != nil {
+		return err
+	}
+
+	envelope, err := json.Marshal(claimCheckEnvelope{claimCheckReference{
+		Container: c.container,
+		Blob:      name,
+		Size:      len(message),
+	}})
+	if err != nil {
+		return err
+	}
+	if err := c.queue.PutMessage(queue, string(envelope), params); err != nil {
+		c.blob.DeleteBlobIfExists(c.container, name)
+		return err
+	}
+	return nil
+}
+
+// GetMessages retrieves one or more messages from the front of the queue and
+// resolves the payloads of messages referencing blobs.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179474.aspx
+func (c ClaimCheckQueueClient) GetMessages(queue string, params GetMessagesParameters) ([]ClaimCheckMessage, error) {
+	r, err := c.queue.GetMessages(queue, params)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ClaimCheckMessage, len(r.QueueMessagesList))
+	for i, m := range r.QueueMessagesList {
+		out[i].GetMessageResponse = m
+		text, ref, err := c.resolve(queue, m.MessageText)
+		if err != nil {
+			return out, fmt.Errorf("storage: cannot resolve payload of message %s: %v", m.MessageID, err)
+		}
+		out[i].MessageText = text
+		out[i].Container = ref.Container
+		out[i].Blob = ref.Blob
+	}
+	return out, nil
+}
+
+// PeekMessages retrieves one or more messages from the front of the queue
+// without altering their visibility and resolves the payloads of messages
+// referencing blobs.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179472.aspx
+func (c ClaimCheckQueueClient) PeekMessages(queue string, params PeekMessagesParameters) (PeekMessagesResponse, error) {
+	r, err := c.queue.PeekMessages(queue, params)
+	if err != nil {
+		return r, err
+	}
+
+	for i, m := range r.QueueMessagesList {
+		text, _, err := c.resolve(queue, m.MessageText)
+		if err != nil {
+			return r, fmt.Errorf("storage: cannot resolve payload of message %s: %v", m.MessageID, err)
+		}
+		r.QueueMessagesList[i].MessageText = text
+	}
+	return r, nil
+}
+
+// DeleteMessage deletes the specified message and the blob holding its
+// payload, if any. The message is deleted first, so that a failure cannot
+// leave a message behind whose payload is gone.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179347.aspx
+func (c ClaimCheckQueueClient) DeleteMessage(queue string, message ClaimCheckMessage) error {
+	ref := claimCheckReference{Container: message.Container, Blob: message.Blob}
+	if ref.Blob != "" && !c.isPayloadBlob(queue, ref) {
+		return fmt.Errorf("storage: blob %s/%s is not a payload of queue %s", ref.Container, ref.Blob, queue)
+	}
+	if err := c.queue.DeleteMessage(queue, message.MessageID, message.PopReceipt); err != nil {
+		return err
+	}
+	if ref.Blob == "" {
+		return nil
+	}
+	_, err := c.blob.DeleteBlobIfExists(ref.Container, ref.Blob)
+	return err
+}
+
+// resolve returns the payload of a message retrieved from the given queue
+// and the reference to the blob holding it, which is empty if the payload was
+// put on the queue directly.
+func (c ClaimCheckQueueClient) resolve(queue, text string) (string, claimCheckReference, error) {
+	if strings.HasPrefix(text, claimCheckInlinePrefix) {
+		var e claimCheckInlineEnvelope
+		if err := json.Unmarshal([]byte(text), &e); err == nil {
+			return string(e.ClaimCheckInline), claimCheckReference{}, nil
+		}
+		return text, claimCheckReference{}, nil
+	}
+
+	ref, ok := parseClaimCheckEnvelope(text)
+	if !ok || !c.isPayloadBlob(queue, ref) {
+		return text, claimCheckReference{}, nil
+	}
+	payload, err := c.getPayload(ref)
+	if err != nil {
+		return text, claimCheckReference{}, err
+	}
+	return payload, ref, nil
+}
+
+// isPayloadBlob reports whether the reference is to a blob PutMessage of
+// this client could have created for the queue.
+func (c ClaimCheckQueueClient) isPayloadBlob(queue string, ref claimCheckReference) bool {
+	if ref.Container != c.container || !strings.HasPrefix(ref.Blob, queue+"/") {
+		return false
+	}
+	id := strings.TrimPrefix(ref.Blob, queue+"/")
+	if len(id) != 2*claimCheckIDLength {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func (c ClaimCheckQueueClient) getPayload(ref claimCheckReference) (string, error) {
+	body, err := c.blob.GetBlob(ref.Container, ref.Blob)
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	b, err := ioutil.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	if len(b) != ref.Size {
+		return "", fmt.Errorf("storage: payload blob has %d bytes, expected %d", len(b), ref.Size)
+	}
+	return string(b), nil
+}
+
+// parseClaimCheckEnvelope returns the blob reference in the message text if
+// it is a reference envelope.
+func parseClaimCheckEnvelope(text string) (claimCheckReference, bool) {
+	var e claimCheckEnvelope
+	if !strings.HasPrefix(text, claimCheckPrefix) {
+		return e.ClaimCheck, false
+	}
+	if err := json.Unmarshal([]byte(text), &e); err != nil || e.ClaimCheck.Blob == "" {
+		return e.ClaimCheck, false
+	}
+	return e.ClaimCheck, true
+}
+
+// escapeClaimCheckText returns the text of a message put on the queue
+// directly, which is wrapped in an inline envelope if it could be taken for
+// an envelope.
+func escapeClaimCheckText(message string) (string, error) {
+	if !strings.HasPrefix(message, claimCheckPrefix) && !strings.HasPrefix(message, claimCheckInlinePrefix) {
+		return message, nil
+	}
+	b, err := json.Marshal(claimCheckInlineEnvelope{[]byte(message)})
+	return string(b), err
+}
+
+// claimCheckIDLength is the number of random bytes in payload blob names.
+const claimCheckIDLength = 16
+
+// claimCheckBlobName returns a unique name for the payload blob of a message
+// put on the given queue.
+func claimCheckBlobName(queue string) (string, error) {
+	id := make([]byte, claimCheckIDLength)
+	if _, err := io.ReadFull(rand.Reader, id); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", queue, hex.EncodeToString(id)), nil
+}
@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	chk "github.com/Azure/azure-sdk-for-go/Godeps/_workspace/src/gopkg.in/check.v1"
+)
+
+type StorageClaimCheckSuite struct{}
+
+var _ = chk.Suite(&StorageClaimCheckSuite{})
+
+func (s *StorageClaimCheckSuite) Test_parseClaimCheckEnvelope(c *chk.C) {
+	b, err := json.Marshal(claimCheckEnvelope{claimCheckReference{"cnt", "q/blob", 10}})
+	c.Assert(err, chk.IsNil)
+
+	ref, ok := parseClaimCheckEnvelope(string(b))
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(ref, chk.DeepEquals, claimCheckReference{"cnt", "q/blob", 10})
+
+	for _, text := range []string{"", "foo", `{"ClaimCheck":`, `{"ClaimCheck":{}}`, `{"Other":{"Blob":"b"}}`} {
+		_, ok = parseClaimCheckEnvelope(text)
+		c.Assert(ok, chk.Equals, false, chk.Commentf("%q", text))
+	}
+}
+
+func (s *StorageClaimCheckSuite) Test_claimCheckBlobName(c *chk.C) {
+	n1, err := claimCheckBlobName("q")
+	c.Assert(err, chk.IsNil)
+	n2, err := claimCheckBlobName("q")
+	c.Assert(err, chk.IsNil)
+	c.Assert(n1, chk.Matches, "q/[0-9a-f]{32}")
+	c.Assert(n1, chk.Not(chk.Equals), n2)
+}
+
+func (s *StorageClaimCheckSuite) TestNewClaimCheckQueueClient_DefaultThreshold(c *chk.C) {
+	cli := NewClaimCheckQueueClient(QueueServiceClient{}, BlobStorageClient{}, "cnt", 0)
+	c.Assert(cli.threshold, chk.Equals, DefaultClaimCheckThreshold)
+}
+
+func (s *StorageClaimCheckSuite) Test_escapeClaimCheckText(c *chk.C) {
+	cli := ClaimCheckQueueClient{container: "cnt"}
+	for _, text := range []string{"", "foo", `{"ClaimCheck":{"Container":"cnt","Blob":"q/b","Size":1}}`, `{"ClaimCheckInline":"Zm9v"}`} {
+		escaped, err := escapeClaimCheckText(text)
+		c.Assert(err, chk.IsNil)
+		_, ok := parseClaimCheckEnvelope(escaped)
+		c.Assert(ok, chk.Equals, false, chk.Commentf("%q", text))
+
+		out, ref, err := cli.resolve("q", escaped)
+		c.Assert(err, chk.IsNil)
+		c.Assert(out, chk.Equals, text)
+		c.Assert(ref, chk.Equals, claimCheckReference{})
+	}
+}
+
+func (s *StorageClaimCheckSuite) Test_isPayloadBlob(c *chk.C) {
+	cli := ClaimCheckQueueClient{container: "cnt"}
+	name, err := claimCheckBlobName("q")
+	c.Assert(err, chk.IsNil)
+	c.Assert(cli.isPayloadBlob("q", claimCheckReference{Container: "cnt", Blob: name}), chk.Equals, true)
+
+	for _, ref := range []claimCheckReference{
+		{Container: "other", Blob: name},
+		{Container: "cnt", Blob: "secret"},
+		{Container: "cnt", Blob: "q/secret"},
+		{Container: "cnt", Blob: "q2/" + strings.TrimPrefix(name, "q/")},
+		{Container: "cnt", Blob: name + "/../secret"},
+	} {
+		c.Assert(cli.isPayloadBlob("q", ref), chk.Equals, false, chk.Commentf("%+v", ref))
+	}
+}
+
+// fakeQueueServer emulates a single queue kept in memory, whose messages are
+// identified by their index and are always visible.
+type fakeQueueServer struct {
+	mu       sync.Mutex
+	messages []string
+	deleted  map[int]bool
+}
+
+func (f *fakeQueueServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.Method {
+	case "POST":
+		var m putMessageRequest
+		if err := xml.NewDecoder(r.Body).Decode(&m); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.messages = append(f.messages, m.MessageText)
+		w.WriteHeader(http.StatusCreated)
+	case "GET":
+		var out GetMessagesResponse
+		for i, text := range f.messages {
+			if !f.deleted[i] {
+				out.QueueMessagesList = append(out.QueueMessagesList, GetMessageResponse{
+					MessageID:   strconv.Itoa(i),
+					PopReceipt:  "r",
+					MessageText: text,
+				})
+			}
+		}
+		b, _ := xml.Marshal(out)
+		w.WriteHeader(http.StatusOK)
+		w.Write(b)
+	case "DELETE":
+		i, err := strconv.Atoi(r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+		if err != nil || i >= len(f.messages) || f.deleted[i] {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		f.deleted[i] = true
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (f *fakeQueueServer) remaining() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return len(f.messages) - len(f.deleted)
+}
+
+// serviceRouter dispatches requests to the handler of their service.
+type serviceRouter map[string]http.Handler
+
+func (r serviceRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	service := strings.Split(req.Host, ".")[1]
+	r[service].ServeHTTP(w, req)
+}
+
+func newTestClaimCheckClient(c *chk.C, threshold int) (ClaimCheckQueueClient, *fakeQueueServer, *fakeBlobServer, func()) {
+	cli, err := NewBasicClient("foo", "YmFy")
+	c.Assert(err, chk.IsNil)
+	q := &fakeQueueServer{deleted: make(map[int]bool)}
+	b := newFakeBlobServer()
+	ts := useTestServer(&cli, serviceRouter{"queue": q, "blob": b})
+	return NewClaimCheckQueueClient(cli.GetQueueService(), cli.GetBlobService(), "cnt", threshold), q, b, ts.Close
+}
+
+func (s *StorageClaimCheckSuite) TestGetMessages_ResolvesOwnPayloads(c *chk.C) {
+	cli, q, b, done := newTestClaimCheckClient(c, 128)
+	defer done()
+
+	large := strings.Repeat("x", 200)
+	lookalike := `{"ClaimCheck":{"Container":"cnt","Blob":"secret","Size":6}}`
+	c.Assert(cli.PutMessage("q", large, PutMessageParameters{}), chk.IsNil)
+	c.Assert(cli.PutMessage("q", lookalike, PutMessageParameters{}), chk.IsNil)
+	c.Assert(b.names(), chk.HasLen, 1)
+	payload := b.names()[0]
+
+	msgs, err := cli.GetMessages("q", GetMessagesParameters{NumOfMessages: 2})
+	c.Assert(err, chk.IsNil)
+	c.Assert(msgs, chk.HasLen, 2)
+	c.Assert(msgs[0].MessageText, chk.Equals, large)
+	c.Assert(msgs[0].Container+"/"+msgs[0].Blob, chk.Equals, payload)
+	c.Assert(msgs[1].MessageText, chk.Equals, lookalike)
+	c.Assert(msgs[1].Blob, chk.Equals, "")
+
+	for _, m := range msgs {
+		c.Assert(cli.DeleteMessage("q", m), chk.IsNil)
+	}
+	c.Assert(q.remaining(), chk.Equals, 0)
+	c.Assert(b.names(), chk.HasLen, 0)
+}
+
+func (s *StorageClaimCheckSuite) TestGetMessages_IgnoresForeignReferences(c *chk.C) {
+	cli, q, b, done := newTestClaimCheckClient(c, 64)
+	defer done()
+	b.blobs["cnt/secret"] = &fakeBlob{data: []byte("secret")}
+	b.blobs["other/q/00000000000000000000000000000000"] = &fakeBlob{data: []byte("secret")}
+
+	// put by a message author with the plain queue client
+	for _, text := range []string{
+		`{"ClaimCheck":{"Container":"cnt","Blob":"secret","Size":6}}`,
+		`{"ClaimCheck":{"Container":"other","Blob":"q/00000000000000000000000000000000","Size":6}}`,
+	} {
+		c.Assert(cli.queue.PutMessage("q", text, PutMessageParameters{}), chk.IsNil)
+	}
+
+	msgs, err := cli.GetMessages("q", GetMessagesParameters{NumOfMessages: 2})
+	c.Assert(err, chk.IsNil)
+	c.Assert(msgs, chk.HasLen, 2)
+	for _, m := range msgs {
+		c.Assert(m.MessageText, chk.Not(chk.Equals), "secret")
+		c.Assert(m.Blob, chk.Equals, "")
+		c.Assert(cli.DeleteMessage("q", m), chk.IsNil)
+	}
+	c.Assert(b.names(), chk.HasLen, 2)
+
+	// forged messages are rejected before anything is deleted
+	c.Assert(cli.queue.PutMessage("q", "foo", PutMessageParameters{}), chk.IsNil)
+	forged := ClaimCheckMessage{Container: "other", Blob: "q/00000000000000000000000000000000"}
+	forged.MessageID, forged.PopReceipt = "2", "r"
+	c.Assert(cli.DeleteMessage("q", forged), chk.NotNil)
+	c.Assert(q.remaining(), chk.Equals, 1)
+	c.Assert(b.names(), chk.HasLen, 2)
+}
+
+func (s *StorageClaimCheckSuite) TestPutMessage_GetMessages_DeleteMessage(c *chk.C) {
+	api := getBasicClient(c)
+	queue := api.GetQueueService()
+	blob := api.GetBlobService()
+
+	q := randString(20)
+	c.Assert(queue.CreateQueue(q), chk.IsNil)
+	defer queue.DeleteQueue(q)
+	cnt := randContainer()
+	c.Assert(blob.CreateContainer(cnt, ContainerAccessTypePrivate), chk.IsNil)
+	defer blob.DeleteContainer(cnt)
+
+	cli := NewClaimCheckQueueClient(queue, blob, cnt, 1024)
+	small := randString(1024)
+	large := randString(256 * 1024)
+	c.Assert(cli.PutMessage(q, small, PutMessageParameters{}), chk.IsNil)
+	c.Assert(cli.PutMessage(q, large, PutMessageParameters{}), chk.IsNil)
+
+	blobs, err := blob.ListBlobs(cnt, ListBlobsParameters{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(blobs.Blobs), chk.Equals, 1)
+
+	peeked, err := cli.PeekMessages(q, PeekMessagesParameters{NumOfMessages: 2})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(peeked.QueueMessagesList), chk.Equals, 2)
+	c.Assert(peeked.QueueMessagesList[1].MessageText, chk.Equals, large)
+
+	msgs, err := cli.GetMessages(q, GetMessagesParameters{NumOfMessages: 2})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(msgs), chk.Equals, 2)
+	c.Assert(msgs[0].MessageText, chk.Equals, small)
+	c.Assert(msgs[0].Blob, chk.Equals, "")
+	c.Assert(msgs[1].MessageText, chk.Equals, large)
+	c.Assert(msgs[1].Container, chk.Equals, cnt)
+	c.Assert(msgs[1].Blob, chk.Equals, blobs.Blobs[0].Name)
+
+	for _, m := range msgs {
+		c.Assert(cli.DeleteMessage(q, m), chk.IsNil)
+	}
+	blobs, err = blob.ListBlobs(cnt, ListBlobsParameters{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(blobs.Blobs), chk.Equals, 0)
+}
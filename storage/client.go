@@ -11,8 +11,6 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
-	"regexp"
-	"sort"
 	"strconv"
 	"strings"
 )
@@ -37,8 +35,16 @@ const (
 // Client is the object that needs to be constructed to perform
 // operations on the storage account.
 type Client struct {
+	// HTTPClient is the client used to send requests to the storage
+	// service. If nil, a default client is used.
+	HTTPClient *http.Client
+
+	// LocationMode selects the endpoints read requests are sent to. The
+	// zero value sends all requests to the primary endpoint.
+	LocationMode LocationMode
+
 	accountName string
-	accountKey  []byte
+	keys        *accountKeys
 	useHTTPS    bool
 	baseURL     string
 	apiVersion  string
@@ -97,23 +103,46 @@ func NewBasicClient(accountName, accountKey string) (Client, error) {
 // to specify whether to use HTTPS, a specific REST API version or a custom
 // storage endpoint than Azure Public Cloud.
 func NewClient(accountName, accountKey, blobServiceBaseURL, apiVersion string, useHTTPS bool) (Client, error) {
+	return NewClientWithKeys(accountName, accountKey, "", blobServiceBaseURL, apiVersion, useHTTPS)
+}
+
+// NewBasicClientWithKeys constructs a Client with given storage service name
+// and both of its access keys. See NewClientWithKeys for how the keys are
+// used.
+func NewBasicClientWithKeys(accountName, primaryKey, secondaryKey string) (Client, error) {
+	return NewClientWithKeys(accountName, primaryKey, secondaryKey, DefaultBaseURL, DefaultAPIVersion, defaultUseHTTPS)
+}
+
+// NewClientWithKeys constructs a Client holding both access keys of the
+// storage account. Requests are signed with the primary key at first; if the
+// service rejects a request with AuthenticationFailed, the client and all
+// service clients created from it switch over to the other key and the
+// request is retried once. The secondary key is optional.
+func NewClientWithKeys(accountName, primaryKey, secondaryKey, blobServiceBaseURL, apiVersion string, useHTTPS bool) (Client, error) {
 	var c Client
 	if accountName == "" {
 		return c, fmt.Errorf("azure: account name required")
-	} else if accountKey == "" {
+	} else if primaryKey == "" {
 		return c, fmt.Errorf("azure: account key required")
 	} else if blobServiceBaseURL == "" {
 		return c, fmt.Errorf("azure: base storage service url required")
 	}
 
-	key, err := base64.StdEncoding.DecodeString(accountKey)
-	if err != nil {
-		return c, err
+	keys := &accountKeys{}
+	for i, k := range []string{primaryKey, secondaryKey} {
+		if k == "" {
+			continue
+		}
+		key, err := base64.StdEncoding.DecodeString(k)
+		if err != nil {
+			return c, err
+		}
+		keys.keys[i] = key
 	}
 
 	return Client{
 		accountName: accountName,
-		accountKey:  key,
+		keys:        keys,
 		useHTTPS:    useHTTPS,
 		baseURL:     blobServiceBaseURL,
 		apiVersion:  apiVersion,
@@ -173,14 +202,22 @@ func (c Client) createAuthorizationHeader(canonicalizedString string) string {
 	return fmt.Sprintf("%s %s:%s", "SharedKey", c.accountName, signature)
 }
 
-func (c Client) getAuthorizationHeader(verb, url string, headers map[string]string) (string, error) {
-	canonicalizedResource, err := c.buildCanonicalizedResource(url)
+func (c Client) getAuthorizationHeader(verb, uri string, headers map[string]string) (string, error) {
+	_, key := c.keys.get()
+	return c.getAuthorizationHeaderWithKey(key, verb, uri, headers)
+}
+
+// getAuthorizationHeaderWithKey signs with the given account key rather than
+// the active one, so that a request is signed with the key it is known to
+// have been sent with.
+func (c Client) getAuthorizationHeaderWithKey(key []byte, verb, uri string, headers map[string]string) (string, error) {
+	u, err := url.Parse(uri)
 	if err != nil {
-		return "", err
+		return "", fmt.Errorf("getAuthorizationHeader error: %s", err.Error())
 	}
 
-	canonicalizedString := c.buildCanonicalizedString(verb, headers, canonicalizedResource)
-	return c.createAuthorizationHeader(canonicalizedString), nil
+	canonicalizedString := c.buildCanonicalizedString(verb, headers, canonicalizedResource(c.accountName, u))
+	return fmt.Sprintf("%s %s:%s", "SharedKey", c.accountName, hmac256(key, canonicalizedString)), nil
 }
 
 func (c Client) getStandardHeaders() map[string]string {
@@ -190,40 +227,19 @@ func (c Client) getStandardHeaders() map[string]string {
 	}
 }
 
+// buildCanonicalizedHeader returns the x-ms-* headers as signed with the
+// Shared Key scheme, separated by new lines.
 func (c Client) buildCanonicalizedHeader(headers map[string]string) string {
-	cm := make(map[string]string)
-
+	h := make(http.Header, len(headers))
 	for k, v := range headers {
-		headerName := strings.TrimSpace(strings.ToLower(k))
-		match, _ := regexp.MatchString("x-ms-", headerName)
-		if match {
-			cm[headerName] = v
-		}
+		h[k] = []string{v}
 	}
-
-	if len(cm) == 0 {
-		return ""
-	}
-
-	keys := make([]string, 0, len(cm))
-	for key := range cm {
-		keys = append(keys, key)
-	}
-
-	sort.Strings(keys)
-
-	ch := ""
-
-	for i, key := range keys {
-		if i == len(keys)-1 {
-			ch += fmt.Sprintf("%s:%s", key, cm[key])
-		} else {
-			ch += fmt.Sprintf("%s:%s\n", key, cm[key])
-		}
-	}
-	return ch
+	return strings.TrimSuffix(canonicalizedHeaders(h), "\n")
 }
 
+// buildCanonicalizedResource returns the resource as signed in shared access
+// signatures, which sign the decoded path of the uri rather than the path as
+// it is sent, as Shared Key authorization does.
 func (c Client) buildCanonicalizedResource(uri string) (string, error) {
 	errMsg := "buildCanonicalizedResource error: %s"
 	u, err := url.Parse(uri)
@@ -231,42 +247,20 @@ func (c Client) buildCanonicalizedResource(uri string) (string, error) {
 		return "", fmt.Errorf(errMsg, err.Error())
 	}
 
-	cr := "/" + c.accountName
-	if len(u.Path) > 0 {
-		cr += u.Path
-	}
-
 	params, err := url.ParseQuery(u.RawQuery)
 	if err != nil {
 		return "", fmt.Errorf(errMsg, err.Error())
 	}
-
-	if len(params) > 0 {
-		cr += "\n"
-		keys := make([]string, 0, len(params))
-		for key := range params {
-			keys = append(keys, key)
-		}
-
-		sort.Strings(keys)
-
-		for i, key := range keys {
-			if len(params[key]) > 1 {
-				sort.Strings(params[key])
-			}
-
-			if i == len(keys)-1 {
-				cr += fmt.Sprintf("%s:%s", key, strings.Join(params[key], ","))
-			} else {
-				cr += fmt.Sprintf("%s:%s\n", key, strings.Join(params[key], ","))
-			}
-		}
-	}
-	return cr, nil
+	return canonicalizeResource(c.accountName, u.Path, params), nil
 }
 
 func (c Client) buildCanonicalizedString(verb string, headers map[string]string, canonicalizedResource string) string {
-	canonicalizedString := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s",
+	canonicalizedHeader := c.buildCanonicalizedHeader(headers)
+	if canonicalizedHeader != "" {
+		canonicalizedHeader += "\n"
+	}
+
+	canonicalizedString := fmt.Sprintf("%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s\n%s%s",
 		verb,
 		headers["Content-Encoding"],
 		headers["Content-Language"],
@@ -279,14 +273,75 @@ func (c Client) buildCanonicalizedString(verb string, headers map[string]string,
 		headers["If-None-Match"],
 		headers["If-Unmodified-Since"],
 		headers["Range"],
-		c.buildCanonicalizedHeader(headers),
+		canonicalizedHeader,
 		canonicalizedResource)
 
 	return canonicalizedString
 }
 
 func (c Client) exec(verb, url string, headers map[string]string, body io.Reader) (*storageResponse, error) {
-	authHeader, err := c.getAuthorizationHeader(verb, url, headers)
+	if c.LocationMode == LocationModePrimaryOnly || !isReadRequest(verb, url) {
+		return c.execWithKeyFailover(verb, url, headers, body)
+	}
+
+	secondaryURL, err := c.getSecondaryEndpoint(url)
+	if err != nil {
+		return nil, err
+	}
+	if c.LocationMode == LocationModeSecondaryOnly {
+		return c.execWithKeyFailover(verb, secondaryURL, headers, body)
+	}
+
+	resp, err := c.execWithKeyFailover(verb, url, headers, body)
+	if !isRetriableFailure(resp, err) {
+		return resp, err
+	}
+	secondaryResp, secondaryErr := c.execWithKeyFailover(verb, secondaryURL, headers, body)
+	if secondaryResp != nil && secondaryResp.statusCode == http.StatusNotFound {
+		// most likely not replicated yet, report the primary failure
+		secondaryResp.body.Close()
+		return resp, err
+	}
+	if resp != nil {
+		resp.body.Close()
+	}
+	return secondaryResp, secondaryErr
+}
+
+func (c Client) execWithKeyFailover(verb, url string, headers map[string]string, body io.Reader) (*storageResponse, error) {
+	var offset int64
+	seeker, seekable := body.(io.Seeker)
+	if seekable {
+		var err error
+		if offset, err = seeker.Seek(0, io.SeekCurrent); err != nil {
+			seekable = false
+		}
+	}
+
+	// the failover decision is made for the key the request was signed with,
+	// even if the keys have been switched meanwhile
+	used, key := c.keys.get()
+	resp, err := c.execOnce(key, verb, url, headers, body)
+	if !isAuthenticationFailure(resp, err) || !c.keys.failover(used) {
+		return resp, err
+	}
+
+	// retry with the other account key, provided the body can be replayed
+	if body != nil {
+		if !seekable {
+			return resp, err
+		}
+		if _, errSeek := seeker.Seek(offset, io.SeekStart); errSeek != nil {
+			return resp, err
+		}
+	}
+	resp.body.Close()
+	_, key = c.keys.get()
+	return c.execOnce(key, verb, url, headers, body)
+}
+
+func (c Client) execOnce(key []byte, verb, url string, headers map[string]string, body io.Reader) (*storageResponse, error) {
+	authHeader, err := c.getAuthorizationHeaderWithKey(key, verb, url, headers)
 	if err != nil {
 		return nil, err
 	}
@@ -313,7 +368,10 @@ func (c Client) exec(verb, url string, headers map[string]string, body io.Reader
 	for k, v := range headers {
 		req.Header.Add(k, v)
 	}
-	httpClient := http.Client{}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, err
@@ -328,8 +386,12 @@ func (c Client) exec(verb, url string, headers map[string]string, body io.Reader
 		}
 
 		if len(respBody) == 0 {
-			// no error in response body
-			err = fmt.Errorf("storage: service returned without a response body (%s)", resp.Status)
+			// no error in response body, as in responses to HEAD requests
+			err = AzureStorageServiceError{
+				Message:    fmt.Sprintf("service returned without a response body (%s)", resp.Status),
+				StatusCode: resp.StatusCode,
+				RequestID:  resp.Header.Get("x-ms-request-id"),
+			}
 		} else {
 			// response contains storage service error object, unmarshal
 			storageErr, errIn := serviceErrFromXML(respBody, resp.StatusCode, resp.Header.Get("x-ms-request-id"))
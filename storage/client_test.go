@@ -2,6 +2,9 @@ package storage
 
 import (
 	"encoding/base64"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
 	"testing"
@@ -31,6 +34,22 @@ func getBasicClient(c *chk.C) Client {
 	return cli
 }
 
+// useTestServer starts a local server with the given handler and makes cli
+// send all of its requests there, regardless of the host in the request URL.
+// The caller must close the returned server.
+func useTestServer(cli *Client, handler http.Handler) *httptest.Server {
+	ts := httptest.NewServer(handler)
+	cli.useHTTPS = false
+	cli.HTTPClient = &http.Client{
+		Transport: &http.Transport{
+			Dial: func(network, addr string) (net.Conn, error) {
+				return net.Dial(network, ts.Listener.Addr().String())
+			},
+		},
+	}
+	return ts
+}
+
 func (s *StorageClientSuite) TestGetBaseURL_Basic_Https(c *chk.C) {
 	cli, err := NewBasicClient("foo", "YmFy")
 	c.Assert(err, chk.IsNil)
@@ -102,6 +121,9 @@ func (s *StorageClientSuite) Test_buildCanonicalizedResource(c *chk.C) {
 		{"https://foo.blob.core.windows.net/path?a=b&c=d", "/foo/path\na:b\nc:d"},
 		{"https://foo.blob.core.windows.net/?comp=list", "/foo/\ncomp:list"},
 		{"https://foo.blob.core.windows.net/cnt/blob", "/foo/cnt/blob"},
+		{"https://foo.blob.core.windows.net/cnt/a%20b", "/foo/cnt/a b"},
+		{"https://foo.blob.core.windows.net/cnt/50%25", "/foo/cnt/50%"},
+		{"https://foo.blob.core.windows.net/cnt/%C3%A9", "/foo/cnt/é"},
 	}
 
 	for _, i := range tests {
@@ -0,0 +1,668 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// encryptionDataMetadataKey is the user-defined metadata key under which
+	// the encryption envelope of a client-side encrypted blob is stored.
+	encryptionDataMetadataKey = "encryptiondata"
+
+	encryptionProtocolV1         = "1.0"
+	encryptionAlgorithmAESCBC256 = "AES_CBC_256"
+	encryptionModeFullBlob       = "FullBlob"
+
+	contentEncryptionKeySize = 32
+)
+
+var (
+	errNotClientSideEncrypted = errors.New("storage: resource is not client-side encrypted")
+	errInvalidPadding         = errors.New("storage: invalid padding in decrypted content")
+	errKeyUnwrapFailed        = errors.New("storage: key unwrap integrity check failed")
+)
+
+// KeyWrapper protects the random content encryption keys generated for each
+// encrypted blob or queue message. Implementations can keep the key
+// encryption key locally or delegate to an external key management service.
+type KeyWrapper interface {
+	// KeyID returns an identifier of the key encryption key. It is stored
+	// alongside the wrapped key so that a mismatching key can be detected on
+	// decryption.
+	KeyID() string
+
+	// WrapKey encrypts the given content encryption key and returns the
+	// wrapped key along with the name of the wrapping algorithm used.
+	WrapKey(cek []byte) (wrapped []byte, algorithm string, err error)
+
+	// UnwrapKey decrypts a content encryption key which was wrapped using the
+	// specified algorithm.
+	UnwrapKey(wrapped []byte, algorithm string) ([]byte, error)
+}
+
+// EncryptedBlobStorageClient wraps a BlobStorageClient and transparently
+// encrypts blob contents on upload and decrypts them on download.
+//
+// Contents are encrypted with AES-256 in CBC mode using a random content key
+// per blob. The content key is wrapped with the KeyWrapper and saved together
+// with the IV in the blob metadata, using the same envelope format as the
+// other Azure Storage client libraries.
+type EncryptedBlobStorageClient struct {
+	blob       BlobStorageClient
+	keyWrapper KeyWrapper
+}
+
+// EncryptedQueueServiceClient wraps a QueueServiceClient and transparently
+// encrypts message texts before they are put on the queue and decrypts them
+// when they are retrieved.
+type EncryptedQueueServiceClient struct {
+	queue      QueueServiceClient
+	keyWrapper KeyWrapper
+}
+
+type encryptionData struct {
+	EncryptionMode      string `json:",omitempty"`
+	WrappedContentKey   wrappedContentKey
+	EncryptionAgent     encryptionAgent
+	ContentEncryptionIV []byte
+	KeyWrappingMetadata map[string]string `json:",omitempty"`
+}
+
+type wrappedContentKey struct {
+	KeyID        string `json:"KeyId"`
+	EncryptedKey []byte
+	Algorithm    string
+}
+
+type encryptionAgent struct {
+	Protocol            string
+	EncryptionAlgorithm string
+}
+
+type encryptedQueueMessage struct {
+	EncryptedMessageContents []byte
+	EncryptionData           encryptionData
+}
+
+// GetEncryptedBlobService returns an EncryptedBlobStorageClient which can
+// operate on client-side encrypted blobs of the storage account.
+func (c Client) GetEncryptedBlobService(keyWrapper KeyWrapper) EncryptedBlobStorageClient {
+	return EncryptedBlobStorageClient{c.GetBlobService(), keyWrapper}
+}
+
+// GetEncryptedQueueService returns an EncryptedQueueServiceClient which can
+// put and get client-side encrypted messages on the queues of the storage
+// account.
+func (c Client) GetEncryptedQueueService(keyWrapper KeyWrapper) EncryptedQueueServiceClient {
+	return EncryptedQueueServiceClient{c.GetQueueService(), keyWrapper}
+}
+
+// CreateBlockBlobFromReader encrypts data read from reader and uploads it as
+// a block blob. Size must be the number of plaintext bytes read from reader.
+// The encryption envelope is stored in the blob metadata within the same
+// request.
+//
+// Encryption pads the content to the next 16 byte boundary, so the API limit
+// of 64 MiB applies to the padded size.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179451.aspx
+func (e EncryptedBlobStorageClient) CreateBlockBlobFromReader(container, name string, size uint64, blob io.Reader, props *BlobProperties) error {
+	key, iv, data, err := newEncryptionData(e.keyWrapper)
+	if err != nil {
+		return err
+	}
+	envelope, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	if blob == nil {
+		blob = bytes.NewReader(nil)
+	}
+	r, err := newCBCEncryptingReader(io.LimitReader(blob, int64(size)), key, iv)
+	if err != nil {
+		return err
+	}
+
+	if props != nil {
+		// MD5 of the plaintext does not match the uploaded content
+		p := *props
+		p.ContentMD5 = ""
+		props = &p
+	}
+
+	return e.blob.createBlockBlobFromReader(container, name, encryptedSize(size), r, props,
+		map[string]string{encryptionDataMetadataKey: string(envelope)})
+}
+
+// GetBlob downloads and decrypts the specified blob. The whole blob is
+// buffered in memory before it is returned.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179440.aspx
+func (e EncryptedBlobStorageClient) GetBlob(container, name string) (io.ReadCloser, error) {
+	resp, err := e.blob.getBlobRange(container, name, "")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.body.Close()
+
+	if err := checkRespCode(resp.statusCode, []int{http.StatusOK}); err != nil {
+		return nil, err
+	}
+
+	key, iv, err := e.contentKey(resp.headers)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := ioutil.ReadAll(resp.body)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := decryptCBC(key, iv, ciphertext, true)
+	if err != nil {
+		return nil, err
+	}
+	return ioutil.NopCloser(bytes.NewReader(plaintext)), nil
+}
+
+// GetBlobRange reads and decrypts the specified plaintext range of an
+// encrypted blob. The bytesRange string must be in a format like "0-",
+// "10-100" as defined in HTTP 1.1 spec. Only the cipher blocks covering the
+// range (and the block preceding it, used as IV) are downloaded.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179440.aspx
+func (e EncryptedBlobStorageClient) GetBlobRange(container, name, bytesRange string) (io.ReadCloser, error) {
+	start, end, err := parseBytesRange(bytesRange)
+	if err != nil {
+		return nil, err
+	}
+
+	encStart, encEnd := encryptedBlobRange(start, end)
+	encRange := fmt.Sprintf("%d-", encStart)
+	if encEnd >= 0 {
+		encRange = fmt.Sprintf("%d-%d", encStart, encEnd)
+	}
+
+	resp, err := e.blob.getBlobRange(container, name, encRange)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.body.Close()
+
+	if err := checkRespCode(resp.statusCode, []int{http.StatusOK, http.StatusPartialContent}); err != nil {
+		return nil, err
+	}
+
+	key, iv, err := e.contentKey(resp.headers)
+	if err != nil {
+		return nil, err
+	}
+	ciphertext, err := ioutil.ReadAll(resp.body)
+	if err != nil {
+		return nil, err
+	}
+
+	isLast := true
+	if total, ok := contentRangeTotal(resp.headers.Get("Content-Range")); ok {
+		isLast = encStart+int64(len(ciphertext)) >= total
+	}
+
+	plainStart := encStart
+	if encStart > 0 {
+		// the first downloaded block is the IV of the following one
+		if len(ciphertext) < aes.BlockSize {
+			return nil, fmt.Errorf("storage: unexpected length of encrypted range: %d", len(ciphertext))
+		}
+		iv, ciphertext = ciphertext[:aes.BlockSize], ciphertext[aes.BlockSize:]
+		plainStart += aes.BlockSize
+	}
+
+	plaintext, err := decryptCBC(key, iv, ciphertext, isLast)
+	if err != nil {
+		return nil, err
+	}
+
+	offset := start - plainStart
+	if offset > int64(len(plaintext)) {
+		return nil, fmt.Errorf("storage: range %q is outside of the blob", bytesRange)
+	}
+	plaintext = plaintext[offset:]
+	if end >= 0 && end-start+1 < int64(len(plaintext)) {
+		plaintext = plaintext[:end-start+1]
+	}
+	return ioutil.NopCloser(bytes.NewReader(plaintext)), nil
+}
+
+// contentKey unwraps the content encryption key from the encryption envelope
+// found in the metadata headers of a blob response.
+func (e EncryptedBlobStorageClient) contentKey(headers http.Header) (key, iv []byte, err error) {
+	envelope := headers.Get(userDefinedMetadataHeaderPrefix + encryptionDataMetadataKey)
+	if envelope == "" {
+		return nil, nil, errNotClientSideEncrypted
+	}
+
+	var data encryptionData
+	if err := json.Unmarshal([]byte(envelope), &data); err != nil {
+		return nil, nil, fmt.Errorf("storage: cannot parse encryption metadata: %v", err)
+	}
+	return unwrapContentKey(e.keyWrapper, data)
+}
+
+// PutMessage encrypts the message and adds it to the back of the message
+// queue. The encrypted message and its envelope are encoded as JSON, which
+// makes the message text noticeably larger than the plaintext.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179346.aspx
+func (e EncryptedQueueServiceClient) PutMessage(queue string, message string, params PutMessageParameters) error {
+	text, err := encryptQueueMessage(e.keyWrapper, message)
+	if err != nil {
+		return err
+	}
+	return e.queue.PutMessage(queue, text, params)
+}
+
+// GetMessages retrieves one or more messages from the front of the queue and
+// decrypts their message texts.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179474.aspx
+func (e EncryptedQueueServiceClient) GetMessages(queue string, params GetMessagesParameters) (GetMessagesResponse, error) {
+	r, err := e.queue.GetMessages(queue, params)
+	if err != nil {
+		return r, err
+	}
+	for i, m := range r.QueueMessagesList {
+		text, err := decryptQueueMessage(e.keyWrapper, m.MessageText)
+		if err != nil {
+			return r, fmt.Errorf("storage: cannot decrypt message %s: %v", m.MessageID, err)
+		}
+		r.QueueMessagesList[i].MessageText = text
+	}
+	return r, nil
+}
+
+// PeekMessages retrieves one or more messages from the front of the queue
+// without altering their visibility and decrypts their message texts.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179472.aspx
+func (e EncryptedQueueServiceClient) PeekMessages(queue string, params PeekMessagesParameters) (PeekMessagesResponse, error) {
+	r, err := e.queue.PeekMessages(queue, params)
+	if err != nil {
+		return r, err
+	}
+	for i, m := range r.QueueMessagesList {
+		text, err := decryptQueueMessage(e.keyWrapper, m.MessageText)
+		if err != nil {
+			return r, fmt.Errorf("storage: cannot decrypt message %s: %v", m.MessageID, err)
+		}
+		r.QueueMessagesList[i].MessageText = text
+	}
+	return r, nil
+}
+
+// DeleteMessage deletes the specified message.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179347.aspx
+func (e EncryptedQueueServiceClient) DeleteMessage(queue, messageID, popReceipt string) error {
+	return e.queue.DeleteMessage(queue, messageID, popReceipt)
+}
+
+func encryptQueueMessage(kw KeyWrapper, message string) (string, error) {
+	key, iv, data, err := newEncryptionData(kw)
+	if err != nil {
+		return "", err
+	}
+	data.EncryptionMode = ""
+
+	ciphertext, err := encryptCBC(key, iv, []byte(message))
+	if err != nil {
+		return "", err
+	}
+	out, err := json.Marshal(encryptedQueueMessage{
+		EncryptedMessageContents: ciphertext,
+		EncryptionData:           data,
+	})
+	return string(out), err
+}
+
+func decryptQueueMessage(kw KeyWrapper, text string) (string, error) {
+	var m encryptedQueueMessage
+	if err := json.Unmarshal([]byte(text), &m); err != nil || m.EncryptedMessageContents == nil {
+		return "", errNotClientSideEncrypted
+	}
+	key, iv, err := unwrapContentKey(kw, m.EncryptionData)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := decryptCBC(key, iv, m.EncryptedMessageContents, true)
+	return string(plaintext), err
+}
+
+// newEncryptionData generates a random content encryption key and IV and
+// returns them along with the envelope holding the wrapped key.
+func newEncryptionData(kw KeyWrapper) (key, iv []byte, data encryptionData, err error) {
+	if kw == nil {
+		return nil, nil, data, errors.New("storage: key wrapper required for client-side encryption")
+	}
+
+	key = make([]byte, contentEncryptionKeySize)
+	iv = make([]byte, aes.BlockSize)
+	if _, err = io.ReadFull(rand.Reader, key); err != nil {
+		return nil, nil, data, err
+	}
+	if _, err = io.ReadFull(rand.Reader, iv); err != nil {
+		return nil, nil, data, err
+	}
+
+	wrapped, alg, err := kw.WrapKey(key)
+	if err != nil {
+		return nil, nil, data, err
+	}
+
+	data = encryptionData{
+		EncryptionMode: encryptionModeFullBlob,
+		WrappedContentKey: wrappedContentKey{
+			KeyID:        kw.KeyID(),
+			EncryptedKey: wrapped,
+			Algorithm:    alg,
+		},
+		EncryptionAgent: encryptionAgent{
+			Protocol:            encryptionProtocolV1,
+			EncryptionAlgorithm: encryptionAlgorithmAESCBC256,
+		},
+		ContentEncryptionIV: iv,
+	}
+	return key, iv, data, nil
+}
+
+func unwrapContentKey(kw KeyWrapper, data encryptionData) (key, iv []byte, err error) {
+	if kw == nil {
+		return nil, nil, errors.New("storage: key wrapper required for client-side encryption")
+	}
+	if data.EncryptionAgent.EncryptionAlgorithm != encryptionAlgorithmAESCBC256 {
+		return nil, nil, fmt.Errorf("storage: unsupported content encryption algorithm: '%s'", data.EncryptionAgent.EncryptionAlgorithm)
+	}
+	if id := data.WrappedContentKey.KeyID; id != kw.KeyID() {
+		return nil, nil, fmt.Errorf("storage: content key is wrapped with key '%s', not '%s'", id, kw.KeyID())
+	}
+	if len(data.ContentEncryptionIV) != aes.BlockSize {
+		return nil, nil, fmt.Errorf("storage: invalid content encryption IV length: %d", len(data.ContentEncryptionIV))
+	}
+
+	key, err = kw.UnwrapKey(data.WrappedContentKey.EncryptedKey, data.WrappedContentKey.Algorithm)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, data.ContentEncryptionIV, nil
+}
+
+// encryptedSize returns the size of the ciphertext produced for a plaintext
+// of given size, which is always padded with 1 to 16 bytes.
+func encryptedSize(size uint64) uint64 {
+	return size - size%aes.BlockSize + aes.BlockSize
+}
+
+// encryptedBlobRange maps a plaintext range onto the range of the ciphertext
+// which has to be downloaded to decrypt it. The cipher block preceding the
+// range is included as it serves as the IV of the first block. An end of -1
+// denotes an open range.
+func encryptedBlobRange(start, end int64) (encStart, encEnd int64) {
+	encStart = start - start%aes.BlockSize - aes.BlockSize
+	if encStart < 0 {
+		encStart = 0
+	}
+	encEnd = -1
+	if end >= 0 {
+		encEnd = end - end%aes.BlockSize + aes.BlockSize - 1
+	}
+	return encStart, encEnd
+}
+
+// parseBytesRange parses range strings like "0-" or "10-100" and returns -1
+// as the end of open ranges.
+func parseBytesRange(bytesRange string) (start, end int64, err error) {
+	parts := strings.SplitN(bytesRange, "-", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("storage: invalid range: %q", bytesRange)
+	}
+	if start, err = strconv.ParseInt(parts[0], 10, 64); err != nil || start < 0 {
+		return 0, 0, fmt.Errorf("storage: invalid range: %q", bytesRange)
+	}
+	end = -1
+	if parts[1] != "" {
+		if end, err = strconv.ParseInt(parts[1], 10, 64); err != nil || end < start {
+			return 0, 0, fmt.Errorf("storage: invalid range: %q", bytesRange)
+		}
+	}
+	return start, end, nil
+}
+
+// contentRangeTotal returns the complete length from a Content-Range header
+// value such as "bytes 0-15/48".
+func contentRangeTotal(contentRange string) (int64, bool) {
+	i := strings.LastIndex(contentRange, "/")
+	if i < 0 {
+		return 0, false
+	}
+	total, err := strconv.ParseInt(contentRange[i+1:], 10, 64)
+	return total, err == nil
+}
+
+func encryptCBC(key, iv, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	data := pkcs7Pad(plaintext, aes.BlockSize)
+	cipher.NewCBCEncrypter(block, iv).CryptBlocks(data, data)
+	return data, nil
+}
+
+// decryptCBC decrypts ciphertext and strips the padding if it contains the
+// final block of the encrypted content.
+func decryptCBC(key, iv, ciphertext []byte, unpad bool) ([]byte, error) {
+	if len(ciphertext)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("storage: encrypted content length %d is not a multiple of the block size", len(ciphertext))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, len(ciphertext))
+	cipher.NewCBCDecrypter(block, iv).CryptBlocks(out, ciphertext)
+	if unpad {
+		return pkcs7Unpad(out, aes.BlockSize)
+	}
+	return out, nil
+}
+
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	n := blockSize - len(data)%blockSize
+	out := make([]byte, len(data)+n)
+	copy(out, data)
+	for i := len(data); i < len(out); i++ {
+		out[i] = byte(n)
+	}
+	return out
+}
+
+func pkcs7Unpad(data []byte, blockSize int) ([]byte, error) {
+	if len(data) == 0 || len(data)%blockSize != 0 {
+		return nil, errInvalidPadding
+	}
+	n := int(data[len(data)-1])
+	if n == 0 || n > blockSize {
+		return nil, errInvalidPadding
+	}
+	for _, b := range data[len(data)-n:] {
+		if int(b) != n {
+			return nil, errInvalidPadding
+		}
+	}
+	return data[:len(data)-n], nil
+}
+
+// cbcEncryptingReader encrypts the data read from the underlying reader and
+// appends PKCS#7 padding once the underlying reader is exhausted.
+type cbcEncryptingReader struct {
+	src  io.Reader
+	mode cipher.BlockMode
+	in   []byte
+	out  []byte
+	eof  bool
+}
+
+func newCBCEncryptingReader(src io.Reader, key, iv []byte) (io.Reader, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &cbcEncryptingReader{
+		src:  src,
+		mode: cipher.NewCBCEncrypter(block, iv),
+		in:   make([]byte, 256*aes.BlockSize),
+	}, nil
+}
+
+func (r *cbcEncryptingReader) Read(p []byte) (int, error) {
+	for len(r.out) == 0 {
+		if r.eof {
+			return 0, io.EOF
+		}
+
+		n, err := io.ReadFull(r.src, r.in)
+		switch err {
+		case nil:
+			r.out = r.in[:n]
+		case io.EOF, io.ErrUnexpectedEOF:
+			r.out = pkcs7Pad(r.in[:n], aes.BlockSize)
+			r.eof = true
+		default:
+			return 0, err
+		}
+		r.mode.CryptBlocks(r.out, r.out)
+	}
+
+	n := copy(p, r.out)
+	r.out = r.out[n:]
+	return n, nil
+}
+
+// aesKeyWrapper is a KeyWrapper using the AES Key Wrap algorithm defined in
+// RFC 3394 with a local key encryption key.
+type aesKeyWrapper struct {
+	keyID string
+	kek   []byte
+}
+
+// aesKeyWrapIV is the default initial value defined in RFC 3394 section 2.2.3.
+var aesKeyWrapIV = []byte{0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6, 0xA6}
+
+// NewAESKeyWrapper returns a KeyWrapper which wraps content encryption keys
+// with the given 128, 192 or 256 bit key encryption key using AES Key Wrap
+// (RFC 3394). The key ID is stored with each wrapped key.
+func NewAESKeyWrapper(keyID string, kek []byte) (KeyWrapper, error) {
+	switch len(kek) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("storage: invalid AES key encryption key length: %d", len(kek))
+	}
+	return aesKeyWrapper{keyID, kek}, nil
+}
+
+// NewAESKeyWrapperFromBase64 is NewAESKeyWrapper with a base64 encoded key
+// encryption key.
+func NewAESKeyWrapperFromBase64(keyID, kek string) (KeyWrapper, error) {
+	key, err := base64.StdEncoding.DecodeString(kek)
+	if err != nil {
+		return nil, err
+	}
+	return NewAESKeyWrapper(keyID, key)
+}
+
+func (w aesKeyWrapper) KeyID() string { return w.keyID }
+
+func (w aesKeyWrapper) algorithm() string { return fmt.Sprintf("A%dKW", len(w.kek)*8) }
+
+func (w aesKeyWrapper) WrapKey(cek []byte) ([]byte, string, error) {
+	wrapped, err := aesKeyWrap(w.kek, cek)
+	return wrapped, w.algorithm(), err
+}
+
+func (w aesKeyWrapper) UnwrapKey(wrapped []byte, algorithm string) ([]byte, error) {
+	if algorithm != w.algorithm() {
+		return nil, fmt.Errorf("storage: unsupported key wrap algorithm: '%s'", algorithm)
+	}
+	return aesKeyUnwrap(w.kek, wrapped)
+}
+
+func aesKeyWrap(kek, plaintext []byte) ([]byte, error) {
+	if len(plaintext) < 16 || len(plaintext)%8 != 0 {
+		return nil, fmt.Errorf("storage: cannot wrap key of length %d", len(plaintext))
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(plaintext) / 8
+	out := make([]byte, 8+len(plaintext))
+	copy(out[8:], plaintext)
+	a := append([]byte{}, aesKeyWrapIV...)
+	buf := make([]byte, aes.BlockSize)
+
+	for j := 0; j < 6; j++ {
+		for i := 1; i <= n; i++ {
+			copy(buf, a)
+			copy(buf[8:], out[i*8:i*8+8])
+			block.Encrypt(buf, buf)
+			binary.BigEndian.PutUint64(a, binary.BigEndian.Uint64(buf[:8])^uint64(n*j+i))
+			copy(out[i*8:i*8+8], buf[8:])
+		}
+	}
+	copy(out[:8], a)
+	return out, nil
+}
+
+func aesKeyUnwrap(kek, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 24 || len(ciphertext)%8 != 0 {
+		return nil, fmt.Errorf("storage: cannot unwrap key of length %d", len(ciphertext))
+	}
+	block, err := aes.NewCipher(kek)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(ciphertext)/8 - 1
+	out := make([]byte, len(ciphertext)-8)
+	copy(out, ciphertext[8:])
+	a := append([]byte{}, ciphertext[:8]...)
+	buf := make([]byte, aes.BlockSize)
+
+	for j := 5; j >= 0; j-- {
+		for i := n; i >= 1; i-- {
+			binary.BigEndian.PutUint64(buf[:8], binary.BigEndian.Uint64(a)^uint64(n*j+i))
+			copy(buf[8:], out[(i-1)*8:i*8])
+			block.Decrypt(buf, buf)
+			copy(a, buf[:8])
+			copy(out[(i-1)*8:i*8], buf[8:])
+		}
+	}
+	if subtle.ConstantTimeCompare(a, aesKeyWrapIV) != 1 {
+		return nil, errKeyUnwrapFailed
+	}
+	return out, nil
+}
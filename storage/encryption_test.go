@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+
+	chk "github.com/Azure/azure-sdk-for-go/Godeps/_workspace/src/gopkg.in/check.v1"
+)
+
+type StorageEncryptionSuite struct{}
+
+var _ = chk.Suite(&StorageEncryptionSuite{})
+
+func getTestKeyWrapper(c *chk.C) KeyWrapper {
+	kw, err := NewAESKeyWrapper("test-key", randBytes(32))
+	c.Assert(err, chk.IsNil)
+	return kw
+}
+
+func (s *StorageEncryptionSuite) Test_aesKeyWrap(c *chk.C) {
+	// from RFC 3394 section 4.6
+	kek, _ := hex.DecodeString("000102030405060708090A0B0C0D0E0F101112131415161718191A1B1C1D1E1F")
+	key, _ := hex.DecodeString("00112233445566778899AABBCCDDEEFF000102030405060708090A0B0C0D0E0F")
+	expected, _ := hex.DecodeString("28C9F404C4B810F4CBCCB35CFB87F8263F5786E2D80ED326CBC7F0E71A99F43BFB988B9B7A02DD21")
+
+	wrapped, err := aesKeyWrap(kek, key)
+	c.Assert(err, chk.IsNil)
+	c.Assert(wrapped, chk.DeepEquals, expected)
+
+	unwrapped, err := aesKeyUnwrap(kek, wrapped)
+	c.Assert(err, chk.IsNil)
+	c.Assert(unwrapped, chk.DeepEquals, key)
+
+	wrapped[0] ^= 1
+	_, err = aesKeyUnwrap(kek, wrapped)
+	c.Assert(err, chk.Equals, errKeyUnwrapFailed)
+}
+
+func (s *StorageEncryptionSuite) TestNewAESKeyWrapper_InvalidKey(c *chk.C) {
+	_, err := NewAESKeyWrapper("k", []byte("short"))
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *StorageEncryptionSuite) Test_cbcEncryptingReader(c *chk.C) {
+	key := randBytes(32)
+	iv := randBytes(16)
+
+	for _, n := range []int{0, 1, 15, 16, 17, 4096, 4097, 10000} {
+		data := randBytes(n + 1)[:n]
+		r, err := newCBCEncryptingReader(bytes.NewReader(data), key, iv)
+		c.Assert(err, chk.IsNil)
+		ciphertext, err := ioutil.ReadAll(r)
+		c.Assert(err, chk.IsNil)
+		c.Assert(uint64(len(ciphertext)), chk.Equals, encryptedSize(uint64(n)))
+
+		expected, err := encryptCBC(key, iv, data)
+		c.Assert(err, chk.IsNil)
+		c.Assert(ciphertext, chk.DeepEquals, expected)
+
+		plaintext, err := decryptCBC(key, iv, ciphertext, true)
+		c.Assert(err, chk.IsNil)
+		c.Assert(plaintext, chk.DeepEquals, data)
+	}
+}
+
+func (s *StorageEncryptionSuite) Test_encryptedBlobRange(c *chk.C) {
+	for _, t := range []struct {
+		start, end       int64
+		encStart, encEnd int64
+	}{
+		{0, -1, 0, -1},
+		{0, 0, 0, 15},
+		{15, 16, 0, 31},
+		{16, 31, 0, 31},
+		{33, -1, 16, -1},
+		{40, 70, 16, 79},
+	} {
+		encStart, encEnd := encryptedBlobRange(t.start, t.end)
+		c.Assert(encStart, chk.Equals, t.encStart, chk.Commentf("%v", t))
+		c.Assert(encEnd, chk.Equals, t.encEnd, chk.Commentf("%v", t))
+	}
+}
+
+func (s *StorageEncryptionSuite) Test_parseBytesRange(c *chk.C) {
+	start, end, err := parseBytesRange("10-")
+	c.Assert(err, chk.IsNil)
+	c.Assert(start, chk.Equals, int64(10))
+	c.Assert(end, chk.Equals, int64(-1))
+
+	start, end, err = parseBytesRange("1-3")
+	c.Assert(err, chk.IsNil)
+	c.Assert(start, chk.Equals, int64(1))
+	c.Assert(end, chk.Equals, int64(3))
+
+	for _, r := range []string{"", "-", "-3", "3-1", "a-b"} {
+		_, _, err = parseBytesRange(r)
+		c.Assert(err, chk.NotNil, chk.Commentf("%q", r))
+	}
+}
+
+func (s *StorageEncryptionSuite) Test_contentRangeTotal(c *chk.C) {
+	total, ok := contentRangeTotal("bytes 0-15/48")
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(total, chk.Equals, int64(48))
+
+	_, ok = contentRangeTotal("")
+	c.Assert(ok, chk.Equals, false)
+}
+
+func (s *StorageEncryptionSuite) Test_encryptQueueMessage_Roundtrips(c *chk.C) {
+	kw := getTestKeyWrapper(c)
+	text, err := encryptQueueMessage(kw, "hello, world")
+	c.Assert(err, chk.IsNil)
+	c.Assert(text, chk.Not(chk.Equals), "hello, world")
+
+	out, err := decryptQueueMessage(kw, text)
+	c.Assert(err, chk.IsNil)
+	c.Assert(out, chk.Equals, "hello, world")
+
+	_, err = decryptQueueMessage(getTestKeyWrapper(c), text)
+	c.Assert(err, chk.NotNil)
+
+	_, err = decryptQueueMessage(kw, "plain text")
+	c.Assert(err, chk.Equals, errNotClientSideEncrypted)
+}
+
+func (s *StorageEncryptionSuite) TestEncryptedBlob_GetBlob_GetBlobRange(c *chk.C) {
+	api := getBasicClient(c)
+	cli := api.GetBlobService()
+	enc := api.GetEncryptedBlobService(getTestKeyWrapper(c))
+
+	cnt := randContainer()
+	c.Assert(cli.CreateContainer(cnt, ContainerAccessTypePrivate), chk.IsNil)
+	defer cli.deleteContainer(cnt)
+
+	name := randString(20)
+	data := randBytes(100)
+	c.Assert(enc.CreateBlockBlobFromReader(cnt, name, uint64(len(data)), bytes.NewReader(data), nil), chk.IsNil)
+
+	// stored content is not plaintext
+	raw, err := cli.GetBlob(cnt, name)
+	c.Assert(err, chk.IsNil)
+	rawData, err := ioutil.ReadAll(raw)
+	raw.Close()
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(rawData), chk.Equals, 112)
+	c.Assert(bytes.Contains(rawData, data[:16]), chk.Equals, false)
+
+	body, err := enc.GetBlob(cnt, name)
+	c.Assert(err, chk.IsNil)
+	gotData, err := ioutil.ReadAll(body)
+	body.Close()
+	c.Assert(err, chk.IsNil)
+	c.Assert(gotData, chk.DeepEquals, data)
+
+	for _, r := range []struct {
+		start, end int
+	}{
+		{0, -1}, {1, 3}, {16, 31}, {20, 99}, {50, -1}, {90, 200},
+	} {
+		rangeStr := fmt.Sprintf("%d-", r.start)
+		expected := data[r.start:]
+		if r.end >= 0 {
+			rangeStr = fmt.Sprintf("%d-%d", r.start, r.end)
+			if r.end+1 < len(data) {
+				expected = data[r.start : r.end+1]
+			}
+		}
+
+		body, err := enc.GetBlobRange(cnt, name, rangeStr)
+		c.Assert(err, chk.IsNil)
+		gotData, err := ioutil.ReadAll(body)
+		body.Close()
+		c.Assert(err, chk.IsNil)
+		c.Assert(gotData, chk.DeepEquals, expected, chk.Commentf("range %s", rangeStr))
+	}
+}
+
+func (s *StorageEncryptionSuite) TestEncryptedQueue_PutMessage_GetMessages(c *chk.C) {
+	api := getBasicClient(c)
+	cli := api.GetQueueService()
+	enc := api.GetEncryptedQueueService(getTestKeyWrapper(c))
+
+	q := randString(20)
+	c.Assert(cli.CreateQueue(q), chk.IsNil)
+	defer cli.DeleteQueue(q)
+
+	msg := randString(1024)
+	c.Assert(enc.PutMessage(q, msg, PutMessageParameters{}), chk.IsNil)
+
+	r, err := enc.GetMessages(q, GetMessagesParameters{})
+	c.Assert(err, chk.IsNil)
+	c.Assert(len(r.QueueMessagesList), chk.Equals, 1)
+	m := r.QueueMessagesList[0]
+	c.Assert(m.MessageText, chk.Equals, msg)
+	c.Assert(enc.DeleteMessage(q, m.MessageID, m.PopReceipt), chk.IsNil)
+}
@@ -0,0 +1,174 @@
+package storage
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var fakeBlobLastModified = time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+
+// fakeBlobFSServer extends fakeBlobServer with the parts of the Blob service
+// used by the blob file system: block uploads, ranged reads, modification
+// times and listings grouped by a delimiter.
+type fakeBlobFSServer struct {
+	*fakeBlobServer
+	blocks map[string]map[string][]byte // uncommitted blocks by "container/name"
+}
+
+func newFakeBlobFSServer(names ...string) *fakeBlobFSServer {
+	return &fakeBlobFSServer{
+		fakeBlobServer: newFakeBlobServer(names...),
+		blocks:         make(map[string]map[string][]byte),
+	}
+}
+
+func writeFakeBlobError(w http.ResponseWriter, code int, errCode string) {
+	w.WriteHeader(code)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>%s</Code><Message>m</Message></Error>`, errCode)
+}
+
+func (f *fakeBlobFSServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	path := strings.TrimPrefix(r.URL.Path, "/")
+	if r.Method == "GET" || r.Method == "HEAD" {
+		w.Header().Set("Last-Modified", fakeBlobLastModified.Format(http.TimeFormat))
+	}
+
+	switch {
+	case q.Get("restype") == "container" && q.Get("comp") == "list":
+		f.serve(func() { f.list(w, path, q) })
+	case r.Method == "PUT" && q.Get("comp") == "block":
+		f.serve(func() { f.putBlock(w, r, path, q.Get("blockid")) })
+	case r.Method == "PUT" && q.Get("comp") == "blocklist":
+		f.serve(func() { f.putBlockList(w, r, path) })
+	case r.Method == "GET" && r.Header.Get("Range") != "":
+		f.serve(func() { f.getRange(w, r, path) })
+	default:
+		f.fakeBlobServer.ServeHTTP(w, r)
+	}
+}
+
+// serve runs fn as one request with the server locked.
+func (f *fakeBlobFSServer) serve(fn func()) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests++
+	fn()
+}
+
+func (f *fakeBlobFSServer) putBlock(w http.ResponseWriter, r *http.Request, path, id string) {
+	if f.blocks[path] == nil {
+		f.blocks[path] = make(map[string][]byte)
+	}
+	f.blocks[path][id], _ = ioutil.ReadAll(r.Body)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *fakeBlobFSServer) putBlockList(w http.ResponseWriter, r *http.Request, path string) {
+	var list struct {
+		IDs []string `xml:"Uncommitted"`
+	}
+	if err := xml.NewDecoder(r.Body).Decode(&list); err != nil {
+		writeFakeBlobError(w, http.StatusBadRequest, "InvalidBlockList")
+		return
+	}
+	data := []byte{}
+	for _, id := range list.IDs {
+		block, ok := f.blocks[path][id]
+		if !ok {
+			writeFakeBlobError(w, http.StatusBadRequest, "InvalidBlockList")
+			return
+		}
+		data = append(data, block...)
+	}
+	delete(f.blocks, path)
+	f.blobs[path] = &fakeBlob{data: data}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (f *fakeBlobFSServer) getRange(w http.ResponseWriter, r *http.Request, path string) {
+	blob, ok := f.blobs[path]
+	if !ok {
+		writeFakeBlobError(w, http.StatusNotFound, "BlobNotFound")
+		return
+	}
+	var start, end int
+	if _, err := fmt.Sscanf(r.Header.Get("Range"), "bytes=%d-%d", &start, &end); err != nil || start >= len(blob.data) {
+		writeFakeBlobError(w, http.StatusRequestedRangeNotSatisfiable, "InvalidRange")
+		return
+	}
+	if end >= len(blob.data) {
+		end = len(blob.data) - 1
+	}
+	w.Header().Set("Content-Length", strconv.Itoa(end+1-start))
+	w.Header().Set("Content-Type", blob.contentType)
+	w.Header().Set("x-ms-blob-type", string(BlobTypeBlock))
+	w.WriteHeader(http.StatusPartialContent)
+	w.Write(blob.data[start : end+1])
+}
+
+// list responds to a List Blobs request, grouping names by the delimiter
+// into blob prefixes if one is given.
+func (f *fakeBlobFSServer) list(w http.ResponseWriter, container string, q url.Values) {
+	prefix, delimiter, marker := q.Get("prefix"), q.Get("delimiter"), q.Get("marker")
+	maxResults := f.pageSize
+	if n, err := strconv.Atoi(q.Get("maxresults")); err == nil && n < maxResults {
+		maxResults = n
+	}
+
+	found := false
+	entries := map[string]bool{} // name to whether it is a blob prefix
+	for n := range f.blobs {
+		found = found || strings.HasPrefix(n, container+"/")
+		if !strings.HasPrefix(n, container+"/"+prefix) {
+			continue
+		}
+		name := strings.TrimPrefix(n, container+"/")
+		if i := strings.Index(name[len(prefix):], delimiter); delimiter != "" && i >= 0 {
+			entries[name[:len(prefix)+i+len(delimiter)]] = true
+		} else {
+			entries[name] = false
+		}
+	}
+	if !found {
+		writeFakeBlobError(w, http.StatusNotFound, "ContainerNotFound")
+		return
+	}
+
+	var names []string
+	for n := range entries {
+		if marker == "" || n > marker {
+			names = append(names, n)
+		}
+	}
+	sort.Strings(names)
+
+	out := BlobListResponse{Delimiter: delimiter}
+	for i, n := range names {
+		if i == maxResults {
+			out.NextMarker = names[i-1]
+			break
+		}
+		if entries[n] {
+			out.BlobPrefixes = append(out.BlobPrefixes, n)
+			continue
+		}
+		blob := f.blobs[container+"/"+n]
+		out.Blobs = append(out.Blobs, Blob{Name: n, Properties: BlobProperties{
+			LastModified:  fakeBlobLastModified.Format(http.TimeFormat),
+			ContentLength: int64(len(blob.data)),
+			ContentType:   blob.contentType,
+			BlobType:      BlobTypeBlock,
+		}})
+	}
+	b, _ := xml.Marshal(out)
+	w.WriteHeader(http.StatusOK)
+	w.Write(b)
+}
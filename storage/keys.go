@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+// AccountKey identifies one of the two access keys of a storage account.
+type AccountKey int
+
+// Access keys of a storage account
+const (
+	PrimaryAccountKey AccountKey = iota
+	SecondaryAccountKey
+)
+
+func (k AccountKey) other() AccountKey {
+	return 1 - k
+}
+
+func (k AccountKey) String() string {
+	switch k {
+	case PrimaryAccountKey:
+		return "primary"
+	case SecondaryAccountKey:
+		return "secondary"
+	}
+	return fmt.Sprintf("AccountKey(%d)", int(k))
+}
+
+// accountKeys holds the access keys of a storage account and tracks which
+// of them is used to sign requests. It is shared by all copies of a Client,
+// so that switching keys affects every service client created from it.
+type accountKeys struct {
+	mu     sync.RWMutex
+	keys   [2][]byte
+	active AccountKey
+}
+
+func (a *accountKeys) get() (AccountKey, []byte) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	return a.active, a.keys[a.active]
+}
+
+func (a *accountKeys) activeKey() AccountKey {
+	k, _ := a.get()
+	return k
+}
+
+// failover switches to the other key if the given key is still the active
+// one. It returns true if a different key than the given one is active
+// afterwards.
+func (a *accountKeys) failover(used AccountKey) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.active == used && a.keys[used.other()] != nil {
+		a.active = used.other()
+	}
+	return a.active != used
+}
+
+func (a *accountKeys) set(k AccountKey, key []byte) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.keys[k] = key
+}
+
+func (a *accountKeys) use(k AccountKey) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.keys[k] == nil {
+		return fmt.Errorf("storage: %s account key is not set", k)
+	}
+	a.active = k
+	return nil
+}
+
+// isAuthenticationFailure returns true if the service rejected the request
+// because of its signature. Responses to HEAD requests carry no error body,
+// so a 403 without an error code is treated as an authentication failure as
+// well.
+func isAuthenticationFailure(resp *storageResponse, err error) bool {
+	if resp == nil || err == nil {
+		return false
+	}
+	if storageErr, ok := err.(AzureStorageServiceError); ok {
+		return storageErr.StatusCode == http.StatusForbidden &&
+			(storageErr.Code == "" || storageErr.Code == "AuthenticationFailed")
+	}
+	return resp.statusCode == http.StatusForbidden
+}
+
+// ActiveAccountKey returns which access key is currently used to sign
+// requests.
+func (c Client) ActiveAccountKey() AccountKey {
+	return c.keys.activeKey()
+}
+
+// UseAccountKey makes the client and all service clients created from it
+// sign requests with the given access key, which must have been set.
+func (c Client) UseAccountKey(k AccountKey) error {
+	if k != PrimaryAccountKey && k != SecondaryAccountKey {
+		return fmt.Errorf("storage: invalid account key: %d", int(k))
+	}
+	return c.keys.use(k)
+}
+
+// SetAccountKey replaces the value of the given access key of the client and
+// all service clients created from it, for instance after the key has been
+// regenerated.
+func (c Client) SetAccountKey(k AccountKey, accountKey string) error {
+	if k != PrimaryAccountKey && k != SecondaryAccountKey {
+		return fmt.Errorf("storage: invalid account key: %d", int(k))
+	}
+	if accountKey == "" {
+		return fmt.Errorf("azure: account key required")
+	}
+	key, err := base64.StdEncoding.DecodeString(accountKey)
+	if err != nil {
+		return err
+	}
+	c.keys.set(k, key)
+	return nil
+}
+
+// KeyRegenerator regenerates the given access key of a storage account and
+// returns its new value. Regenerators for classic and Azure Resource Manager
+// storage accounts are in the storage/accountkeys package.
+type KeyRegenerator func(k AccountKey) (string, error)
+
+// RotateAccountKey regenerates one access key of a storage account without
+// interrupting the given clients, which must hold both keys. The clients are
+// switched over to the other key first, then the key is regenerated and its
+// new value is set on the clients. To rotate both keys, call it once for
+// each key.
+func RotateAccountKey(regenerate KeyRegenerator, k AccountKey, clients ...Client) error {
+	for _, c := range clients {
+		if err := c.UseAccountKey(k.other()); err != nil {
+			return err
+		}
+	}
+
+	key, err := regenerate(k)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range clients {
+		if err := c.SetAccountKey(k, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
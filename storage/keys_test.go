@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+
+	chk "github.com/Azure/azure-sdk-for-go/Godeps/_workspace/src/gopkg.in/check.v1"
+)
+
+type StorageKeysSuite struct{}
+
+var _ = chk.Suite(&StorageKeysSuite{})
+
+var (
+	testPrimaryKey   = base64.StdEncoding.EncodeToString([]byte("primary"))
+	testSecondaryKey = base64.StdEncoding.EncodeToString([]byte("secondary"))
+)
+
+// authFailingHandler rejects requests signed with any key other than the
+// accepted one and records the Authorization headers it has seen.
+type authFailingHandler struct {
+	mu       sync.Mutex
+	accepted string
+	auth     []string
+}
+
+func (h *authFailingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.auth = append(h.auth, r.Header.Get("Authorization"))
+
+	signer, err := NewBasicClient("foo", h.accepted)
+	if err != nil {
+		panic(err)
+	}
+	headers := map[string]string{}
+	for k := range r.Header {
+		if k != "Authorization" {
+			headers[k] = r.Header.Get(k)
+		}
+	}
+	headers["Content-Length"] = r.Header.Get("Content-Length")
+	expected, err := signer.getAuthorizationHeader(r.Method, "http://"+r.Host+r.URL.String(), lowerMsHeaders(headers))
+	if err != nil {
+		panic(err)
+	}
+
+	if r.Header.Get("Authorization") != expected {
+		w.WriteHeader(http.StatusForbidden)
+		if r.Method != "HEAD" {
+			fmt.Fprint(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>AuthenticationFailed</Code><Message>Server failed to authenticate the request.</Message></Error>`)
+		}
+		return
+	}
+	if r.Method == "HEAD" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+// lowerMsHeaders restores the lower case x-ms-* header names as they were
+// signed by the client.
+func lowerMsHeaders(h map[string]string) map[string]string {
+	out := map[string]string{}
+	for k, v := range h {
+		if len(k) > 5 && (k[:5] == "X-Ms-" || k[:5] == "x-ms-") {
+			out["x-ms-"+k[5:]] = v
+		} else {
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func (s *StorageKeysSuite) TestFailoverToSecondaryKey(c *chk.C) {
+	cli, err := NewBasicClientWithKeys("foo", testPrimaryKey, testSecondaryKey)
+	c.Assert(err, chk.IsNil)
+	h := &authFailingHandler{accepted: testSecondaryKey}
+	ts := useTestServer(&cli, h)
+	defer ts.Close()
+
+	blob := cli.GetBlobService()
+	queue := cli.GetQueueService()
+	c.Assert(cli.ActiveAccountKey(), chk.Equals, PrimaryAccountKey)
+
+	c.Assert(blob.CreateContainer("cnt", ContainerAccessTypePrivate), chk.IsNil)
+	c.Assert(len(h.auth), chk.Equals, 2)
+	c.Assert(h.auth[0], chk.Not(chk.Equals), h.auth[1])
+
+	// the switch is visible to all service clients of the client
+	c.Assert(cli.ActiveAccountKey(), chk.Equals, SecondaryAccountKey)
+	c.Assert(queue.CreateQueue("q"), chk.IsNil)
+	c.Assert(len(h.auth), chk.Equals, 3)
+}
+
+func (s *StorageKeysSuite) TestFailover_HeadRequest(c *chk.C) {
+	cli, err := NewBasicClientWithKeys("foo", testPrimaryKey, testSecondaryKey)
+	c.Assert(err, chk.IsNil)
+	h := &authFailingHandler{accepted: testSecondaryKey}
+	ts := useTestServer(&cli, h)
+	defer ts.Close()
+
+	// responses to HEAD requests carry no error code
+	ok, err := cli.GetBlobService().ContainerExists("cnt")
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(len(h.auth), chk.Equals, 2)
+	c.Assert(cli.ActiveAccountKey(), chk.Equals, SecondaryAccountKey)
+}
+
+func (s *StorageKeysSuite) TestFailover_ReplaysBody(c *chk.C) {
+	cli, err := NewBasicClientWithKeys("foo", testPrimaryKey, testSecondaryKey)
+	c.Assert(err, chk.IsNil)
+	h := &authFailingHandler{accepted: testSecondaryKey}
+	ts := useTestServer(&cli, h)
+	defer ts.Close()
+
+	c.Assert(cli.GetBlobService().PutBlock("cnt", "blob", "id", []byte("data")), chk.IsNil)
+	c.Assert(len(h.auth), chk.Equals, 2)
+}
+
+func (s *StorageKeysSuite) TestNoFailoverWithSingleKey(c *chk.C) {
+	cli, err := NewBasicClient("foo", testPrimaryKey)
+	c.Assert(err, chk.IsNil)
+	h := &authFailingHandler{accepted: testSecondaryKey}
+	ts := useTestServer(&cli, h)
+	defer ts.Close()
+
+	err = cli.GetBlobService().CreateContainer("cnt", ContainerAccessTypePrivate)
+	c.Assert(err, chk.NotNil)
+	c.Assert(err.(AzureStorageServiceError).Code, chk.Equals, "AuthenticationFailed")
+	c.Assert(len(h.auth), chk.Equals, 1)
+	c.Assert(cli.ActiveAccountKey(), chk.Equals, PrimaryAccountKey)
+}
+
+func (s *StorageKeysSuite) TestUseAccountKey(c *chk.C) {
+	cli, err := NewBasicClient("foo", testPrimaryKey)
+	c.Assert(err, chk.IsNil)
+	c.Assert(cli.UseAccountKey(SecondaryAccountKey), chk.NotNil)
+
+	c.Assert(cli.SetAccountKey(SecondaryAccountKey, testSecondaryKey), chk.IsNil)
+	c.Assert(cli.UseAccountKey(SecondaryAccountKey), chk.IsNil)
+	c.Assert(cli.GetBlobService().client.ActiveAccountKey(), chk.Equals, SecondaryAccountKey)
+	c.Assert(cli.computeHmac256("foo"), chk.Equals, computeHmac256Key([]byte("secondary"), "foo"))
+}
+
+func (s *StorageKeysSuite) TestRotateAccountKey(c *chk.C) {
+	cli, err := NewBasicClientWithKeys("foo", testPrimaryKey, testSecondaryKey)
+	c.Assert(err, chk.IsNil)
+
+	newKey := base64.StdEncoding.EncodeToString([]byte("new primary"))
+	var regenerated []AccountKey
+	regenerate := func(k AccountKey) (string, error) {
+		// clients must have moved off the key being regenerated
+		c.Assert(cli.ActiveAccountKey(), chk.Equals, SecondaryAccountKey)
+		regenerated = append(regenerated, k)
+		return newKey, nil
+	}
+
+	c.Assert(RotateAccountKey(regenerate, PrimaryAccountKey, cli), chk.IsNil)
+	c.Assert(regenerated, chk.DeepEquals, []AccountKey{PrimaryAccountKey})
+	c.Assert(cli.computeHmac256("foo"), chk.Equals, computeHmac256Key([]byte("secondary"), "foo"))
+
+	c.Assert(cli.UseAccountKey(PrimaryAccountKey), chk.IsNil)
+	c.Assert(cli.computeHmac256("foo"), chk.Equals, computeHmac256Key([]byte("new primary"), "foo"))
+}
+
+func (s *StorageKeysSuite) TestRotateAccountKey_RegenerateFails(c *chk.C) {
+	cli, err := NewBasicClientWithKeys("foo", testPrimaryKey, testSecondaryKey)
+	c.Assert(err, chk.IsNil)
+
+	fail := func(k AccountKey) (string, error) { return "", errors.New("failed") }
+	c.Assert(RotateAccountKey(fail, SecondaryAccountKey, cli), chk.NotNil)
+	c.Assert(cli.ActiveAccountKey(), chk.Equals, PrimaryAccountKey)
+	c.Assert(cli.computeHmac256("foo"), chk.Equals, computeHmac256Key([]byte("primary"), "foo"))
+}
+
+func computeHmac256Key(key []byte, message string) string {
+	c := Client{keys: &accountKeys{}}
+	c.keys.set(PrimaryAccountKey, key)
+	return c.computeHmac256(message)
+}
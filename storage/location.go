@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// LocationMode defines which endpoints of a read-access geo-redundant
+// (RA-GRS) storage account the read requests of a Client are sent to. Write
+// requests are always sent to the primary endpoint, as the secondary
+// endpoint is read-only.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dn783454.aspx
+type LocationMode int
+
+// Location modes for read requests
+const (
+	// LocationModePrimaryOnly sends read requests to the primary endpoint.
+	LocationModePrimaryOnly LocationMode = iota
+
+	// LocationModePrimaryThenSecondary sends read requests to the primary
+	// endpoint and repeats them against the secondary endpoint if the
+	// primary fails with a retriable error. As the secondary may lag behind
+	// the primary, a 404 from the secondary is not reported in place of the
+	// primary failure.
+	LocationModePrimaryThenSecondary
+
+	// LocationModeSecondaryOnly sends read requests to the secondary
+	// endpoint.
+	LocationModeSecondaryOnly
+)
+
+// secondaryLocationSuffix is appended to the account name in the host name of
+// the secondary endpoint.
+const secondaryLocationSuffix = "-secondary"
+
+// getSecondaryEndpoint rewrites an endpoint of the client to point to the
+// secondary location of the storage account. The account name used for
+// signing the request does not change.
+func (c Client) getSecondaryEndpoint(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+
+	prefix := c.accountName + "."
+	if !strings.HasPrefix(u.Host, prefix) {
+		return "", fmt.Errorf("storage: cannot determine secondary endpoint of %s", u.Host)
+	}
+	u.Host = c.accountName + secondaryLocationSuffix + "." + strings.TrimPrefix(u.Host, prefix)
+	return u.String(), nil
+}
+
+// isReadRequest returns true if the request can be served by the secondary
+// endpoint. Get Messages is a GET request but dequeues messages, so it is not
+// a read request unless it only peeks.
+func isReadRequest(verb, uri string) bool {
+	if verb != "GET" && verb != "HEAD" {
+		return false
+	}
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		return false
+	}
+	if strings.HasSuffix(u.Path, "/messages") && u.Query().Get("peekonly") != "true" {
+		return strings.Index(u.Host, "."+queueServiceName+".") < 0
+	}
+	return true
+}
+
+// isRetriableFailure returns true if the request failed in a way that another
+// endpoint might not, that is on connection failures, timeouts and server
+// errors.
+func isRetriableFailure(resp *storageResponse, err error) bool {
+	if resp == nil {
+		return err != nil
+	}
+
+	switch resp.statusCode {
+	case http.StatusRequestTimeout,
+		http.StatusInternalServerError,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	chk "github.com/Azure/azure-sdk-for-go/Godeps/_workspace/src/gopkg.in/check.v1"
+)
+
+type StorageLocationSuite struct{}
+
+var _ = chk.Suite(&StorageLocationSuite{})
+
+// locationHandler responds to requests with the status code configured for
+// the location the request was sent to and records those locations.
+type locationHandler struct {
+	mu        sync.Mutex
+	primary   int
+	secondary int
+	hosts     []string
+}
+
+func (h *locationHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.hosts = append(h.hosts, r.Host)
+
+	code, location := h.primary, "primary"
+	if strings.HasPrefix(r.Host, "foo-secondary.") {
+		code, location = h.secondary, "secondary"
+	}
+	w.WriteHeader(code)
+	if code >= 400 {
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>%s</Code><Message>m</Message></Error>`, location)
+		return
+	}
+	fmt.Fprint(w, location)
+}
+
+func newLocationTestClient(c *chk.C, mode LocationMode, h *locationHandler) (BlobStorageClient, func()) {
+	cli, err := NewBasicClient("foo", "YmFy")
+	c.Assert(err, chk.IsNil)
+	cli.LocationMode = mode
+	ts := useTestServer(&cli, h)
+	return cli.GetBlobService(), ts.Close
+}
+
+func readBlob(c *chk.C, cli BlobStorageClient) (string, error) {
+	body, err := cli.GetBlob("cnt", "blob")
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+	b, err := ioutil.ReadAll(body)
+	c.Assert(err, chk.IsNil)
+	return string(b), nil
+}
+
+func (s *StorageLocationSuite) Test_getSecondaryEndpoint(c *chk.C) {
+	cli, err := NewBasicClient("foo", "YmFy")
+	c.Assert(err, chk.IsNil)
+
+	out, err := cli.getSecondaryEndpoint("https://foo.blob.core.windows.net/cnt/blob?comp=metadata")
+	c.Assert(err, chk.IsNil)
+	c.Assert(out, chk.Equals, "https://foo-secondary.blob.core.windows.net/cnt/blob?comp=metadata")
+
+	_, err = cli.getSecondaryEndpoint("https://bar.blob.core.windows.net/")
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *StorageLocationSuite) Test_isReadRequest(c *chk.C) {
+	for _, t := range []struct {
+		verb, url string
+		expected  bool
+	}{
+		{"GET", "https://foo.blob.core.windows.net/cnt/blob", true},
+		{"HEAD", "https://foo.blob.core.windows.net/cnt/blob", true},
+		{"PUT", "https://foo.blob.core.windows.net/cnt/blob", false},
+		{"DELETE", "https://foo.blob.core.windows.net/cnt/blob", false},
+		{"GET", "https://foo.blob.core.windows.net/cnt/messages", true},
+		{"GET", "https://foo.queue.core.windows.net/q/messages", false},
+		{"GET", "https://foo.queue.core.windows.net/q/messages?peekonly=true", true},
+		{"GET", "https://foo.queue.core.windows.net/q?comp=metadata", true},
+	} {
+		c.Assert(isReadRequest(t.verb, t.url), chk.Equals, t.expected, chk.Commentf("%s %s", t.verb, t.url))
+	}
+}
+
+func (s *StorageLocationSuite) TestPrimaryOnly(c *chk.C) {
+	h := &locationHandler{primary: http.StatusServiceUnavailable, secondary: http.StatusOK}
+	cli, done := newLocationTestClient(c, LocationModePrimaryOnly, h)
+	defer done()
+
+	_, err := readBlob(c, cli)
+	c.Assert(err, chk.NotNil)
+	c.Assert(h.hosts, chk.DeepEquals, []string{"foo.blob.core.windows.net"})
+}
+
+func (s *StorageLocationSuite) TestPrimaryThenSecondary_PrimaryHealthy(c *chk.C) {
+	h := &locationHandler{primary: http.StatusOK, secondary: http.StatusOK}
+	cli, done := newLocationTestClient(c, LocationModePrimaryThenSecondary, h)
+	defer done()
+
+	out, err := readBlob(c, cli)
+	c.Assert(err, chk.IsNil)
+	c.Assert(out, chk.Equals, "primary")
+	c.Assert(len(h.hosts), chk.Equals, 1)
+}
+
+func (s *StorageLocationSuite) TestPrimaryThenSecondary_FallsBack(c *chk.C) {
+	h := &locationHandler{primary: http.StatusServiceUnavailable, secondary: http.StatusOK}
+	cli, done := newLocationTestClient(c, LocationModePrimaryThenSecondary, h)
+	defer done()
+
+	out, err := readBlob(c, cli)
+	c.Assert(err, chk.IsNil)
+	c.Assert(out, chk.Equals, "secondary")
+	c.Assert(h.hosts, chk.DeepEquals, []string{"foo.blob.core.windows.net", "foo-secondary.blob.core.windows.net"})
+}
+
+func (s *StorageLocationSuite) TestPrimaryThenSecondary_NoFallbackOnClientError(c *chk.C) {
+	h := &locationHandler{primary: http.StatusNotFound, secondary: http.StatusOK}
+	cli, done := newLocationTestClient(c, LocationModePrimaryThenSecondary, h)
+	defer done()
+
+	_, err := readBlob(c, cli)
+	c.Assert(err, chk.NotNil)
+	c.Assert(len(h.hosts), chk.Equals, 1)
+}
+
+func (s *StorageLocationSuite) TestPrimaryThenSecondary_ReplicationLag(c *chk.C) {
+	h := &locationHandler{primary: http.StatusInternalServerError, secondary: http.StatusNotFound}
+	cli, done := newLocationTestClient(c, LocationModePrimaryThenSecondary, h)
+	defer done()
+
+	_, err := readBlob(c, cli)
+	c.Assert(err, chk.NotNil)
+	storageErr, ok := err.(AzureStorageServiceError)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(storageErr.Code, chk.Equals, "primary")
+	c.Assert(len(h.hosts), chk.Equals, 2)
+}
+
+func (s *StorageLocationSuite) TestSecondaryOnly(c *chk.C) {
+	h := &locationHandler{primary: http.StatusCreated, secondary: http.StatusOK}
+	cli, done := newLocationTestClient(c, LocationModeSecondaryOnly, h)
+	defer done()
+
+	out, err := readBlob(c, cli)
+	c.Assert(err, chk.IsNil)
+	c.Assert(out, chk.Equals, "secondary")
+
+	// writes never go to the secondary
+	c.Assert(cli.PutBlock("cnt", "blob", "id", []byte("data")), chk.IsNil)
+	c.Assert(h.hosts, chk.DeepEquals, []string{"foo-secondary.blob.core.windows.net", "foo.blob.core.windows.net"})
+}
@@ -0,0 +1,250 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrCampaignCancelled is returned from Campaign when the acquisition
+	// loop is cancelled through signaling the channel.
+	ErrCampaignCancelled = errors.New("storage: lock campaign cancelled")
+)
+
+// Bounds of the duration of finite blob leases
+const (
+	MinLeaseDuration = 15 * time.Second
+	MaxLeaseDuration = 60 * time.Second
+)
+
+// BlobLock is a distributed mutex built on a lease on a well-known blob. It
+// can be used for leader election among instances of a service: the
+// instance holding the lease is the leader.
+//
+// While the lock is held, the lease is renewed in a background goroutine.
+// If the lease cannot be renewed before it expires, or the service reports
+// that it is gone (for instance because it was broken), the channel returned
+// by Lost is closed and the lock has to be acquired again. Lost is closed
+// when the lease may have expired by the local clock even if a renewal is
+// still in flight. Holders only observe it asynchronously though, and clocks
+// can drift, so a new holder may briefly overlap with one which has not
+// noticed the loss yet. Work which must not overlap should be fenced, for
+// instance by making writes conditional on the lease ID.
+type BlobLock struct {
+	blob          BlobStorageClient
+	container     string
+	name          string
+	leaseDuration time.Duration
+	renewInterval time.Duration
+
+	mu      sync.Mutex
+	leaseID string
+	lost    chan struct{}
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// NewBlobLock returns a BlobLock on the specified blob, which is created on
+// first use if it does not exist. The container must exist. The lease
+// duration must be between MinLeaseDuration and MaxLeaseDuration; the lease
+// is renewed every third of it.
+func NewBlobLock(blob BlobStorageClient, container, name string, leaseDuration time.Duration) (*BlobLock, error) {
+	if container == "" || name == "" {
+		return nil, errors.New("storage: container and blob name required for lock")
+	}
+	if leaseDuration < MinLeaseDuration || leaseDuration > MaxLeaseDuration {
+		return nil, fmt.Errorf("storage: lease duration must be between %v and %v", MinLeaseDuration, MaxLeaseDuration)
+	}
+	return &BlobLock{
+		blob:          blob,
+		container:     container,
+		name:          name,
+		leaseDuration: leaseDuration,
+		renewInterval: leaseDuration / 3,
+	}, nil
+}
+
+// LeaseID returns the ID of the lease currently held, or empty string if the
+// lock is not held.
+func (l *BlobLock) LeaseID() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.leaseID
+}
+
+// Lost returns a channel which is closed when the lock acquired last is lost
+// or released. It returns nil if the lock has never been acquired.
+func (l *BlobLock) Lost() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lost
+}
+
+// TryAcquire makes a single attempt to acquire the lock. It returns false
+// without an error if the lease is held by someone else, and true if the
+// lock is held by l afterwards.
+func (l *BlobLock) TryAcquire() (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.leaseID != "" {
+		return true, nil
+	}
+
+	// the lease expires at most its duration after the request was sent
+	started := time.Now()
+	id, err := l.acquireLease()
+	if err != nil {
+		if isLeaseConflict(err) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	l.leaseID = id
+	l.lost = make(chan struct{})
+	l.stop = make(chan struct{})
+	l.done = make(chan struct{})
+	go l.renew(id, started.Add(l.leaseDuration), l.lost, l.stop, l.done)
+	return true, nil
+}
+
+// Campaign keeps trying to acquire the lock every retryInterval until it
+// succeeds. Conflicts and transient failures are retried, other errors are
+// returned.
+//
+// Cancellation of the loop is done through the cancel channel, a nil channel
+// can be provided to wait indefinitely. If cancelling is signaled,
+// ErrCampaignCancelled is returned.
+func (l *BlobLock) Campaign(retryInterval time.Duration, cancel chan struct{}) error {
+	for {
+		ok, err := l.TryAcquire()
+		if ok {
+			return nil
+		}
+		if err != nil && !isTransientError(err) {
+			return err
+		}
+
+		select {
+		case <-time.After(retryInterval):
+		case <-cancel:
+			return ErrCampaignCancelled
+		}
+	}
+}
+
+// Close stops renewing the lease and releases it if the lock is held. The
+// channel returned by Lost is closed before the lease is released. The lock
+// can be acquired again afterwards.
+func (l *BlobLock) Close() error {
+	l.mu.Lock()
+	id, stop, done := l.leaseID, l.stop, l.done
+	l.leaseID, l.stop, l.done = "", nil, nil
+	l.mu.Unlock()
+
+	if stop == nil {
+		return nil
+	}
+	close(stop)
+	<-done
+
+	if id == "" {
+		return nil // lost meanwhile
+	}
+	err := l.blob.ReleaseLease(l.container, l.name, id)
+	if err != nil && isLeaseConflict(err) {
+		return nil // expired or broken, nothing to release
+	}
+	return err
+}
+
+// acquireLease acquires a lease on the blob, creating the blob if it does not
+// exist yet.
+func (l *BlobLock) acquireLease() (string, error) {
+	seconds := int(l.leaseDuration / time.Second)
+	id, err := l.blob.AcquireLease(l.container, l.name, seconds, "")
+	if !isStorageErrorWithStatus(err, http.StatusNotFound) {
+		return id, err
+	}
+
+	// a conflict means someone else has created and leased it meanwhile
+	if err := l.blob.CreateBlockBlob(l.container, l.name); err != nil && !isLeaseConflict(err) {
+		return "", err
+	}
+	return l.blob.AcquireLease(l.container, l.name, seconds, "")
+}
+
+// renew renews the lease with given ID, which expires at the given time
+// unless renewed, until stop is closed or the lease is lost, and closes lost
+// and done on return. Renewals are made in the background, so that the
+// lease is given up when it may have expired even if a renewal hangs.
+func (l *BlobLock) renew(id string, expires time.Time, lost, stop, done chan struct{}) {
+	defer close(done)
+	defer close(lost)
+	defer func() {
+		l.mu.Lock()
+		if l.leaseID == id {
+			l.leaseID = ""
+		}
+		l.mu.Unlock()
+	}()
+
+	expiry := time.NewTimer(expires.Sub(time.Now()))
+	defer expiry.Stop()
+
+	type renewal struct {
+		started time.Time
+		err     error
+	}
+	// buffered, so that a renewal still in flight on return does not block
+	results := make(chan renewal, 1)
+	next := time.After(l.renewInterval)
+	for {
+		select {
+		case <-stop:
+			return
+		case <-expiry.C:
+			return
+		case <-next:
+			next = nil
+			go func() {
+				started := time.Now()
+				results <- renewal{started, l.blob.RenewLease(l.container, l.name, id)}
+			}()
+		case r := <-results:
+			if r.err != nil && !isTransientError(r.err) {
+				return
+			}
+			if r.err == nil {
+				if !expiry.Stop() {
+					<-expiry.C
+				}
+				expiry.Reset(r.started.Add(l.leaseDuration).Sub(time.Now()))
+			}
+			next = time.After(l.renewInterval)
+		}
+	}
+}
+
+// isLeaseConflict returns true if the error reports that the blob is leased
+// by someone else or the given lease is not held anymore.
+func isLeaseConflict(err error) bool {
+	return isStorageErrorWithStatus(err, http.StatusConflict) ||
+		isStorageErrorWithStatus(err, http.StatusPreconditionFailed)
+}
+
+func isStorageErrorWithStatus(err error, statusCode int) bool {
+	storageErr, ok := err.(AzureStorageServiceError)
+	return ok && storageErr.StatusCode == statusCode
+}
+
+// isTransientError returns true for errors other than those the service
+// reports for the request itself, such as connection failures and server
+// errors.
+func isTransientError(err error) bool {
+	storageErr, ok := err.(AzureStorageServiceError)
+	return !ok || storageErr.StatusCode >= http.StatusInternalServerError || storageErr.StatusCode == http.StatusRequestTimeout
+}
@@ -0,0 +1,295 @@
+package storage
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	chk "github.com/Azure/azure-sdk-for-go/Godeps/_workspace/src/gopkg.in/check.v1"
+)
+
+type StorageLockSuite struct{}
+
+var _ = chk.Suite(&StorageLockSuite{})
+
+// fakeLeaseServer emulates Put Blob and Lease Blob calls on a single blob.
+type fakeLeaseServer struct {
+	mu        sync.Mutex
+	exists    bool
+	leaseID   string
+	expires   time.Time
+	nextID    int
+	renewals  int
+	failRenew bool
+	hangRenew chan struct{} // renewals block until it is closed, if set
+}
+
+func (f *fakeLeaseServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	held := f.leaseID != "" && time.Now().Before(f.expires)
+	fail := func(code int, errCode string) {
+		w.WriteHeader(code)
+		fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>%s</Code><Message>m</Message></Error>`, errCode)
+	}
+
+	if r.URL.Query().Get("comp") != "lease" {
+		if held {
+			fail(http.StatusPreconditionFailed, "LeaseIdMissing")
+			return
+		}
+		f.exists = true
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+
+	if !f.exists {
+		fail(http.StatusNotFound, "BlobNotFound")
+		return
+	}
+
+	switch r.Header.Get(leaseAction) {
+	case acquireLease:
+		if held {
+			fail(http.StatusConflict, "LeaseAlreadyPresent")
+			return
+		}
+		seconds, _ := strconv.Atoi(r.Header.Get(leaseDuration))
+		f.nextID++
+		f.leaseID = fmt.Sprintf("lease-%d", f.nextID)
+		f.expires = time.Now().Add(time.Duration(seconds) * time.Second)
+		w.Header().Set(leaseID, f.leaseID)
+		w.WriteHeader(http.StatusCreated)
+	case renewLease:
+		if hang := f.hangRenew; hang != nil {
+			f.mu.Unlock()
+			<-hang
+			f.mu.Lock()
+		}
+		if f.failRenew {
+			fail(http.StatusServiceUnavailable, "ServerBusy")
+			return
+		}
+		if !held || r.Header.Get(leaseID) != f.leaseID {
+			fail(http.StatusConflict, "LeaseIdMismatchWithLeaseOperation")
+			return
+		}
+		f.renewals++
+		w.WriteHeader(http.StatusOK)
+	case releaseLease:
+		if r.Header.Get(leaseID) != f.leaseID {
+			fail(http.StatusConflict, "LeaseIdMismatchWithLeaseOperation")
+			return
+		}
+		f.leaseID = ""
+		w.WriteHeader(http.StatusOK)
+	case breakLease:
+		f.leaseID = ""
+		w.Header().Set(leaseTime, "0")
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		fail(http.StatusBadRequest, "InvalidHeaderValue")
+	}
+}
+
+func (f *fakeLeaseServer) state() (exists bool, leaseID string, renewals int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.exists, f.leaseID, f.renewals
+}
+
+func newTestLock(c *chk.C, blob BlobStorageClient) *BlobLock {
+	l, err := NewBlobLock(blob, "cnt", "leader", MinLeaseDuration)
+	c.Assert(err, chk.IsNil)
+	l.renewInterval = 10 * time.Millisecond
+	return l
+}
+
+func newLeaseTestClient(c *chk.C, f *fakeLeaseServer) (BlobStorageClient, func()) {
+	cli, err := NewBasicClient("foo", "YmFy")
+	c.Assert(err, chk.IsNil)
+	ts := useTestServer(&cli, f)
+	return cli.GetBlobService(), ts.Close
+}
+
+func isClosed(ch <-chan struct{}, timeout time.Duration) bool {
+	select {
+	case <-ch:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+func (s *StorageLockSuite) TestNewBlobLock_Validation(c *chk.C) {
+	_, err := NewBlobLock(BlobStorageClient{}, "", "leader", MinLeaseDuration)
+	c.Assert(err, chk.NotNil)
+	_, err = NewBlobLock(BlobStorageClient{}, "cnt", "leader", time.Second)
+	c.Assert(err, chk.NotNil)
+	_, err = NewBlobLock(BlobStorageClient{}, "cnt", "leader", 2*MaxLeaseDuration)
+	c.Assert(err, chk.NotNil)
+}
+
+func (s *StorageLockSuite) TestTryAcquire_CreatesBlob(c *chk.C) {
+	f := &fakeLeaseServer{}
+	blob, done := newLeaseTestClient(c, f)
+	defer done()
+
+	l := newTestLock(c, blob)
+	c.Assert(l.Lost(), chk.IsNil)
+	ok, err := l.TryAcquire()
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, true)
+	defer l.Close()
+
+	exists, leaseID, _ := f.state()
+	c.Assert(exists, chk.Equals, true)
+	c.Assert(leaseID, chk.Equals, l.LeaseID())
+
+	// acquiring again is a no-op
+	ok, err = l.TryAcquire()
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, true)
+	_, leaseID, _ = f.state()
+	c.Assert(leaseID, chk.Equals, l.LeaseID())
+}
+
+func (s *StorageLockSuite) TestTryAcquire_Exclusive(c *chk.C) {
+	f := &fakeLeaseServer{}
+	blob, done := newLeaseTestClient(c, f)
+	defer done()
+
+	l1, l2 := newTestLock(c, blob), newTestLock(c, blob)
+	ok, err := l1.TryAcquire()
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, true)
+
+	ok, err = l2.TryAcquire()
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, false)
+	c.Assert(l2.LeaseID(), chk.Equals, "")
+
+	lost := l1.Lost()
+	c.Assert(l1.Close(), chk.IsNil)
+	c.Assert(isClosed(lost, time.Second), chk.Equals, true)
+	c.Assert(l1.LeaseID(), chk.Equals, "")
+
+	ok, err = l2.TryAcquire()
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, true)
+	c.Assert(l2.Close(), chk.IsNil)
+}
+
+func (s *StorageLockSuite) TestRenewsLease(c *chk.C) {
+	f := &fakeLeaseServer{}
+	blob, done := newLeaseTestClient(c, f)
+	defer done()
+
+	l := newTestLock(c, blob)
+	ok, err := l.TryAcquire()
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, true)
+	defer l.Close()
+
+	c.Assert(isClosed(l.Lost(), 100*time.Millisecond), chk.Equals, false)
+	_, _, renewals := f.state()
+	c.Assert(renewals > 0, chk.Equals, true)
+}
+
+func (s *StorageLockSuite) TestLostWhenLeaseBroken(c *chk.C) {
+	f := &fakeLeaseServer{}
+	blob, done := newLeaseTestClient(c, f)
+	defer done()
+
+	l := newTestLock(c, blob)
+	ok, err := l.TryAcquire()
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, true)
+
+	_, err = blob.BreakLease("cnt", "leader")
+	c.Assert(err, chk.IsNil)
+	c.Assert(isClosed(l.Lost(), time.Second), chk.Equals, true)
+	c.Assert(l.LeaseID(), chk.Equals, "")
+	c.Assert(l.Close(), chk.IsNil)
+}
+
+func (s *StorageLockSuite) TestLostWhenRenewalsFail(c *chk.C) {
+	f := &fakeLeaseServer{failRenew: true}
+	blob, done := newLeaseTestClient(c, f)
+	defer done()
+
+	// transient failures are retried until the lease is about to expire
+	l := newTestLock(c, blob)
+	l.leaseDuration = 50 * time.Millisecond
+	ok, err := l.TryAcquire()
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, true)
+	defer l.Close()
+
+	c.Assert(isClosed(l.Lost(), 5*time.Second), chk.Equals, true)
+	c.Assert(l.LeaseID(), chk.Equals, "")
+}
+
+func (s *StorageLockSuite) TestLostWhenRenewalHangs(c *chk.C) {
+	hang := make(chan struct{})
+	f := &fakeLeaseServer{hangRenew: hang}
+	blob, done := newLeaseTestClient(c, f)
+	defer done()
+	defer close(hang)
+
+	l := newTestLock(c, blob)
+	l.leaseDuration = 100 * time.Millisecond
+	started := time.Now()
+	ok, err := l.TryAcquire()
+	c.Assert(err, chk.IsNil)
+	c.Assert(ok, chk.Equals, true)
+	defer l.Close()
+
+	c.Assert(isClosed(l.Lost(), time.Second), chk.Equals, true)
+	c.Assert(time.Since(started) < time.Second, chk.Equals, true)
+	c.Assert(l.LeaseID(), chk.Equals, "")
+}
+
+func (s *StorageLockSuite) TestCampaign(c *chk.C) {
+	f := &fakeLeaseServer{}
+	blob, done := newLeaseTestClient(c, f)
+	defer done()
+
+	l1, l2 := newTestLock(c, blob), newTestLock(c, blob)
+	c.Assert(l1.Campaign(10*time.Millisecond, nil), chk.IsNil)
+
+	result := make(chan error)
+	go func() { result <- l2.Campaign(10*time.Millisecond, nil) }()
+	select {
+	case err := <-result:
+		c.Fatalf("campaign finished while lock is held: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.Assert(l1.Close(), chk.IsNil)
+	select {
+	case err := <-result:
+		c.Assert(err, chk.IsNil)
+	case <-time.After(5 * time.Second):
+		c.Fatal("campaign did not acquire the released lock")
+	}
+	c.Assert(l2.LeaseID(), chk.Not(chk.Equals), "")
+	c.Assert(l2.Close(), chk.IsNil)
+}
+
+func (s *StorageLockSuite) TestCampaign_Cancel(c *chk.C) {
+	f := &fakeLeaseServer{}
+	blob, done := newLeaseTestClient(c, f)
+	defer done()
+
+	l1, l2 := newTestLock(c, blob), newTestLock(c, blob)
+	c.Assert(l1.Campaign(10*time.Millisecond, nil), chk.IsNil)
+	defer l1.Close()
+
+	cancel := make(chan struct{})
+	close(cancel)
+	c.Assert(l2.Campaign(10*time.Millisecond, cancel), chk.Equals, ErrCampaignCancelled)
+}
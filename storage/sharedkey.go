@@ -0,0 +1,261 @@
+package storage
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SharedKeyScheme is a variant of the Shared Key authorization scheme of the
+// storage services.
+//
+// See https://msdn.microsoft.com/en-us/library/azure/dd179428.aspx
+type SharedKeyScheme int
+
+// Shared Key authorization schemes
+const (
+	// SharedKey is the scheme of the Blob, Queue and File services.
+	SharedKey SharedKeyScheme = iota
+	// SharedKeyLite is the lite scheme of the Blob, Queue and File services.
+	SharedKeyLite
+	// SharedKeyForTable is the scheme of the Table service.
+	SharedKeyForTable
+	// SharedKeyLiteForTable is the lite scheme of the Table service.
+	SharedKeyLiteForTable
+)
+
+// String returns the name of the scheme as it appears in the Authorization
+// header.
+func (s SharedKeyScheme) String() string {
+	switch s {
+	case SharedKeyLite, SharedKeyLiteForTable:
+		return "SharedKeyLite"
+	default:
+		return "SharedKey"
+	}
+}
+
+// ErrInvalidSignature is returned from SharedKeySigner.Verify when the
+// Authorization header of the request is missing, malformed or does not
+// match the signature computed with the account key.
+var ErrInvalidSignature = errors.New("storage: invalid shared key signature")
+
+// contentLengthOmittedVersion is the first service version in which a zero
+// Content-Length is signed as an empty string.
+const contentLengthOmittedVersion = "2015-02-21"
+
+// SharedKeySigner signs requests to the storage services made with any HTTP
+// stack, and verifies the signatures of incoming requests, with a storage
+// account key.
+type SharedKeySigner struct {
+	accountName string
+	key         []byte
+	scheme      SharedKeyScheme
+}
+
+// NewSharedKeySigner returns a SharedKeySigner for the given storage account
+// name and base64-encoded key which uses the given scheme.
+func NewSharedKeySigner(accountName, accountKey string, scheme SharedKeyScheme) (SharedKeySigner, error) {
+	if accountName == "" {
+		return SharedKeySigner{}, errors.New("azure: account name required")
+	}
+	key, err := base64.StdEncoding.DecodeString(accountKey)
+	if err != nil {
+		return SharedKeySigner{}, err
+	}
+	return SharedKeySigner{accountName: accountName, key: key, scheme: scheme}, nil
+}
+
+// Sign sets the Authorization header of the request. The x-ms-date header is
+// set to the current time first if the request has neither it nor a Date
+// header. The request must not be modified afterwards.
+func (s SharedKeySigner) Sign(req *http.Request) {
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	if req.Header.Get("x-ms-date") == "" && req.Header.Get("Date") == "" {
+		req.Header.Set("x-ms-date", currentTimeRfc1123Formatted())
+	}
+	req.Header.Set("Authorization", fmt.Sprintf("%s %s:%s", s.scheme, s.accountName, s.signature(s.StringToSign(req))))
+}
+
+// Verify checks the Authorization header of an incoming request against the
+// signature computed with the account key, and returns ErrInvalidSignature
+// if it does not match.
+func (s SharedKeySigner) Verify(req *http.Request) error {
+	auth := req.Header.Get("Authorization")
+	prefix := fmt.Sprintf("%s %s:", s.scheme, s.accountName)
+	if !strings.HasPrefix(auth, prefix) {
+		return ErrInvalidSignature
+	}
+	got, err := base64.StdEncoding.DecodeString(auth[len(prefix):])
+	if err != nil {
+		return ErrInvalidSignature
+	}
+	expected, _ := base64.StdEncoding.DecodeString(s.signature(s.StringToSign(req)))
+	if !hmac.Equal(got, expected) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// StringToSign returns the string the signature of the request is computed
+// over with the scheme of the signer.
+func (s SharedKeySigner) StringToSign(req *http.Request) string {
+	h := req.Header
+	switch s.scheme {
+	case SharedKeyLite:
+		date := h.Get("Date")
+		if h.Get("x-ms-date") != "" {
+			date = ""
+		}
+		return strings.Join([]string{
+			req.Method,
+			h.Get("Content-MD5"),
+			h.Get("Content-Type"),
+			date,
+			canonicalizedHeaders(h) + liteCanonicalizedResource(s.accountName, req.URL),
+		}, "\n")
+	case SharedKeyForTable:
+		return strings.Join([]string{
+			req.Method,
+			h.Get("Content-MD5"),
+			h.Get("Content-Type"),
+			tableDate(h),
+			liteCanonicalizedResource(s.accountName, req.URL),
+		}, "\n")
+	case SharedKeyLiteForTable:
+		return tableDate(h) + "\n" + liteCanonicalizedResource(s.accountName, req.URL)
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		h.Get("Content-Encoding"),
+		h.Get("Content-Language"),
+		signedContentLength(req),
+		h.Get("Content-MD5"),
+		h.Get("Content-Type"),
+		h.Get("Date"),
+		h.Get("If-Modified-Since"),
+		h.Get("If-Match"),
+		h.Get("If-None-Match"),
+		h.Get("If-Unmodified-Since"),
+		h.Get("Range"),
+		canonicalizedHeaders(h) + canonicalizedResource(s.accountName, req.URL),
+	}, "\n")
+}
+
+func (s SharedKeySigner) signature(stringToSign string) string {
+	h := hmac.New(sha256.New, s.key)
+	h.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// signedContentLength returns the Content-Length of the request as it is
+// signed: empty for requests without a body, and also for a zero length in
+// service versions after which it is omitted.
+func signedContentLength(req *http.Request) string {
+	cl := req.Header.Get("Content-Length")
+	if cl == "" && req.ContentLength > 0 {
+		cl = strconv.FormatInt(req.ContentLength, 10)
+	}
+	if cl == "0" && req.Header.Get("x-ms-version") >= contentLengthOmittedVersion {
+		cl = ""
+	}
+	return cl
+}
+
+// tableDate returns the date signed by the Table service schemes, which is
+// x-ms-date if specified.
+func tableDate(h http.Header) string {
+	if d := h.Get("x-ms-date"); d != "" {
+		return d
+	}
+	return h.Get("Date")
+}
+
+// canonicalizedHeaders returns the x-ms-* headers with lower case names in
+// lexicographical order, each followed by a new line. Linear whitespace in
+// the values is folded into single spaces and multiple values of a header
+// are separated by commas.
+func canonicalizedHeaders(h http.Header) string {
+	values := make(map[string][]string)
+	for k, v := range h {
+		name := strings.ToLower(strings.TrimSpace(k))
+		if !strings.HasPrefix(name, "x-ms-") {
+			continue
+		}
+		for _, s := range v {
+			values[name] = append(values[name], strings.Join(strings.Fields(s), " "))
+		}
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []string
+	for _, name := range names {
+		out = append(out, name+":"+strings.Join(values[name], ",")+"\n")
+	}
+	return strings.Join(out, "")
+}
+
+// canonicalizedResource returns the resource of the request as signed with
+// the Shared Key scheme of the Blob, Queue and File services: the account
+// name and the encoded path, followed by every query parameter with its name
+// in lower case and its values sorted, in lexicographical order.
+//
+// The path is signed as it is sent, which is what the service verifies
+// against. Signing the decoded path instead makes requests for resources
+// whose names are escaped in URLs, such as names with spaces, percent signs
+// or non-ASCII characters, fail authentication.
+func canonicalizedResource(accountName string, u *url.URL) string {
+	return canonicalizeResource(accountName, u.EscapedPath(), u.Query())
+}
+
+// canonicalizeResource returns the account name and the path, followed by
+// every query parameter with its name in lower case and its values sorted,
+// in lexicographical order. It is shared by Shared Key authorization and
+// shared access signatures, which only differ in the form of the path.
+func canonicalizeResource(accountName, path string, query url.Values) string {
+	cr := "/" + accountName + path
+
+	params := make(map[string][]string)
+	for k, v := range query {
+		name := strings.ToLower(k)
+		params[name] = append(params[name], v...)
+	}
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		v := params[name]
+		sort.Strings(v)
+		cr += "\n" + name + ":" + strings.Join(v, ",")
+	}
+	return cr
+}
+
+// liteCanonicalizedResource returns the resource of the request as signed
+// with the Shared Key Lite and Table service schemes: the account name and
+// the encoded path, followed by the comp query parameter if present.
+func liteCanonicalizedResource(accountName string, u *url.URL) string {
+	cr := "/" + accountName + u.EscapedPath()
+	if comp, ok := u.Query()["comp"]; ok {
+		cr += "?comp=" + strings.Join(comp, ",")
+	}
+	return cr
+}
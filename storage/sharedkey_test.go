@@ -0,0 +1,189 @@
+package storage
+
+import (
+	"net/http"
+	"strings"
+
+	chk "github.com/Azure/azure-sdk-for-go/Godeps/_workspace/src/gopkg.in/check.v1"
+)
+
+type StorageSharedKeySuite struct{}
+
+var _ = chk.Suite(&StorageSharedKeySuite{})
+
+func newTestRequest(c *chk.C, method, url string, headers map[string]string) *http.Request {
+	req, err := http.NewRequest(method, url, nil)
+	c.Assert(err, chk.IsNil)
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	return req
+}
+
+func (s *StorageSharedKeySuite) Test_canonicalizedHeaders(c *chk.C) {
+	type test struct {
+		headers  http.Header
+		expected string
+	}
+	tests := []test{
+		{http.Header{}, ""},
+		{http.Header{"x-ms-foo": {"bar"}}, "x-ms-foo:bar\n"},
+		{http.Header{"foo:": {"bar"}}, ""},
+		{http.Header{"foo:": {"bar"}, "X-Ms-Foo": {"bar"}}, "x-ms-foo:bar\n"},
+		{http.Header{"foo-x-ms-bar": {"baz"}}, ""},
+		{http.Header{
+			"x-ms-version":   {"9999-99-99"},
+			"x-ms-blob-type": {"BlockBlob"}}, "x-ms-blob-type:BlockBlob\nx-ms-version:9999-99-99\n"},
+		{http.Header{"X-Ms-Meta-Foo": {"  a   b\r\n\tc  "}}, "x-ms-meta-foo:a b c\n"},
+		{http.Header{"X-Ms-Meta-Foo": {"b", "a"}}, "x-ms-meta-foo:b,a\n"},
+	}
+
+	for _, i := range tests {
+		c.Assert(canonicalizedHeaders(i.headers), chk.Equals, i.expected)
+	}
+}
+
+func (s *StorageSharedKeySuite) Test_canonicalizedResource(c *chk.C) {
+	type test struct{ url, expected, lite string }
+	tests := []test{
+		{"https://foo.blob.core.windows.net/path?a=b&c=d", "/foo/path\na:b\nc:d", "/foo/path"},
+		{"https://foo.blob.core.windows.net/?comp=list", "/foo/\ncomp:list", "/foo/?comp=list"},
+		{"https://foo.blob.core.windows.net/cnt/blob", "/foo/cnt/blob", "/foo/cnt/blob"},
+		{"https://foo.blob.core.windows.net/cnt/a%20b?comp=metadata", "/foo/cnt/a%20b\ncomp:metadata", "/foo/cnt/a%20b?comp=metadata"},
+		{"https://foo.blob.core.windows.net/cnt?restype=container&comp=list&include=snapshots&include=metadata&Prefix=x",
+			"/foo/cnt\ncomp:list\ninclude:metadata,snapshots\nprefix:x\nrestype:container",
+			"/foo/cnt?comp=list"},
+	}
+
+	cli, err := NewBasicClient("foo", "YmFy")
+	c.Assert(err, chk.IsNil)
+	for name, want := range map[string]string{
+		"a b": "/foo/cnt/a%20b",
+		"50%": "/foo/cnt/50%25",
+		"é":   "/foo/cnt/%C3%A9",
+	} {
+		url := cli.getEndpoint(blobServiceName, pathForBlob("cnt", name), nil)
+		tests = append(tests, test{url, want, want})
+	}
+
+	for _, i := range tests {
+		req := newTestRequest(c, "GET", i.url, nil)
+		c.Assert(canonicalizedResource("foo", req.URL), chk.Equals, i.expected)
+		c.Assert(liteCanonicalizedResource("foo", req.URL), chk.Equals, i.lite)
+	}
+}
+
+func (s *StorageSharedKeySuite) TestStringToSign(c *chk.C) {
+	headers := map[string]string{
+		"Content-Type": "text/plain",
+		"Content-MD5":  "bWQ1",
+		"x-ms-date":    "Sun, 11 Oct 2009 21:49:13 GMT",
+		"x-ms-version": "2014-02-14",
+	}
+	url := "https://foo.blob.core.windows.net/cnt/blob?comp=metadata"
+	expected := map[SharedKeyScheme]string{
+		SharedKey: "PUT\n\n\n0\nbWQ1\ntext/plain\n\n\n\n\n\n\n" +
+			"x-ms-date:Sun, 11 Oct 2009 21:49:13 GMT\nx-ms-version:2014-02-14\n" +
+			"/foo/cnt/blob\ncomp:metadata",
+		SharedKeyLite: "PUT\nbWQ1\ntext/plain\n\n" +
+			"x-ms-date:Sun, 11 Oct 2009 21:49:13 GMT\nx-ms-version:2014-02-14\n" +
+			"/foo/cnt/blob?comp=metadata",
+		SharedKeyForTable:     "PUT\nbWQ1\ntext/plain\nSun, 11 Oct 2009 21:49:13 GMT\n/foo/cnt/blob?comp=metadata",
+		SharedKeyLiteForTable: "Sun, 11 Oct 2009 21:49:13 GMT\n/foo/cnt/blob?comp=metadata",
+	}
+
+	for scheme, want := range expected {
+		signer, err := NewSharedKeySigner("foo", "YmFy", scheme)
+		c.Assert(err, chk.IsNil)
+		req := newTestRequest(c, "PUT", url, headers)
+		req.Header.Set("Content-Length", "0")
+		c.Check(signer.StringToSign(req), chk.Equals, want, chk.Commentf("%v", scheme))
+	}
+}
+
+func (s *StorageSharedKeySuite) Test_signedContentLength(c *chk.C) {
+	req := newTestRequest(c, "GET", "https://foo.blob.core.windows.net/cnt/blob", nil)
+	c.Assert(signedContentLength(req), chk.Equals, "")
+
+	req = newTestRequest(c, "PUT", "https://foo.blob.core.windows.net/cnt/blob", map[string]string{"x-ms-version": "2014-02-14"})
+	req.ContentLength = 10
+	c.Assert(signedContentLength(req), chk.Equals, "10")
+	req.Header.Set("Content-Length", "0")
+	c.Assert(signedContentLength(req), chk.Equals, "0")
+	req.Header.Set("x-ms-version", "2015-02-21")
+	c.Assert(signedContentLength(req), chk.Equals, "")
+}
+
+func (s *StorageSharedKeySuite) TestSignAndVerify(c *chk.C) {
+	signer, err := NewSharedKeySigner("foo", "YmFy", SharedKey)
+	c.Assert(err, chk.IsNil)
+
+	req := newTestRequest(c, "GET", "https://foo.queue.core.windows.net/q/messages?numofmessages=2", nil)
+	signer.Sign(req)
+	c.Assert(req.Header.Get("x-ms-date"), chk.Not(chk.Equals), "")
+	c.Assert(strings.HasPrefix(req.Header.Get("Authorization"), "SharedKey foo:"), chk.Equals, true)
+	c.Assert(signer.Verify(req), chk.IsNil)
+
+	// tampered requests and other keys are rejected
+	req.URL.RawQuery = "numofmessages=32"
+	c.Assert(signer.Verify(req), chk.Equals, ErrInvalidSignature)
+
+	other, err := NewSharedKeySigner("foo", "YmF6", SharedKey)
+	c.Assert(err, chk.IsNil)
+	req = newTestRequest(c, "GET", "https://foo.queue.core.windows.net/q", nil)
+	other.Sign(req)
+	c.Assert(signer.Verify(req), chk.Equals, ErrInvalidSignature)
+
+	lite, err := NewSharedKeySigner("foo", "YmFy", SharedKeyLite)
+	c.Assert(err, chk.IsNil)
+	lite.Sign(req)
+	c.Assert(strings.HasPrefix(req.Header.Get("Authorization"), "SharedKeyLite foo:"), chk.Equals, true)
+	c.Assert(lite.Verify(req), chk.IsNil)
+	c.Assert(signer.Verify(req), chk.Equals, ErrInvalidSignature)
+
+	req.Header.Del("Authorization")
+	c.Assert(signer.Verify(req), chk.Equals, ErrInvalidSignature)
+}
+
+func (s *StorageSharedKeySuite) TestNewSharedKeySigner_InvalidKey(c *chk.C) {
+	_, err := NewSharedKeySigner("foo", "not base64!", SharedKey)
+	c.Assert(err, chk.NotNil)
+	_, err = NewSharedKeySigner("", "YmFy", SharedKey)
+	c.Assert(err, chk.NotNil)
+}
+
+// verifyingHandler verifies incoming requests with a signer.
+type verifyingHandler struct {
+	signer SharedKeySigner
+	errs   []error
+}
+
+func (h *verifyingHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.errs = append(h.errs, h.signer.Verify(r))
+	if r.URL.Query().Get("comp") == "metadata" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (s *StorageSharedKeySuite) TestVerifyRequestsOfClient(c *chk.C) {
+	cli, err := NewBasicClient("foo", "YmFy")
+	c.Assert(err, chk.IsNil)
+	signer, err := NewSharedKeySigner("foo", "YmFy", SharedKey)
+	c.Assert(err, chk.IsNil)
+	h := &verifyingHandler{signer: signer}
+	ts := useTestServer(&cli, h)
+	defer ts.Close()
+
+	blob := cli.GetBlobService()
+	c.Assert(blob.PutBlock("cnt", "a blob", "id", []byte("data")), chk.IsNil)
+	c.Assert(blob.CreateBlockBlob("cnt", "50%"), chk.IsNil)
+	c.Assert(blob.CreateBlockBlob("cnt", "é"), chk.IsNil)
+	c.Assert(blob.SetBlobMetadata("cnt", "blob", map[string]string{"foo": "a  b"}), chk.IsNil)
+
+	c.Assert(h.errs, chk.HasLen, 4)
+	for _, err := range h.errs {
+		c.Assert(err, chk.IsNil)
+	}
+}
@@ -15,7 +15,12 @@ import (
 )
 
 func (c Client) computeHmac256(message string) string {
-	h := hmac.New(sha256.New, c.accountKey)
+	_, key := c.keys.get()
+	return hmac256(key, message)
+}
+
+func hmac256(key []byte, message string) string {
+	h := hmac.New(sha256.New, key)
 	h.Write([]byte(message))
 	return base64.StdEncoding.EncodeToString(h.Sum(nil))
 }